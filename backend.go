@@ -0,0 +1,128 @@
+package search
+
+import (
+	"context"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// ErrNotFound is returned by backends when a document cannot be found.
+var ErrNotFound = errors.Base("not found")
+
+// Query describes which documents to match. Zero value matches all documents.
+type Query struct {
+	// Text is a full-text query matched against names and identifier, reference,
+	// text, and string claims. Backends match names and text claims in all languages,
+	// except ElasticSearch which indexes only English ones.
+	// If empty, it matches all documents.
+	Text string
+	// Props are sets of property IDs. A document matches if for every set
+	// it has an active claim for any of the properties in the set.
+	Props [][]Identifier
+	// Classes are IDs of classes. A document matches if it is an instance
+	// of all of them (it has INSTANCE_OF_CLASS relation claims to them).
+	Classes []Identifier
+	// Times are time filters. A document matches if it matches all of them.
+	Times []TimeFilter
+	// References are IDs of documents. A document matches if any of its
+	// active claims (or their meta claims) references any of them.
+	References []Identifier
+}
+
+// TimeFilter matches documents with an active time or time range claim for any
+// of the properties, if the effective interval of the claim (see TimeClaim.Interval
+// and TimeRangeClaim.Interval) intersects the interval.
+type TimeFilter struct {
+	Props    []Identifier
+	Interval TimeInterval
+}
+
+// SearchOptions configures a search.
+type SearchOptions struct {
+	// Size is the maximum number of documents to return.
+	Size int
+	// Preference is used by backends to route searches from the same
+	// client consistently, e.g., to make pagination consistent.
+	Preference string
+}
+
+// SearchResult is a result of a search.
+type SearchResult struct {
+	// IDs of found documents, ordered by relevance.
+	IDs []Identifier
+	// Total is the number of all documents matching the query.
+	Total int64
+	// TotalIsLowerBound is true if there are at least Total documents
+	// matching the query, but the exact number is not known.
+	TotalIsLowerBound bool
+}
+
+// Version identifies a stored version of a document. It is opaque and specific to
+// the backend which returned it, so it should be passed only back to the same backend.
+type Version string
+
+// Update is a conditional replacement of a document.
+type Update struct {
+	// Document is the new version of the document.
+	Document *Document
+	// Version is the version of the document as it was read from the backend
+	// (see Backend.Get and Backend.Scroll). The document is replaced only if the
+	// stored document still has the same version.
+	Version Version
+}
+
+// BulkResult is the result of writing documents in bulk.
+type BulkResult struct {
+	// Conflicts are IDs of documents which have not been replaced because they
+	// have been changed (or removed) since they have been read.
+	Conflicts []Identifier
+	// Temporary are IDs of documents which have not been written because the backend
+	// could not accept them at the moment (e.g., it was overloaded or it blocked writes).
+	// Writing them can be retried later.
+	Temporary []Identifier
+	// Failed maps IDs of documents which have not been written because the backend
+	// rejected them (e.g., they could not be indexed) to reasons why. Writing them
+	// again will fail again.
+	Failed map[Identifier]string
+}
+
+// Backend stores documents and searches them.
+//
+// Documents returned from a backend are owned by the caller and can be modified.
+type Backend interface {
+	// Get returns the document with the ID and its version. The document includes at least
+	// parts of the document selected by the projection, but it can include more,
+	// so callers should project the document themselves if needed.
+	// It returns ErrNotFound if the document does not exist.
+	Get(ctx context.Context, id Identifier, projection Projection) (*Document, Version, errors.E)
+
+	// GetMany returns documents with IDs. Documents are the same as those returned
+	// by Get. Documents which do not exist are missing from the returned map.
+	GetMany(ctx context.Context, ids []Identifier, projection Projection) (map[Identifier]*Document, errors.E)
+
+	// FindByIdentifier returns documents with an active identifier claim for any of the
+	// properties with the value. Values might be matched case insensitive, so callers
+	// should check returned documents themselves if they need an exact match.
+	// Returned documents include at least their identifier claims.
+	FindByIdentifier(ctx context.Context, props []Identifier, value string) ([]*Document, errors.E)
+
+	// Search returns IDs of documents matching the query.
+	Search(ctx context.Context, query Query, options SearchOptions) (*SearchResult, errors.E)
+
+	// Scroll calls fn for every document matching the query, together with its version.
+	// Documents include at least parts of the document selected by the projection.
+	// If fn returns an error, scrolling stops and the error is returned.
+	Scroll(ctx context.Context, query Query, projection Projection, fn func(*Document, Version) errors.E) errors.E
+
+	// Bulk inserts or replaces documents. Written documents might become
+	// visible to other methods only after Refresh.
+	Bulk(ctx context.Context, documents []*Document) (*BulkResult, errors.E)
+
+	// BulkUpdate replaces documents which have not changed in the backend since
+	// they have been read. Replaced documents might become visible to other methods
+	// only after Refresh.
+	BulkUpdate(ctx context.Context, updates []Update) (*BulkResult, errors.E)
+
+	// Refresh makes all written documents visible to other methods.
+	Refresh(ctx context.Context) errors.E
+}
@@ -0,0 +1,368 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/olivere/elastic/v7"
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/x"
+)
+
+const elasticScrollSize = 1000
+
+// field describes a nested field for ElasticSearch to search on.
+type field struct {
+	Prefix string
+	Field  string
+}
+
+// ElasticBackend is a Backend which stores documents in an ElasticSearch index.
+type ElasticBackend struct {
+	Client *elastic.Client
+	// Index is the name of the ElasticSearch index (or its alias) with documents.
+	Index string
+}
+
+var _ Backend = (*ElasticBackend)(nil)
+
+// NewElasticBackend returns a new ElasticBackend using the index.
+func NewElasticBackend(client *elastic.Client, index string) *ElasticBackend {
+	return &ElasticBackend{
+		Client: client,
+		Index:  index,
+	}
+}
+
+// opaqueID returns the request ID to be passed to ElasticSearch as X-Opaque-ID header.
+func opaqueID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+func decodeDocument(id string, source []byte) (*Document, errors.E) {
+	var document Document
+	errE := x.UnmarshalWithoutUnknownFields(source, &document)
+	if errE != nil {
+		errors.Details(errE)["doc"] = id
+		return nil, errE
+	}
+	// ID is not stored in the document, so we set it here ourselves.
+	document.ID = Identifier(id)
+	return &document, nil
+}
+
+// elasticQuery returns ElasticSearch query for the query.
+func elasticQuery(query Query) elastic.Query {
+	var q elastic.Query
+	if query.Text == "" {
+		q = elastic.NewMatchAllQuery()
+	} else {
+		// TODO: Determine which operator should be the default?
+		// TODO: Make sure right analyzers are used for all fields.
+		// TODO: Limit allowed syntax for simple queries (disable fuzzy matching).
+		boolQuery := elastic.NewBoolQuery()
+		// TODO: Check which analyzer is used.
+		boolQuery = boolQuery.Should(elastic.NewSimpleQueryStringQuery(query.Text).Field("name.en").DefaultOperator("AND"))
+		for _, field := range []field{
+			{"active.id", "id"},
+			{"active.ref", "iri"},
+			{"active.text", "html.en"},
+			{"active.string", "string"},
+		} {
+			// TODO: Can we use simple query for keyword fields? Which analyzer is used?
+			sq := elastic.NewSimpleQueryStringQuery(query.Text).Field(field.Prefix + "." + field.Field).DefaultOperator("AND")
+			boolQuery = boolQuery.Should(elastic.NewNestedQuery(field.Prefix, sq))
+		}
+		q = boolQuery
+	}
+
+	filters := []elastic.Query{}
+	for _, props := range query.Props {
+		ids := make([]interface{}, len(props))
+		for i, prop := range props {
+			ids[i] = string(prop)
+		}
+		boolQuery := elastic.NewBoolQuery()
+		for _, claimType := range claimTypeNames() {
+			path := "active." + claimType
+			boolQuery = boolQuery.Should(elastic.NewNestedQuery(path, elastic.NewTermsQuery(path+".prop._id", ids...)))
+		}
+		filters = append(filters, boolQuery)
+	}
+	for _, class := range query.Classes {
+		filters = append(filters, elastic.NewNestedQuery("active.rel", elastic.NewBoolQuery().Must(
+			elastic.NewTermQuery("active.rel.prop._id", GetStandardPropertyID("INSTANCE_OF_CLASS")),
+			elastic.NewTermQuery("active.rel.to._id", string(class)),
+		)))
+	}
+
+	for _, filter := range query.Times {
+		ids := make([]interface{}, len(filter.Props))
+		for i, prop := range filter.Props {
+			ids[i] = string(prop)
+		}
+		// Intervals are indexed as ranges of sortable encodings of timestamps.
+		// The encoding preserves order, so it preserves intersections as well.
+		boolQuery := elastic.NewBoolQuery()
+		for _, claimType := range []string{"time", "timeRange"} {
+			path := "active." + claimType
+			boolQuery = boolQuery.Should(elastic.NewNestedQuery(path, elastic.NewBoolQuery().Must(
+				elastic.NewTermsQuery(path+".prop._id", ids...),
+				elastic.NewRangeQuery(path+".interval").
+					Gte(filter.Interval.Lower.Sortable()).Lte(filter.Interval.Upper.Sortable()).Relation("intersects"),
+			)))
+		}
+		filters = append(filters, boolQuery)
+	}
+
+	if len(query.References) > 0 {
+		ids := make([]interface{}, len(query.References))
+		for i, id := range query.References {
+			ids[i] = string(id)
+		}
+		// IDs of all referenced documents are copied to embeddedIds at indexing time.
+		filters = append(filters, elastic.NewTermsQuery("embeddedIds", ids...))
+	}
+
+	if len(filters) > 0 {
+		q = elastic.NewBoolQuery().Must(q).Filter(filters...)
+	}
+	return q
+}
+
+// elasticVersion returns the version of a document with the sequence number and the primary term.
+func elasticVersion(seqNo, primaryTerm *int64) Version {
+	if seqNo == nil || primaryTerm == nil {
+		return ""
+	}
+	return Version(fmt.Sprintf("%d/%d", *seqNo, *primaryTerm))
+}
+
+// parseElasticVersion returns the sequence number and the primary term from the version.
+func parseElasticVersion(version Version) (int64, int64, errors.E) {
+	var seqNo, primaryTerm int64
+	_, err := fmt.Sscanf(string(version), "%d/%d", &seqNo, &primaryTerm)
+	if err != nil {
+		errE := errors.WithMessage(err, "invalid version")
+		errors.Details(errE)["version"] = string(version)
+		return 0, 0, errE
+	}
+	return seqNo, primaryTerm, nil
+}
+
+// elasticTemporaryFailure returns true if the bulk item failed because of a condition
+// which is expected to pass (e.g., ElasticSearch is overloaded or writes to the index are
+// blocked while it is being reindexed), so the item can be retried.
+func elasticTemporaryFailure(item *elastic.BulkResponseItem) bool {
+	switch item.Status {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusInsufficientStorage:
+		return true
+	}
+	return item.Error != nil && item.Error.Type == "cluster_block_exception"
+}
+
+func (b *ElasticBackend) Get(ctx context.Context, id Identifier, projection Projection) (*Document, Version, errors.E) {
+	// We filter as much as possible already in ElasticSearch.
+	fetchSource := elastic.NewFetchSourceContext(true)
+	includes, excludes := projection.sourceFilter()
+	fetchSource.Include(includes...)
+	fetchSource.Exclude(excludes...)
+	resp, err := b.Client.Get().Index(b.Index).Id(string(id)).FetchSourceContext(fetchSource).
+		Header("X-Opaque-ID", opaqueID(ctx)).Do(ctx)
+	if elastic.IsNotFound(err) {
+		errE := errors.WithStack(ErrNotFound)
+		errors.Details(errE)["doc"] = string(id)
+		return nil, "", errE
+	} else if err != nil {
+		errE := errors.WithStack(err)
+		errors.Details(errE)["doc"] = string(id)
+		return nil, "", errE
+	}
+
+	document, errE := decodeDocument(resp.Id, resp.Source)
+	if errE != nil {
+		return nil, "", errE
+	}
+	return document, elasticVersion(resp.SeqNo, resp.PrimaryTerm), nil
+}
+
+func (b *ElasticBackend) GetMany(ctx context.Context, ids []Identifier, projection Projection) (map[Identifier]*Document, errors.E) {
+	documents := map[Identifier]*Document{}
+	if len(ids) == 0 {
+		return documents, nil
+	}
+
+	fetchSource := elastic.NewFetchSourceContext(true)
+	includes, excludes := projection.sourceFilter()
+	fetchSource.Include(includes...)
+	fetchSource.Exclude(excludes...)
+	mget := b.Client.Mget().Header("X-Opaque-ID", opaqueID(ctx))
+	for _, id := range ids {
+		mget.Add(elastic.NewMultiGetItem().Index(b.Index).Id(string(id)).FetchSource(fetchSource))
+	}
+
+	resp, err := mget.Do(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, doc := range resp.Docs {
+		if doc.Error != nil {
+			errE := errors.New("error getting document")
+			errors.Details(errE)["doc"] = doc.Id
+			errors.Details(errE)["reason"] = doc.Error.Reason
+			return nil, errE
+		} else if !doc.Found {
+			continue
+		}
+		document, errE := decodeDocument(doc.Id, doc.Source)
+		if errE != nil {
+			return nil, errE
+		}
+		documents[document.ID] = document
+	}
+	return documents, nil
+}
+
+func (b *ElasticBackend) FindByIdentifier(ctx context.Context, props []Identifier, value string) ([]*Document, errors.E) {
+	ids := make([]interface{}, len(props))
+	for i, prop := range props {
+		ids[i] = string(prop)
+	}
+
+	searchResult, err := b.Client.Search(b.Index).Header("X-Opaque-ID", opaqueID(ctx)).Query(elastic.NewNestedQuery("active.id",
+		elastic.NewBoolQuery().Must(
+			elastic.NewTermsQuery("active.id.prop._id", ids...),
+			elastic.NewTermQuery("active.id.id", value),
+		),
+	)).FetchSourceContext(elastic.NewFetchSourceContext(true).Include("active.id")).Do(ctx)
+	if err != nil {
+		errE := errors.WithStack(err)
+		errors.Details(errE)["value"] = value
+		return nil, errE
+	}
+
+	documents := make([]*Document, 0, len(searchResult.Hits.Hits))
+	for _, hit := range searchResult.Hits.Hits {
+		document, errE := decodeDocument(hit.Id, hit.Source)
+		if errE != nil {
+			return nil, errE
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+func (b *ElasticBackend) Search(ctx context.Context, query Query, options SearchOptions) (*SearchResult, errors.E) {
+	searchService := b.Client.Search(b.Index).FetchSource(false).Preference(options.Preference).
+		Header("X-Opaque-ID", opaqueID(ctx)).From(0).Size(options.Size).TrackTotalHits(true).
+		Query(elasticQuery(query))
+	res, err := searchService.Do(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	ids := make([]Identifier, len(res.Hits.Hits))
+	for i, hit := range res.Hits.Hits {
+		ids[i] = Identifier(hit.Id)
+	}
+
+	return &SearchResult{
+		IDs:               ids,
+		Total:             res.Hits.TotalHits.Value,
+		TotalIsLowerBound: res.Hits.TotalHits.Relation == "gte",
+	}, nil
+}
+
+func (b *ElasticBackend) Scroll(ctx context.Context, query Query, projection Projection, fn func(*Document, Version) errors.E) errors.E {
+	includes, excludes := projection.sourceFilter()
+	scroll := b.Client.Scroll(b.Index).
+		Size(elasticScrollSize).
+		Sort("_doc", true).
+		SearchSource(elastic.NewSearchSource().Query(elasticQuery(query)).FetchSourceIncludeExclude(includes, excludes).SeqNoAndPrimaryTerm(true))
+	defer scroll.Clear(context.Background()) //nolint:errcheck
+	for {
+		results, err := scroll.Do(ctx)
+		if errors.Is(err, io.EOF) || elastic.IsNotFound(err) {
+			// Index might not (yet) exist.
+			return nil
+		} else if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for _, hit := range results.Hits.Hits {
+			document, errE := decodeDocument(hit.Id, hit.Source)
+			if errE != nil {
+				return errE
+			}
+			errE = fn(document, elasticVersion(hit.SeqNo, hit.PrimaryTerm))
+			if errE != nil {
+				return errE
+			}
+		}
+	}
+}
+
+// doBulk executes the bulk request and returns which items failed and how. Version
+// conflicts are expected only for conditional updates, so only then they are reported
+// as conflicts and not as failures.
+func doBulk(ctx context.Context, bulk *elastic.BulkService, conflicts bool) (*BulkResult, errors.E) {
+	result := &BulkResult{
+		Conflicts: []Identifier{},
+		Temporary: []Identifier{},
+		Failed:    map[Identifier]string{},
+	}
+	if bulk.NumberOfActions() == 0 {
+		return result, nil
+	}
+
+	resp, err := bulk.Do(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, failed := range resp.Failed() {
+		if conflicts && failed.Status == http.StatusConflict {
+			result.Conflicts = append(result.Conflicts, Identifier(failed.Id))
+			continue
+		} else if elasticTemporaryFailure(failed) {
+			result.Temporary = append(result.Temporary, Identifier(failed.Id))
+			continue
+		}
+		reason := http.StatusText(failed.Status)
+		if failed.Error != nil {
+			reason = fmt.Sprintf("%s: %s", failed.Error.Type, failed.Error.Reason)
+		}
+		result.Failed[Identifier(failed.Id)] = reason
+	}
+	return result, nil
+}
+
+func (b *ElasticBackend) Bulk(ctx context.Context, documents []*Document) (*BulkResult, errors.E) {
+	bulk := b.Client.Bulk().Index(b.Index).Header("X-Opaque-ID", opaqueID(ctx))
+	for _, document := range documents {
+		bulk.Add(elastic.NewBulkIndexRequest().Id(string(document.ID)).Doc(document))
+	}
+	return doBulk(ctx, bulk, false)
+}
+
+func (b *ElasticBackend) BulkUpdate(ctx context.Context, updates []Update) (*BulkResult, errors.E) {
+	bulk := b.Client.Bulk().Index(b.Index).Header("X-Opaque-ID", opaqueID(ctx))
+	for _, update := range updates {
+		seqNo, primaryTerm, errE := parseElasticVersion(update.Version)
+		if errE != nil {
+			errors.Details(errE)["doc"] = string(update.Document.ID)
+			return nil, errE
+		}
+		// ElasticSearch replaces the document only if it has not changed since it has been read,
+		// otherwise the item fails with a version conflict.
+		bulk.Add(elastic.NewBulkIndexRequest().Id(string(update.Document.ID)).IfSeqNo(seqNo).IfPrimaryTerm(primaryTerm).Doc(update.Document))
+	}
+	return doBulk(ctx, bulk, true)
+}
+
+func (b *ElasticBackend) Refresh(ctx context.Context) errors.E {
+	_, err := b.Client.Refresh(b.Index).Do(ctx)
+	return errors.WithStack(err)
+}
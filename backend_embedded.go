@@ -0,0 +1,739 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/mapping"
+	bleveSearch "github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/x"
+
+	"gitlab.com/peerdb/search/identifier"
+)
+
+const (
+	embeddedOpenTimeout = "1s"
+	// How many documents are read from the index at once when paging through results.
+	embeddedPageSize = 1000
+	// Separator between a property ID and a value in indexed identifiers.
+	// Property IDs do not contain it.
+	embeddedKeySeparator = "\x00"
+	// Analyzer which only splits text into words and lower-cases them,
+	// so it works for all languages.
+	embeddedPlainAnalyzer = "plain"
+)
+
+// Fields of documents in the index.
+const (
+	// JSON of the document. It is only stored and not indexed.
+	embeddedSourceField = "source"
+	// Version of the document. It is only stored and not indexed.
+	embeddedVersionField = "version"
+	// Fields matched by Query.Text, analyzed as English text.
+	embeddedNameField    = "name"
+	embeddedTextField    = "text"
+	embeddedStringsField = "strings"
+	// Composite field of all fields analyzed as English text.
+	embeddedAllField = "_all"
+	// Names and text claims in all languages and strings, analyzed with embeddedPlainAnalyzer.
+	embeddedPlainField = "plain"
+	// IDs of properties of active claims.
+	embeddedPropsField = "props"
+	// IDs of classes the document is an instance of.
+	embeddedClassesField = "classes"
+	// Identifier claims as property IDs and lower-cased values.
+	embeddedIdentifiersField = "ids"
+	// IDs of documents referenced from active claims (or their meta claims).
+	embeddedReferencesField = "refs"
+	embeddedScoreField      = "score"
+)
+
+// embeddedQueryRegex splits query text into phrases in double quotes and words.
+var embeddedQueryRegex = regexp.MustCompile(`"[^"]*"|\S+`)
+
+// EmbeddedBackend is a Backend which stores documents in a Bleve index on the local disk,
+// so it does not require any external service.
+//
+// Full-text search analyzes English text (e.g., it stems words and ignores stop words),
+// matches words in other languages as they are (only lower-cased), and orders matched
+// documents by their relevance and then by their score. Query.Text supports phrases in
+// double quotes and word prefixes ending with "*" (prefixes are not stemmed). Time filters
+// cannot be expressed in the index so documents with claims for filtered properties are
+// checked one by one. It is suitable for small and medium datasets.
+//
+// It is safe for concurrent use, but only one process can open the same index at once.
+type EmbeddedBackend struct {
+	// Writes are serialized so that conditional updates can compare and write
+	// documents without other writes in between.
+	mu    sync.Mutex
+	index bleve.Index
+}
+
+var _ Backend = (*EmbeddedBackend)(nil)
+
+func embeddedTextFieldMapping() *mapping.FieldMapping {
+	m := bleve.NewTextFieldMapping()
+	m.Analyzer = en.AnalyzerName
+	m.Store = false
+	return m
+}
+
+func embeddedPlainFieldMapping() *mapping.FieldMapping {
+	m := bleve.NewTextFieldMapping()
+	m.Analyzer = embeddedPlainAnalyzer
+	m.Store = false
+	// Words are already in the composite field, only analyzed differently.
+	m.IncludeInAll = false
+	return m
+}
+
+func embeddedKeywordFieldMapping() *mapping.FieldMapping {
+	m := bleve.NewKeywordFieldMapping()
+	m.Store = false
+	m.IncludeInAll = false
+	m.IncludeTermVectors = false
+	m.DocValues = false
+	// These fields are used only to filter documents, so they should not
+	// make some matched documents more relevant than others.
+	m.SkipFreqNorm = true
+	return m
+}
+
+// embeddedMapping returns the mapping of the index.
+func embeddedMapping() (mapping.IndexMapping, errors.E) {
+	source := bleve.NewTextFieldMapping()
+	source.Index = false
+	source.IncludeInAll = false
+	source.IncludeTermVectors = false
+	source.DocValues = false
+
+	version := bleve.NewTextFieldMapping()
+	version.Index = false
+	version.IncludeInAll = false
+	version.IncludeTermVectors = false
+	version.DocValues = false
+
+	score := bleve.NewNumericFieldMapping()
+	score.Store = false
+	score.IncludeInAll = false
+
+	document := bleve.NewDocumentStaticMapping()
+	document.AddFieldMappingsAt(embeddedSourceField, source)
+	document.AddFieldMappingsAt(embeddedVersionField, version)
+	document.AddFieldMappingsAt(embeddedNameField, embeddedTextFieldMapping())
+	document.AddFieldMappingsAt(embeddedTextField, embeddedTextFieldMapping())
+	document.AddFieldMappingsAt(embeddedStringsField, embeddedTextFieldMapping())
+	document.AddFieldMappingsAt(embeddedPlainField, embeddedPlainFieldMapping())
+	document.AddFieldMappingsAt(embeddedPropsField, embeddedKeywordFieldMapping())
+	document.AddFieldMappingsAt(embeddedClassesField, embeddedKeywordFieldMapping())
+	document.AddFieldMappingsAt(embeddedIdentifiersField, embeddedKeywordFieldMapping())
+	document.AddFieldMappingsAt(embeddedReferencesField, embeddedKeywordFieldMapping())
+	document.AddFieldMappingsAt(embeddedScoreField, score)
+
+	indexMapping := bleve.NewIndexMapping()
+	err := indexMapping.AddCustomAnalyzer(embeddedPlainAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name},
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	indexMapping.DefaultMapping = document
+	// Used for the composite field.
+	indexMapping.DefaultAnalyzer = en.AnalyzerName
+	indexMapping.IndexDynamic = false
+	indexMapping.StoreDynamic = false
+	indexMapping.DocValuesDynamic = false
+	return indexMapping, nil
+}
+
+// OpenEmbeddedBackend opens (and creates if necessary) an EmbeddedBackend
+// for the index in the data directory. Call Close when done with it.
+func OpenEmbeddedBackend(dataDir, index string) (*EmbeddedBackend, errors.E) {
+	err := os.MkdirAll(dataDir, 0o755) //nolint:gomnd
+	if err != nil {
+		errE := errors.WithStack(err)
+		errors.Details(errE)["path"] = dataDir
+		return nil, errE
+	}
+
+	path := filepath.Join(dataDir, index+".bleve")
+	// We do not wait forever if another process has the index open.
+	config := map[string]interface{}{"bolt_timeout": embeddedOpenTimeout}
+	idx, err := bleve.OpenUsing(path, config)
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		indexMapping, errE := embeddedMapping()
+		if errE != nil {
+			return nil, errE
+		}
+		idx, err = bleve.NewUsing(path, indexMapping, bleve.Config.DefaultIndexType, bleve.Config.DefaultKVStore, config)
+	}
+	if err != nil {
+		errE := errors.WithStack(err)
+		errors.Details(errE)["path"] = path
+		return nil, errE
+	}
+
+	return &EmbeddedBackend{
+		mu:    sync.Mutex{},
+		index: idx,
+	}, nil
+}
+
+// Close closes the underlying index.
+func (b *EmbeddedBackend) Close() errors.E {
+	return errors.WithStack(b.index.Close())
+}
+
+// identifierKey returns the indexed value for the property and the value of an identifier claim.
+// Same as ElasticSearch, we match identifiers case insensitive.
+func identifierKey(prop Identifier, value string) string {
+	return string(prop) + embeddedKeySeparator + strings.ToLower(value)
+}
+
+type propsVisitor struct {
+	Props []string
+}
+
+func (v *propsVisitor) VisitIdentifier(claim *IdentifierClaim) (VisitResult, errors.E) {
+	v.Props = append(v.Props, string(claim.Prop.ID))
+	return Keep, nil
+}
+
+func (v *propsVisitor) VisitReference(claim *ReferenceClaim) (VisitResult, errors.E) {
+	v.Props = append(v.Props, string(claim.Prop.ID))
+	return Keep, nil
+}
+
+func (v *propsVisitor) VisitText(claim *TextClaim) (VisitResult, errors.E) {
+	v.Props = append(v.Props, string(claim.Prop.ID))
+	return Keep, nil
+}
+
+func (v *propsVisitor) VisitString(claim *StringClaim) (VisitResult, errors.E) {
+	v.Props = append(v.Props, string(claim.Prop.ID))
+	return Keep, nil
+}
+
+func (v *propsVisitor) VisitAmount(claim *AmountClaim) (VisitResult, errors.E) {
+	v.Props = append(v.Props, string(claim.Prop.ID))
+	return Keep, nil
+}
+
+func (v *propsVisitor) VisitAmountRange(claim *AmountRangeClaim) (VisitResult, errors.E) {
+	v.Props = append(v.Props, string(claim.Prop.ID))
+	return Keep, nil
+}
+
+func (v *propsVisitor) VisitEnumeration(claim *EnumerationClaim) (VisitResult, errors.E) {
+	v.Props = append(v.Props, string(claim.Prop.ID))
+	return Keep, nil
+}
+
+func (v *propsVisitor) VisitRelation(claim *RelationClaim) (VisitResult, errors.E) {
+	v.Props = append(v.Props, string(claim.Prop.ID))
+	return Keep, nil
+}
+
+func (v *propsVisitor) VisitFile(claim *FileClaim) (VisitResult, errors.E) {
+	v.Props = append(v.Props, string(claim.Prop.ID))
+	return Keep, nil
+}
+
+func (v *propsVisitor) VisitNoValue(claim *NoValueClaim) (VisitResult, errors.E) {
+	v.Props = append(v.Props, string(claim.Prop.ID))
+	return Keep, nil
+}
+
+func (v *propsVisitor) VisitUnknownValue(claim *UnknownValueClaim) (VisitResult, errors.E) {
+	v.Props = append(v.Props, string(claim.Prop.ID))
+	return Keep, nil
+}
+
+func (v *propsVisitor) VisitTime(claim *TimeClaim) (VisitResult, errors.E) {
+	v.Props = append(v.Props, string(claim.Prop.ID))
+	return Keep, nil
+}
+
+func (v *propsVisitor) VisitTimeRange(claim *TimeRangeClaim) (VisitResult, errors.E) {
+	v.Props = append(v.Props, string(claim.Prop.ID))
+	return Keep, nil
+}
+
+// embeddedDocument returns fields of the document to index with the version.
+func embeddedDocument(document *Document, version Version) (map[string]interface{}, errors.E) {
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	text := []string{}
+	strs := []string{}
+	plain := []string{}
+	for _, name := range document.Name {
+		plain = append(plain, name)
+	}
+	props := propsVisitor{Props: []string{}}
+	classes := []string{}
+	identifiers := []string{}
+	references := []string{}
+	if document.Active != nil {
+		for _, claim := range document.Active.Identifier {
+			strs = append(strs, claim.Identifier)
+			identifiers = append(identifiers, identifierKey(claim.Prop.ID, claim.Identifier))
+		}
+		for _, claim := range document.Active.Reference {
+			strs = append(strs, claim.IRI)
+		}
+		for _, claim := range document.Active.Text {
+			text = append(text, htmlTagRegex.ReplaceAllString(claim.HTML["en"], " "))
+			for _, html := range claim.HTML {
+				plain = append(plain, htmlTagRegex.ReplaceAllString(html, " "))
+			}
+		}
+		for _, claim := range document.Active.String {
+			strs = append(strs, claim.String)
+		}
+		instanceOfClass := GetStandardPropertyID("INSTANCE_OF_CLASS")
+		for _, claim := range document.Active.Relation {
+			if claim.Prop.ID == instanceOfClass {
+				classes = append(classes, string(claim.To.ID))
+			}
+		}
+		errE := document.Active.Visit(&props)
+		if errE != nil {
+			return nil, errE
+		}
+		refs, errE := getReferences(document.Active)
+		if errE != nil {
+			return nil, errE
+		}
+		for _, ref := range refs {
+			references = append(references, string(ref.ID))
+		}
+	}
+
+	return map[string]interface{}{
+		embeddedSourceField:      string(data),
+		embeddedVersionField:     string(version),
+		embeddedNameField:        document.Name["en"],
+		embeddedTextField:        text,
+		embeddedStringsField:     strs,
+		embeddedPlainField:       append(plain, strs...),
+		embeddedPropsField:       props.Props,
+		embeddedClassesField:     classes,
+		embeddedIdentifiersField: identifiers,
+		embeddedReferencesField:  references,
+		embeddedScoreField:       float64(document.Score),
+	}, nil
+}
+
+// termsQuery returns a query matching documents with any of the values in the field.
+func termsQuery(field string, values []Identifier) query.Query {
+	queries := make([]query.Query, 0, len(values))
+	for _, value := range values {
+		q := bleve.NewTermQuery(string(value))
+		q.SetField(field)
+		queries = append(queries, q)
+	}
+	return bleve.NewDisjunctionQuery(queries...)
+}
+
+// textQueries returns queries for the query text. A document matches if it matches all of them.
+func textQueries(text string) []query.Query {
+	queries := []query.Query{}
+	words := []string{}
+	for _, token := range embeddedQueryRegex.FindAllString(text, -1) {
+		if len(token) > 1 && strings.HasPrefix(token, `"`) && strings.HasSuffix(token, `"`) {
+			phrase := strings.TrimSpace(token[1 : len(token)-1])
+			if phrase == "" {
+				continue
+			}
+			// A phrase has to be found whole inside one of the fields.
+			phrases := []query.Query{}
+			for _, field := range []string{embeddedNameField, embeddedTextField, embeddedStringsField, embeddedPlainField} {
+				q := bleve.NewMatchPhraseQuery(phrase)
+				q.SetField(field)
+				phrases = append(phrases, q)
+			}
+			queries = append(queries, bleve.NewDisjunctionQuery(phrases...))
+		} else if strings.HasSuffix(token, "*") {
+			// Prefixes are not analyzed so we only lower-case them and match
+			// them against the field with words which are not stemmed.
+			prefix := strings.ToLower(strings.TrimRight(token, "*"))
+			if prefix == "" {
+				continue
+			}
+			q := bleve.NewPrefixQuery(prefix)
+			q.SetField(embeddedPlainField)
+			queries = append(queries, q)
+		} else {
+			words = append(words, token)
+		}
+	}
+	if len(words) > 0 {
+		// Words can be found in different fields, all in English text (where they are
+		// stemmed and stop words are ignored) or all in text in any language (where they are not).
+		matches := []query.Query{}
+		for _, field := range []string{embeddedAllField, embeddedPlainField} {
+			q := bleve.NewMatchQuery(strings.Join(words, " "))
+			q.SetField(field)
+			q.SetOperator(query.MatchQueryOperatorAnd)
+			matches = append(matches, q)
+		}
+		queries = append(queries, bleve.NewDisjunctionQuery(matches...))
+	}
+	return queries
+}
+
+// embeddedQuery returns the index query for the query. Time filters are only
+// partially applied: it matches documents with claims for filtered properties.
+func embeddedQuery(q Query) query.Query {
+	queries := textQueries(q.Text)
+	for _, props := range q.Props {
+		queries = append(queries, termsQuery(embeddedPropsField, props))
+	}
+	for _, class := range q.Classes {
+		queries = append(queries, termsQuery(embeddedClassesField, []Identifier{class}))
+	}
+	for _, filter := range q.Times {
+		queries = append(queries, termsQuery(embeddedPropsField, filter.Props))
+	}
+	if len(q.References) > 0 {
+		queries = append(queries, termsQuery(embeddedReferencesField, q.References))
+	}
+	if len(queries) == 0 {
+		return bleve.NewMatchAllQuery()
+	}
+	return bleve.NewConjunctionQuery(queries...)
+}
+
+// embeddedSort returns the order of documents matching the query.
+func embeddedSort(q Query) []string {
+	if strings.TrimSpace(q.Text) == "" {
+		return []string{"-" + embeddedScoreField, "_id"}
+	}
+	return []string{"-_score", "-" + embeddedScoreField, "_id"}
+}
+
+// embeddedStoredFields are fields which are loaded for matched documents.
+var embeddedStoredFields = []string{embeddedSourceField, embeddedVersionField}
+
+// decodeHit returns the document from its stored JSON and its version.
+func decodeHit(hit *bleveSearch.DocumentMatch) (*Document, Version, errors.E) {
+	source, ok := hit.Fields[embeddedSourceField].(string)
+	if !ok {
+		errE := errors.New("document without source")
+		errors.Details(errE)["doc"] = hit.ID
+		return nil, "", errE
+	}
+	version, ok := hit.Fields[embeddedVersionField].(string)
+	if !ok {
+		errE := errors.New("document without version")
+		errors.Details(errE)["doc"] = hit.ID
+		return nil, "", errE
+	}
+	var document Document
+	errE := x.UnmarshalWithoutUnknownFields([]byte(source), &document)
+	if errE != nil {
+		errors.Details(errE)["doc"] = hit.ID
+		return nil, "", errE
+	}
+	document.ID = Identifier(hit.ID)
+	return &document, Version(version), nil
+}
+
+// each calls fn for every document matching the index query, in the sort order.
+// It reads documents from the index page by page.
+func (b *EmbeddedBackend) each(ctx context.Context, q query.Query, sort []string, fn func(*Document, Version) errors.E) errors.E {
+	request := bleve.NewSearchRequestOptions(q, embeddedPageSize, 0, false)
+	request.SortBy(sort)
+	request.Fields = embeddedStoredFields
+	// Relevance cannot be used to continue after the last document of the previous page,
+	// so in that case we skip documents of previous pages instead.
+	byRelevance := sort[0] == "-_score"
+	for {
+		result, err := b.index.SearchInContext(ctx, request)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		for _, hit := range result.Hits {
+			document, version, errE := decodeHit(hit)
+			if errE != nil {
+				return errE
+			}
+			errE = fn(document, version)
+			if errE != nil {
+				return errE
+			}
+		}
+		if len(result.Hits) < embeddedPageSize {
+			return nil
+		}
+		if byRelevance {
+			request.From += len(result.Hits)
+		} else {
+			request.SearchAfter = result.Hits[len(result.Hits)-1].Sort
+		}
+	}
+}
+
+// hits returns hits for stored documents with IDs, with stored fields loaded.
+func (b *EmbeddedBackend) hits(ctx context.Context, ids []Identifier, fields []string) (bleveSearch.DocumentMatchCollection, errors.E) {
+	if len(ids) == 0 {
+		return bleveSearch.DocumentMatchCollection{}, nil
+	}
+	docIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		docIDs = append(docIDs, string(id))
+	}
+	request := bleve.NewSearchRequestOptions(bleve.NewDocIDQuery(docIDs), len(docIDs), 0, false)
+	request.Fields = fields
+	result, err := b.index.SearchInContext(ctx, request)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return result.Hits, nil
+}
+
+// getMany returns documents with IDs, together with their versions.
+func (b *EmbeddedBackend) getMany(ctx context.Context, ids []Identifier) (map[Identifier]*Document, map[Identifier]Version, errors.E) {
+	hits, errE := b.hits(ctx, ids, embeddedStoredFields)
+	if errE != nil {
+		return nil, nil, errE
+	}
+	documents := map[Identifier]*Document{}
+	versions := map[Identifier]Version{}
+	for _, hit := range hits {
+		document, version, errE := decodeHit(hit)
+		if errE != nil {
+			return nil, nil, errE
+		}
+		documents[document.ID] = document
+		versions[document.ID] = version
+	}
+	return documents, versions, nil
+}
+
+func (b *EmbeddedBackend) Get(ctx context.Context, id Identifier, _ Projection) (*Document, Version, errors.E) {
+	documents, versions, errE := b.getMany(ctx, []Identifier{id})
+	if errE != nil {
+		return nil, "", errE
+	}
+	document, ok := documents[id]
+	if !ok {
+		errE := errors.WithStack(ErrNotFound)
+		errors.Details(errE)["doc"] = string(id)
+		return nil, "", errE
+	}
+	return document, versions[id], nil
+}
+
+func (b *EmbeddedBackend) GetMany(ctx context.Context, ids []Identifier, _ Projection) (map[Identifier]*Document, errors.E) {
+	documents, _, errE := b.getMany(ctx, ids)
+	return documents, errE
+}
+
+func (b *EmbeddedBackend) FindByIdentifier(ctx context.Context, props []Identifier, value string) ([]*Document, errors.E) {
+	keys := make([]Identifier, 0, len(props))
+	for _, prop := range props {
+		keys = append(keys, Identifier(identifierKey(prop, value)))
+	}
+	documents := []*Document{}
+	errE := b.each(ctx, termsQuery(embeddedIdentifiersField, keys), []string{"_id"}, func(document *Document, _ Version) errors.E {
+		documents = append(documents, document)
+		return nil
+	})
+	if errE != nil {
+		errors.Details(errE)["value"] = value
+		return nil, errE
+	}
+	return documents, nil
+}
+
+func (b *EmbeddedBackend) Search(ctx context.Context, query Query, options SearchOptions) (*SearchResult, errors.E) {
+	if len(query.Times) == 0 {
+		request := bleve.NewSearchRequestOptions(embeddedQuery(query), options.Size, 0, false)
+		request.SortBy(embeddedSort(query))
+		result, err := b.index.SearchInContext(ctx, request)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		ids := make([]Identifier, 0, len(result.Hits))
+		for _, hit := range result.Hits {
+			ids = append(ids, Identifier(hit.ID))
+		}
+		return &SearchResult{
+			IDs:               ids,
+			Total:             int64(result.Total),
+			TotalIsLowerBound: false,
+		}, nil
+	}
+
+	// We check time filters of every candidate to count all matching documents.
+	ids := []Identifier{}
+	total := int64(0)
+	errE := b.each(ctx, embeddedQuery(query), embeddedSort(query), func(document *Document, _ Version) errors.E {
+		if !matches(document, query, nil) {
+			return nil
+		}
+		if len(ids) < options.Size {
+			ids = append(ids, document.ID)
+		}
+		total++
+		return nil
+	})
+	if errE != nil {
+		return nil, errE
+	}
+	return &SearchResult{
+		IDs:               ids,
+		Total:             total,
+		TotalIsLowerBound: false,
+	}, nil
+}
+
+// Scroll calls fn for matching documents in the order of their IDs. Documents
+// are read page by page, so fn can use the backend (e.g., to write documents).
+func (b *EmbeddedBackend) Scroll(ctx context.Context, query Query, _ Projection, fn func(*Document, Version) errors.E) errors.E {
+	// We do not order by relevance nor score because fn might change
+	// documents and we want every document to be visited exactly once.
+	return b.each(ctx, embeddedQuery(query), []string{"_id"}, func(document *Document, version Version) errors.E {
+		if ctx.Err() != nil {
+			return errors.WithStack(ctx.Err())
+		}
+		if len(query.Times) > 0 && !matches(document, query, nil) {
+			return nil
+		}
+		return fn(document, version)
+	})
+}
+
+// newBatch returns a batch indexing documents. Every document gets a new version.
+func (b *EmbeddedBackend) newBatch(documents []*Document) (*bleve.Batch, errors.E) {
+	batch := b.index.NewBatch()
+	for _, document := range documents {
+		data, errE := embeddedDocument(document, Version(identifier.NewRandom()))
+		if errE != nil {
+			errors.Details(errE)["doc"] = string(document.ID)
+			return nil, errE
+		}
+		err := batch.Index(string(document.ID), data)
+		if err != nil {
+			errE := errors.WithStack(err)
+			errors.Details(errE)["doc"] = string(document.ID)
+			return nil, errE
+		}
+	}
+	return batch, nil
+}
+
+func (b *EmbeddedBackend) Bulk(_ context.Context, documents []*Document) (*BulkResult, errors.E) {
+	for _, document := range documents {
+		if document.ID == "" {
+			return nil, errors.New("document without ID")
+		}
+	}
+
+	batch, errE := b.newBatch(documents)
+	if errE != nil {
+		return nil, errE
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// All documents are written at once.
+	err := b.index.Batch(batch)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &BulkResult{
+		Conflicts: []Identifier{},
+		Temporary: []Identifier{},
+		Failed:    map[Identifier]string{},
+	}, nil
+}
+
+func (b *EmbeddedBackend) BulkUpdate(ctx context.Context, updates []Update) (*BulkResult, errors.E) {
+	ids := make([]Identifier, 0, len(updates))
+	for _, update := range updates {
+		if update.Document.ID == "" {
+			return nil, errors.New("document without ID")
+		}
+		ids = append(ids, update.Document.ID)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Writes are serialized, so versions cannot change between reading and writing them.
+	versions, errE := b.versions(ctx, ids)
+	if errE != nil {
+		return nil, errE
+	}
+
+	result := &BulkResult{
+		Conflicts: []Identifier{},
+		Temporary: []Identifier{},
+		Failed:    map[Identifier]string{},
+	}
+	documents := []*Document{}
+	for _, update := range updates {
+		version, ok := versions[update.Document.ID]
+		if !ok || version != update.Version {
+			result.Conflicts = append(result.Conflicts, update.Document.ID)
+			continue
+		}
+		documents = append(documents, update.Document)
+	}
+
+	batch, errE := b.newBatch(documents)
+	if errE != nil {
+		return nil, errE
+	}
+	// All documents are written at once.
+	err := b.index.Batch(batch)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return result, nil
+}
+
+// versions returns versions of stored documents with IDs.
+func (b *EmbeddedBackend) versions(ctx context.Context, ids []Identifier) (map[Identifier]Version, errors.E) {
+	// We do not load (nor decode) documents themselves.
+	hits, errE := b.hits(ctx, ids, []string{embeddedVersionField})
+	if errE != nil {
+		return nil, errE
+	}
+	versions := map[Identifier]Version{}
+	for _, hit := range hits {
+		version, ok := hit.Fields[embeddedVersionField].(string)
+		if !ok {
+			errE := errors.New("document without version")
+			errors.Details(errE)["doc"] = hit.ID
+			return nil, errE
+		}
+		versions[Identifier(hit.ID)] = Version(version)
+	}
+	return versions, nil
+}
+
+// Refresh does nothing because written documents are visible immediately.
+func (b *EmbeddedBackend) Refresh(_ context.Context) errors.E {
+	return nil
+}
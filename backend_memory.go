@@ -0,0 +1,424 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/x"
+)
+
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// MemoryBackend is a Backend which stores documents in memory.
+//
+// Documents are not persisted, so it is suitable only for tests. Full-text search is simple:
+// a document matches if all words of the query are among words of the document.
+// Matched documents are ordered by their score.
+//
+// It is safe for concurrent use.
+type MemoryBackend struct {
+	mu sync.RWMutex
+	// We store documents as JSON so that returned documents do not share memory with stored ones.
+	documents map[Identifier][]byte
+	// Versions of stored documents. Every write gets a new version.
+	versions map[Identifier]Version
+	writes   int64
+}
+
+var _ Backend = (*MemoryBackend)(nil)
+
+// NewMemoryBackend returns a new MemoryBackend without any documents.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		mu:        sync.RWMutex{},
+		documents: map[Identifier][]byte{},
+		versions:  map[Identifier]Version{},
+		writes:    0,
+	}
+}
+
+// words returns lower-cased words in the string.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// documentWords returns all words of the document which are matched by Query.Text.
+func documentWords(document *Document) map[string]bool {
+	res := map[string]bool{}
+	add := func(s string) {
+		for _, word := range words(s) {
+			res[word] = true
+		}
+	}
+	for _, name := range document.Name {
+		add(name)
+	}
+	if document.Active != nil {
+		for _, claim := range document.Active.Identifier {
+			add(claim.Identifier)
+		}
+		for _, claim := range document.Active.Reference {
+			add(claim.IRI)
+		}
+		for _, claim := range document.Active.Text {
+			for _, html := range claim.HTML {
+				add(htmlTagRegex.ReplaceAllString(html, " "))
+			}
+		}
+		for _, claim := range document.Active.String {
+			add(claim.String)
+		}
+	}
+	return res
+}
+
+// hasActiveClaim returns true if the document has an active claim for any of the properties.
+func hasActiveClaim(document *Document, props []Identifier) bool {
+	if document.Active == nil {
+		return false
+	}
+	for _, prop := range props {
+		v := getByPropIDVisitor{
+			ID:     prop,
+			Action: Keep,
+			Result: []Claim{},
+		}
+		_ = document.Active.Visit(&v)
+		if len(v.Result) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasActiveTimeClaim returns true if the document has an active time or time range
+// claim matching the time filter.
+func hasActiveTimeClaim(document *Document, filter TimeFilter) bool {
+	if document.Active == nil {
+		return false
+	}
+	props := map[Identifier]bool{}
+	for _, prop := range filter.Props {
+		props[prop] = true
+	}
+	for i := range document.Active.Time {
+		claim := &document.Active.Time[i]
+		if props[claim.Prop.ID] && claim.Interval().Overlaps(filter.Interval) {
+			return true
+		}
+	}
+	for i := range document.Active.TimeRange {
+		claim := &document.Active.TimeRange[i]
+		if props[claim.Prop.ID] && claim.Interval().Overlaps(filter.Interval) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasReference returns true if any of active claims of the document (or their
+// meta claims) references any of documents with IDs.
+func hasReference(document *Document, ids []Identifier) bool {
+	// Errors are returned only by visitors themselves, and referencesVisitor does not return any.
+	references, _ := getReferences(document.Active)
+	for _, reference := range references {
+		for _, id := range ids {
+			if reference.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matches returns true if the document matches the query.
+func matches(document *Document, query Query, queryWords []string) bool {
+	if len(queryWords) > 0 {
+		ws := documentWords(document)
+		for _, word := range queryWords {
+			if !ws[word] {
+				return false
+			}
+		}
+	}
+
+	for _, props := range query.Props {
+		if !hasActiveClaim(document, props) {
+			return false
+		}
+	}
+
+	for _, filter := range query.Times {
+		if !hasActiveTimeClaim(document, filter) {
+			return false
+		}
+	}
+
+	if len(query.References) > 0 && !hasReference(document, query.References) {
+		return false
+	}
+
+	instanceOfClass := GetStandardPropertyID("INSTANCE_OF_CLASS")
+	for _, class := range query.Classes {
+		found := false
+		if document.Active != nil {
+			for _, claim := range document.Active.Relation {
+				if claim.Prop.ID == instanceOfClass && claim.To.ID == class {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sortDocuments orders documents by their score and then by their ID.
+func sortDocuments(documents []*Document) {
+	sort.Slice(documents, func(i, j int) bool {
+		if documents[i].Score != documents[j].Score {
+			return documents[i].Score > documents[j].Score
+		}
+		return documents[i].ID < documents[j].ID
+	})
+}
+
+// newSearchResult returns a search result for at most size of sorted documents.
+func newSearchResult(documents []*Document, size int) *SearchResult {
+	ids := []Identifier{}
+	for i, document := range documents {
+		if i >= size {
+			break
+		}
+		ids = append(ids, document.ID)
+	}
+
+	return &SearchResult{
+		IDs:               ids,
+		Total:             int64(len(documents)),
+		TotalIsLowerBound: false,
+	}
+}
+
+// get returns the stored document with the ID or nil if it does not exist.
+// The caller must hold the lock.
+func (b *MemoryBackend) get(id Identifier) (*Document, errors.E) {
+	data, ok := b.documents[id]
+	if !ok {
+		return nil, nil
+	}
+	var document Document
+	errE := x.UnmarshalWithoutUnknownFields(data, &document)
+	if errE != nil {
+		errors.Details(errE)["doc"] = string(id)
+		return nil, errE
+	}
+	document.ID = id
+	return &document, nil
+}
+
+// all returns all stored documents matching the query, ordered by their score
+// and then by their ID. The caller must hold the lock.
+func (b *MemoryBackend) all(query Query) ([]*Document, errors.E) {
+	queryWords := words(query.Text)
+	documents := []*Document{}
+	for id := range b.documents {
+		document, errE := b.get(id)
+		if errE != nil {
+			return nil, errE
+		}
+		if matches(document, query, queryWords) {
+			documents = append(documents, document)
+		}
+	}
+	sortDocuments(documents)
+	return documents, nil
+}
+
+func (b *MemoryBackend) Get(_ context.Context, id Identifier, _ Projection) (*Document, Version, errors.E) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	document, errE := b.get(id)
+	if errE != nil {
+		return nil, "", errE
+	}
+	if document == nil {
+		errE := errors.WithStack(ErrNotFound)
+		errors.Details(errE)["doc"] = string(id)
+		return nil, "", errE
+	}
+	return document, b.versions[id], nil
+}
+
+func (b *MemoryBackend) GetMany(_ context.Context, ids []Identifier, _ Projection) (map[Identifier]*Document, errors.E) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	documents := map[Identifier]*Document{}
+	for _, id := range ids {
+		document, errE := b.get(id)
+		if errE != nil {
+			return nil, errE
+		}
+		if document != nil {
+			documents[id] = document
+		}
+	}
+	return documents, nil
+}
+
+func (b *MemoryBackend) FindByIdentifier(_ context.Context, props []Identifier, value string) ([]*Document, errors.E) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	documents, errE := b.all(Query{})
+	if errE != nil {
+		return nil, errE
+	}
+	res := []*Document{}
+	for _, document := range documents {
+		if document.Active == nil {
+			continue
+		}
+		for _, claim := range document.Active.Identifier {
+			found := false
+			for _, prop := range props {
+				// Same as ElasticSearch, we match identifiers case insensitive.
+				if claim.Prop.ID == prop && strings.EqualFold(claim.Identifier, value) {
+					found = true
+					break
+				}
+			}
+			if found {
+				res = append(res, document)
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+func (b *MemoryBackend) Search(_ context.Context, query Query, options SearchOptions) (*SearchResult, errors.E) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	documents, errE := b.all(query)
+	if errE != nil {
+		return nil, errE
+	}
+
+	return newSearchResult(documents, options.Size), nil
+}
+
+func (b *MemoryBackend) Scroll(ctx context.Context, query Query, _ Projection, fn func(*Document, Version) errors.E) errors.E {
+	// We collect documents first so that fn can use the backend (e.g., to write documents).
+	b.mu.RLock()
+	documents, errE := b.all(query)
+	versions := make([]Version, len(documents))
+	for i, document := range documents {
+		versions[i] = b.versions[document.ID]
+	}
+	b.mu.RUnlock()
+	if errE != nil {
+		return errE
+	}
+
+	for i, document := range documents {
+		if ctx.Err() != nil {
+			return errors.WithStack(ctx.Err())
+		}
+		errE := fn(document, versions[i])
+		if errE != nil {
+			return errE
+		}
+	}
+	return nil
+}
+
+// put stores the document JSON and gives it a new version. The caller must hold the lock.
+func (b *MemoryBackend) put(id Identifier, data []byte) {
+	b.writes++
+	b.documents[id] = data
+	b.versions[id] = Version(strconv.FormatInt(b.writes, 10))
+}
+
+func (b *MemoryBackend) Bulk(_ context.Context, documents []*Document) (*BulkResult, errors.E) {
+	data := make(map[Identifier][]byte, len(documents))
+	for _, document := range documents {
+		if document.ID == "" {
+			return nil, errors.New("document without ID")
+		}
+		d, err := json.Marshal(document)
+		if err != nil {
+			errE := errors.WithStack(err)
+			errors.Details(errE)["doc"] = string(document.ID)
+			return nil, errE
+		}
+		data[document.ID] = d
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for id, d := range data {
+		b.put(id, d)
+	}
+	return &BulkResult{
+		Conflicts: []Identifier{},
+		Temporary: []Identifier{},
+		Failed:    map[Identifier]string{},
+	}, nil
+}
+
+func (b *MemoryBackend) BulkUpdate(_ context.Context, updates []Update) (*BulkResult, errors.E) {
+	data := make([][]byte, len(updates))
+	for i, update := range updates {
+		if update.Document.ID == "" {
+			return nil, errors.New("document without ID")
+		}
+		d, err := json.Marshal(update.Document)
+		if err != nil {
+			errE := errors.WithStack(err)
+			errors.Details(errE)["doc"] = string(update.Document.ID)
+			return nil, errE
+		}
+		data[i] = d
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := &BulkResult{
+		Conflicts: []Identifier{},
+		Temporary: []Identifier{},
+		Failed:    map[Identifier]string{},
+	}
+	for i, update := range updates {
+		version, ok := b.versions[update.Document.ID]
+		if !ok || version != update.Version {
+			result.Conflicts = append(result.Conflicts, update.Document.ID)
+			continue
+		}
+		b.put(update.Document.ID, data[i])
+	}
+	return result, nil
+}
+
+// Refresh does nothing because written documents are visible immediately.
+func (b *MemoryBackend) Refresh(_ context.Context) errors.E {
+	return nil
+}
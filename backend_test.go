@@ -0,0 +1,519 @@
+package search_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/olivere/elastic/v7"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/tozd/go/errors"
+
+	"gitlab.com/peerdb/search"
+	"gitlab.com/peerdb/search/identifier"
+)
+
+func backendTestDocument(name, text string, score search.Score) *search.Document {
+	return &search.Document{
+		CoreDocument: search.CoreDocument{
+			ID:    search.Identifier(identifier.NewRandom()),
+			Name:  search.Name{"en": name},
+			Score: score,
+		},
+		Active: &search.ClaimTypes{
+			Text: search.TextClaims{
+				{
+					CoreClaim: search.CoreClaim{
+						ID:         search.Identifier(identifier.NewRandom()),
+						Confidence: 1.0,
+					},
+					Prop: search.GetStandardPropertyReference("DESCRIPTION"),
+					HTML: search.TranslatableHTMLString{"en": text},
+				},
+			},
+		},
+	}
+}
+
+// backendTestAlias is an alias of the first test document.
+var backendTestAlias = identifier.NewRandom()
+
+// backendTestTimeProp is a property of time claims of test documents.
+var backendTestTimeProp = search.Identifier(identifier.NewRandom())
+
+func backendTestTimeClaim(year int64, precision search.TimePrecision) search.TimeClaim {
+	return search.TimeClaim{
+		CoreClaim: search.CoreClaim{
+			ID:         search.Identifier(identifier.NewRandom()),
+			Confidence: 1.0,
+		},
+		Prop: search.DocumentReference{
+			ID:    backendTestTimeProp,
+			Name:  search.Name{"en": "founded"},
+			Score: 0.5,
+		},
+		Timestamp: timestamp(year, time.January, 1, 0, 0, 0),
+		Precision: precision,
+	}
+}
+
+// populateTestBackend stores test documents into the backend and returns them.
+func populateTestBackend(t *testing.T, backend search.Backend) []*search.Document {
+	t.Helper()
+
+	ljubljana := backendTestDocument("Ljubljana", "<b>Capital</b> of Slovenia.", 0.8)
+	maribor := backendTestDocument("Maribor", "A city in Slovenia.", 0.5)
+	paris := backendTestDocument("Paris", "Capital of France.", 0.9)
+	ljubljana.Name["de"] = "Laibach"
+	maribor.Active.Text[0].HTML["sl"] = "Mesto v <i>Sloveniji</i>."
+	alias, errE := search.NewAliasClaim(search.Identifier(identifier.NewRandom()), search.Identifier(backendTestAlias), 1.0)
+	require.NoError(t, errE)
+	ljubljana.Active.Identifier = search.IdentifierClaims{*alias}
+	ljubljana.Active.Relation = search.RelationClaims{
+		{
+			CoreClaim: search.CoreClaim{
+				ID:         search.Identifier(identifier.NewRandom()),
+				Confidence: 1.0,
+			},
+			Prop: search.GetStandardPropertyReference("INSTANCE_OF_CLASS"),
+			To:   search.GetStandardPropertyReference("ITEM"),
+		},
+	}
+
+	ljubljana.Active.Time = search.TimeClaims{backendTestTimeClaim(1144, search.TimePrecisionYear)}
+	// Maribor was first mentioned in 1164, but we use ten years precision here.
+	maribor.Active.Time = search.TimeClaims{backendTestTimeClaim(1164, search.TimePrecisionTenYears)}
+
+	documents := []*search.Document{ljubljana, maribor, paris}
+	_, errE = backend.Bulk(context.Background(), documents)
+	require.NoError(t, errE)
+	require.NoError(t, backend.Refresh(context.Background()))
+	return documents
+}
+
+func timeFilter(prop search.Identifier, from, to int64) search.TimeFilter {
+	return search.TimeFilter{
+		Props: []search.Identifier{prop},
+		Interval: search.TimeInterval{
+			Lower: timestamp(from, time.January, 1, 0, 0, 0),
+			Upper: timestamp(to, time.December, 31, 23, 59, 59),
+		},
+	}
+}
+
+func testBackend(t *testing.T, backend search.Backend) {
+	t.Helper()
+
+	documents := populateTestBackend(t, backend)
+	ljubljana, maribor, paris := documents[0], documents[1], documents[2]
+	ctx := context.Background()
+
+	document, _, errE := backend.Get(ctx, ljubljana.ID, search.FullProjection())
+	require.NoError(t, errE)
+	assert.Equal(t, ljubljana, document)
+
+	// Returned documents do not share memory with stored ones.
+	document.Name["en"] = "Changed"
+	document, _, errE = backend.Get(ctx, ljubljana.ID, search.FullProjection())
+	require.NoError(t, errE)
+	assert.Equal(t, "Ljubljana", document.Name["en"])
+
+	_, _, errE = backend.Get(ctx, search.Identifier(identifier.NewRandom()), search.FullProjection())
+	assert.True(t, errors.Is(errE, search.ErrNotFound))
+
+	missing := search.Identifier(identifier.NewRandom())
+	many, errE := backend.GetMany(ctx, []search.Identifier{maribor.ID, missing, paris.ID}, search.FullProjection())
+	require.NoError(t, errE)
+	assert.Equal(t, map[search.Identifier]*search.Document{maribor.ID: maribor, paris.ID: paris}, many)
+
+	found, errE := backend.FindByIdentifier(ctx, []search.Identifier{search.GetStandardPropertyID("ALIAS")}, strings.ToLower(backendTestAlias))
+	require.NoError(t, errE)
+	assert.Equal(t, []*search.Document{ljubljana}, found)
+
+	id, errE := search.ResolveAlias(ctx, backend, backendTestAlias)
+	require.NoError(t, errE)
+	assert.Equal(t, ljubljana.ID, id)
+	id, errE = search.ResolveAlias(ctx, backend, strings.ToLower(backendTestAlias))
+	require.NoError(t, errE)
+	assert.Equal(t, search.Identifier(""), id)
+
+	tests := []struct {
+		Query    search.Query
+		Expected []search.Identifier
+	}{
+		{search.Query{}, []search.Identifier{paris.ID, ljubljana.ID, maribor.ID}},
+		{search.Query{Text: "capital"}, []search.Identifier{paris.ID, ljubljana.ID}},
+		{search.Query{Text: "Capital slovenia"}, []search.Identifier{ljubljana.ID}},
+		{search.Query{Text: "maribor"}, []search.Identifier{maribor.ID}},
+		// Names and text claims are matched in all languages.
+		{search.Query{Text: "laibach"}, []search.Identifier{ljubljana.ID}},
+		{search.Query{Text: "mesto sloveniji"}, []search.Identifier{maribor.ID}},
+		// HTML tags are not matched.
+		{search.Query{Text: "b"}, []search.Identifier{}},
+		{search.Query{Props: [][]search.Identifier{{search.GetStandardPropertyID("ALIAS")}}}, []search.Identifier{ljubljana.ID}},
+		{search.Query{Classes: []search.Identifier{search.GetStandardPropertyID("ITEM")}}, []search.Identifier{ljubljana.ID}},
+		{search.Query{Text: "france", Classes: []search.Identifier{search.GetStandardPropertyID("ITEM")}}, []search.Identifier{}},
+		{search.Query{Times: []search.TimeFilter{timeFilter(backendTestTimeProp, 1100, 1199)}}, []search.Identifier{ljubljana.ID, maribor.ID}},
+		{search.Query{Times: []search.TimeFilter{timeFilter(backendTestTimeProp, 1145, 1160)}}, []search.Identifier{maribor.ID}},
+		// The interval intersects Maribor's decade.
+		{search.Query{Times: []search.TimeFilter{timeFilter(backendTestTimeProp, 1168, 1300)}}, []search.Identifier{maribor.ID}},
+		{search.Query{Times: []search.TimeFilter{timeFilter(backendTestTimeProp, 1170, 1300)}}, []search.Identifier{}},
+		{search.Query{Times: []search.TimeFilter{timeFilter(search.GetStandardPropertyID("ALIAS"), 1100, 1199)}}, []search.Identifier{}},
+		{search.Query{Text: "capital", Times: []search.TimeFilter{timeFilter(backendTestTimeProp, 1100, 1199)}}, []search.Identifier{ljubljana.ID}},
+		{search.Query{References: []search.Identifier{search.GetStandardPropertyID("ITEM")}}, []search.Identifier{ljubljana.ID}},
+		{search.Query{References: []search.Identifier{backendTestTimeProp, missing}}, []search.Identifier{ljubljana.ID, maribor.ID}},
+		{search.Query{References: []search.Identifier{missing}}, []search.Identifier{}},
+	}
+	for _, tt := range tests {
+		result, errE := backend.Search(ctx, tt.Query, search.SearchOptions{Size: 10, Preference: ""})
+		require.NoError(t, errE, tt.Query)
+		assert.Equal(t, tt.Expected, result.IDs, tt.Query)
+		assert.Equal(t, int64(len(tt.Expected)), result.Total, tt.Query)
+	}
+
+	result, errE := backend.Search(ctx, search.Query{}, search.SearchOptions{Size: 1, Preference: ""})
+	require.NoError(t, errE)
+	assert.Equal(t, []search.Identifier{paris.ID}, result.IDs)
+	assert.Equal(t, int64(3), result.Total)
+
+	scrolled := []search.Identifier{}
+	versions := map[search.Identifier]search.Version{}
+	errE = backend.Scroll(ctx, search.Query{Text: "slovenia"}, search.FullProjection(), func(document *search.Document, version search.Version) errors.E {
+		scrolled = append(scrolled, document.ID)
+		versions[document.ID] = version
+		return nil
+	})
+	require.NoError(t, errE)
+	// Scroll order is not specified.
+	assert.ElementsMatch(t, []search.Identifier{ljubljana.ID, maribor.ID}, scrolled)
+
+	// Replacing a document updates the index.
+	maribor.Name["en"] = "Marburg"
+	_, errE = backend.Bulk(ctx, []*search.Document{maribor})
+	require.NoError(t, errE)
+	require.NoError(t, backend.Refresh(ctx))
+	result, errE = backend.Search(ctx, search.Query{Text: "maribor"}, search.SearchOptions{Size: 10, Preference: ""})
+	require.NoError(t, errE)
+	assert.Empty(t, result.IDs)
+	result, errE = backend.Search(ctx, search.Query{Text: "marburg"}, search.SearchOptions{Size: 10, Preference: ""})
+	require.NoError(t, errE)
+	assert.Equal(t, []search.Identifier{maribor.ID}, result.IDs)
+
+	// Conditional updates replace only documents which have not changed since they have been read.
+	lutetia, version, errE := backend.Get(ctx, paris.ID, search.FullProjection())
+	require.NoError(t, errE)
+	lutetia.Name["en"] = "Lutetia"
+	ljubljana.Name["en"] = "Emona"
+	maribor.Name["en"] = "Marpurch"
+	removed := backendTestDocument("Atlantis", "A missing city.", 0.1)
+	bulkResult, errE := backend.BulkUpdate(ctx, []search.Update{
+		{Document: lutetia, Version: version},
+		// Ljubljana has not been changed since it has been scrolled.
+		{Document: ljubljana, Version: versions[ljubljana.ID]},
+		// Maribor has been replaced since it has been scrolled.
+		{Document: maribor, Version: versions[maribor.ID]},
+		{Document: removed, Version: version},
+	})
+	require.NoError(t, errE)
+	require.NoError(t, backend.Refresh(ctx))
+	assert.ElementsMatch(t, []search.Identifier{maribor.ID, removed.ID}, bulkResult.Conflicts)
+	assert.Empty(t, bulkResult.Temporary)
+	document, _, errE = backend.Get(ctx, paris.ID, search.FullProjection())
+	require.NoError(t, errE)
+	assert.Equal(t, "Lutetia", document.Name["en"])
+	document, _, errE = backend.Get(ctx, ljubljana.ID, search.FullProjection())
+	require.NoError(t, errE)
+	assert.Equal(t, "Emona", document.Name["en"])
+	document, _, errE = backend.Get(ctx, maribor.ID, search.FullProjection())
+	require.NoError(t, errE)
+	assert.Equal(t, "Marburg", document.Name["en"])
+	_, _, errE = backend.Get(ctx, removed.ID, search.FullProjection())
+	assert.True(t, errors.Is(errE, search.ErrNotFound))
+
+	// The same version cannot be used again because the document has changed.
+	bulkResult, errE = backend.BulkUpdate(ctx, []search.Update{{Document: lutetia, Version: version}})
+	require.NoError(t, errE)
+	assert.Equal(t, []search.Identifier{paris.ID}, bulkResult.Conflicts)
+}
+
+func TestMemoryBackend(t *testing.T) {
+	testBackend(t, search.NewMemoryBackend())
+}
+
+func TestEmbeddedBackend(t *testing.T) {
+	dataDir := t.TempDir()
+
+	backend, errE := search.OpenEmbeddedBackend(dataDir, "docs")
+	require.NoError(t, errE)
+	testBackend(t, backend)
+	require.NoError(t, backend.Close())
+
+	// Documents are persisted.
+	backend, errE = search.OpenEmbeddedBackend(dataDir, "docs")
+	require.NoError(t, errE)
+	defer backend.Close()
+	result, errE := backend.Search(context.Background(), search.Query{Text: "capital"}, search.SearchOptions{Size: 10, Preference: ""})
+	require.NoError(t, errE)
+	assert.Len(t, result.IDs, 2)
+}
+
+func TestEmbeddedBackendFullText(t *testing.T) {
+	backend, errE := search.OpenEmbeddedBackend(t.TempDir(), "docs")
+	require.NoError(t, errE)
+	defer backend.Close()
+
+	documents := populateTestBackend(t, backend)
+	ljubljana, maribor, paris := documents[0], documents[1], documents[2]
+
+	tests := []struct {
+		Text     string
+		Expected []search.Identifier
+	}{
+		// Words are stemmed.
+		{"capitals", []search.Identifier{paris.ID, ljubljana.ID}},
+		{"cities", []search.Identifier{maribor.ID}},
+		// Stop words are ignored.
+		{"capital of slovenia", []search.Identifier{ljubljana.ID}},
+		// Words can be found in different fields.
+		{"ljubljana capital", []search.Identifier{ljubljana.ID}},
+		{`"capital of france"`, []search.Identifier{paris.ID}},
+		{`"slovenia capital"`, []search.Identifier{}},
+		{"slov*", []search.Identifier{ljubljana.ID, maribor.ID}},
+		{"slov", []search.Identifier{}},
+		{`"city in" slov*`, []search.Identifier{maribor.ID}},
+		// Prefixes are matched against words which are not stemmed.
+		{"capita*", []search.Identifier{paris.ID, ljubljana.ID}},
+		{"citi*", []search.Identifier{}},
+		{"laib*", []search.Identifier{ljubljana.ID}},
+		{`"v sloveniji"`, []search.Identifier{maribor.ID}},
+	}
+	for _, tt := range tests {
+		result, errE := backend.Search(context.Background(), search.Query{Text: tt.Text}, search.SearchOptions{Size: 10, Preference: ""})
+		require.NoError(t, errE, tt.Text)
+		assert.ElementsMatch(t, tt.Expected, result.IDs, tt.Text)
+	}
+
+	// A document in which the word is a larger part of the text is more relevant,
+	// even if it has a lower score.
+	result, errE := backend.Search(context.Background(), search.Query{Text: "slovenia"}, search.SearchOptions{Size: 10, Preference: ""})
+	require.NoError(t, errE)
+	assert.Equal(t, []search.Identifier{maribor.ID, ljubljana.ID}, result.IDs)
+}
+
+func TestElasticBackendBulkFailures(t *testing.T) {
+	written := backendTestDocument("Ljubljana", "Capital of Slovenia.", 0.8)
+	blocked := backendTestDocument("Maribor", "A city in Slovenia.", 0.5)
+	rejected := backendTestDocument("Paris", "Capital of France.", 0.9)
+
+	// Writes to the old index are blocked while the last pass of reindexing copies
+	// documents to the new index, which ElasticSearch reports as a cluster block.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasSuffix(req.URL.Path, "/_bulk") {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"took":1,"errors":true,"items":[` +
+			`{"index":{"_index":"docs","_id":"` + string(written.ID) + `","status":201}},` +
+			`{"index":{"_index":"docs","_id":"` + string(blocked.ID) + `","status":403,` +
+			`"error":{"type":"cluster_block_exception","reason":"index [docs] blocked by: [FORBIDDEN/8/index write (api)];"}}},` +
+			`{"index":{"_index":"docs","_id":"` + string(rejected.ID) + `","status":400,` +
+			`"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := elastic.NewClient(elastic.SetURL(server.URL), elastic.SetSniff(false), elastic.SetHealthcheck(false))
+	require.NoError(t, err)
+	backend := search.NewElasticBackend(client, "docs")
+
+	result, errE := backend.Bulk(context.Background(), []*search.Document{written, blocked, rejected})
+	require.NoError(t, errE)
+	assert.Empty(t, result.Conflicts)
+	assert.Equal(t, []search.Identifier{blocked.ID}, result.Temporary)
+	assert.Equal(t, map[search.Identifier]string{rejected.ID: "mapper_parsing_exception: failed to parse"}, result.Failed)
+}
+
+func TestLoadPropertyHierarchy(t *testing.T) {
+	parent := propertyReference("parent")
+	child := backendTestDocument("child", "", 0.5)
+	child.Active.Relation = search.RelationClaims{
+		{
+			CoreClaim: search.CoreClaim{
+				ID:         search.Identifier(identifier.NewRandom()),
+				Confidence: 1.0,
+			},
+			Prop: search.GetStandardPropertyReference("SUBPROPERTY_OF"),
+			To:   parent,
+		},
+	}
+
+	backend := search.NewMemoryBackend()
+	_, errE := backend.Bulk(context.Background(), []*search.Document{child})
+	require.NoError(t, errE)
+
+	h, errE := search.LoadPropertyHierarchy(context.Background(), backend)
+	require.NoError(t, errE)
+	assert.Equal(t, []search.DocumentReference{parent}, h.Parents(child.ID))
+}
+
+func TestServiceWithMemoryBackend(t *testing.T) {
+	backend := search.NewMemoryBackend()
+	documents := populateTestBackend(t, backend)
+	ljubljana, paris := documents[0], documents[2]
+
+	s := &search.Service{
+		Backend: backend,
+		Log:     zerolog.Nop(),
+		// We use development mode so that built frontend files are not needed.
+		Development: "http://localhost:5173",
+	}
+	handler, errE := s.RouteWith(httprouter.New(), "test")
+	require.NoError(t, errE)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/d/" + string(paris.ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var document search.Document
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &document))
+	assert.Equal(t, paris.Name, document.Name)
+
+	w = get("/d/" + identifier.NewRandom())
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = get("/d/" + backendTestAlias)
+	if assert.Equal(t, http.StatusMovedPermanently, w.Code) {
+		assert.Equal(t, "/d/"+string(ljubljana.ID), w.Header().Get("Location"))
+	}
+
+	// The frontend follows the redirect of an aliased ID (with query parameters
+	// preserved) and determines the canonical ID from the final URL.
+	w = get("/d/" + backendTestAlias + "?lang=en")
+	require.Equal(t, http.StatusMovedPermanently, w.Code)
+	location := w.Header().Get("Location")
+	assert.Equal(t, "/d/"+string(ljubljana.ID)+"?lang=en", location)
+	w = get(location)
+	assert.Equal(t, http.StatusOK, w.Code)
+	document = search.Document{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &document))
+	assert.Equal(t, search.Name{"en": ljubljana.Name["en"]}, document.Name)
+
+	w = get("/d?q=capital")
+	require.Equal(t, http.StatusOK, w.Code)
+	var query struct {
+		S string `json:"s"`
+		Q string `json:"q"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &query))
+
+	w = get("/d?s=" + query.S + "&q=capital")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Peerdb-Total"))
+	var results []struct {
+		ID string `json:"_id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, string(paris.ID), results[0].ID)
+		assert.Equal(t, string(ljubljana.ID), results[1].ID)
+	}
+
+	w = get("/d?s=" + query.S + "&q=capital&time=" + string(backendTestTimeProp) + ":1100..1199")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Peerdb-Total"))
+
+	w = get("/d?s=" + query.S + "&q=capital&time=" + string(backendTestTimeProp) + ":..1143-12-31T23:59:59Z")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("Peerdb-Total"))
+
+	w = get("/d?s=" + query.S + "&q=capital&time=" + string(backendTestTimeProp) + ":1144-12-31T23:59:59Z..")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Peerdb-Total"))
+
+	for _, value := range []string{string(backendTestTimeProp), string(backendTestTimeProp) + ":1100", "invalid:1100..1199", string(backendTestTimeProp) + ":1199..1100"} {
+		w = get("/d?s=" + query.S + "&q=capital&time=" + value)
+		assert.Equal(t, http.StatusBadRequest, w.Code, value)
+	}
+
+	missing := identifier.NewRandom()
+	w = get("/batch?format=jsonl&id=" + string(paris.ID) + "," + missing + ",invalid&id=" + string(ljubljana.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	require.Len(t, lines, 4)
+	var line struct {
+		ID       string          `json:"_id"`
+		Document search.Document `json:"doc"`
+		Error    string          `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &line))
+	assert.Equal(t, string(paris.ID), line.ID)
+	assert.Equal(t, paris.Name, line.Document.Name)
+	assert.JSONEq(t, `{"_id":"`+missing+`","error":"not found"}`, lines[1])
+	assert.JSONEq(t, `{"_id":"invalid","error":"invalid ID"}`, lines[2])
+	require.NoError(t, json.Unmarshal([]byte(lines[3]), &line))
+	assert.Equal(t, string(ljubljana.ID), line.ID)
+	assert.Equal(t, ljubljana.Name, line.Document.Name)
+}
+
+// failingBackend is a backend which fails to get documents after the first call to GetMany.
+type failingBackend struct {
+	search.Backend
+	calls int
+}
+
+func (b *failingBackend) GetMany(ctx context.Context, ids []search.Identifier, projection search.Projection) (map[search.Identifier]*search.Document, errors.E) {
+	b.calls++
+	if b.calls > 1 {
+		return nil, errors.New("backend failed")
+	}
+	return b.Backend.GetMany(ctx, ids, projection)
+}
+
+func TestDocumentBatchStreamError(t *testing.T) {
+	backend := search.NewMemoryBackend()
+	documents := populateTestBackend(t, backend)
+	paris := documents[2]
+
+	s := &search.Service{
+		Backend: &failingBackend{Backend: backend, calls: 0},
+		Log:     zerolog.Nop(),
+		// We use development mode so that built frontend files are not needed.
+		Development: "http://localhost:5173",
+	}
+	handler, errE := s.RouteWith(httprouter.New(), "test")
+	require.NoError(t, errE)
+
+	// Enough IDs that documents are fetched from the backend more than once.
+	ids := []string{string(paris.ID)}
+	for len(ids) < 200 {
+		ids = append(ids, identifier.NewRandom())
+	}
+	req := httptest.NewRequest(http.MethodGet, "/batch?format=jsonl&id="+strings.Join(ids, ","), nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	// The response has already started when the backend failed.
+	require.Equal(t, http.StatusOK, w.Code)
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	require.Greater(t, len(lines), 1)
+	assert.Less(t, len(lines), len(ids)+1)
+	assert.Contains(t, lines[0], string(paris.ID))
+	// The last line reports the error.
+	assert.JSONEq(t, `{"error":"internal server error"}`, lines[len(lines)-1])
+}
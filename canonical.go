@@ -0,0 +1,178 @@
+package search
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/x"
+)
+
+// sortClaims sorts claims of every claim type by their IDs, recursively
+// sorting meta claims as well.
+func sortClaims(c *ClaimTypes) {
+	if c == nil {
+		return
+	}
+
+	sort.SliceStable(c.Identifier, func(i, j int) bool { return c.Identifier[i].ID < c.Identifier[j].ID })
+	for i := range c.Identifier {
+		sortClaims(c.Identifier[i].Meta)
+	}
+	sort.SliceStable(c.Reference, func(i, j int) bool { return c.Reference[i].ID < c.Reference[j].ID })
+	for i := range c.Reference {
+		sortClaims(c.Reference[i].Meta)
+	}
+	sort.SliceStable(c.Text, func(i, j int) bool { return c.Text[i].ID < c.Text[j].ID })
+	for i := range c.Text {
+		sortClaims(c.Text[i].Meta)
+	}
+	sort.SliceStable(c.String, func(i, j int) bool { return c.String[i].ID < c.String[j].ID })
+	for i := range c.String {
+		sortClaims(c.String[i].Meta)
+	}
+	sort.SliceStable(c.Amount, func(i, j int) bool { return c.Amount[i].ID < c.Amount[j].ID })
+	for i := range c.Amount {
+		sortClaims(c.Amount[i].Meta)
+	}
+	sort.SliceStable(c.AmountRange, func(i, j int) bool { return c.AmountRange[i].ID < c.AmountRange[j].ID })
+	for i := range c.AmountRange {
+		sortClaims(c.AmountRange[i].Meta)
+	}
+	sort.SliceStable(c.Enumeration, func(i, j int) bool { return c.Enumeration[i].ID < c.Enumeration[j].ID })
+	for i := range c.Enumeration {
+		sortClaims(c.Enumeration[i].Meta)
+	}
+	sort.SliceStable(c.Relation, func(i, j int) bool { return c.Relation[i].ID < c.Relation[j].ID })
+	for i := range c.Relation {
+		sortClaims(c.Relation[i].Meta)
+	}
+	sort.SliceStable(c.File, func(i, j int) bool { return c.File[i].ID < c.File[j].ID })
+	for i := range c.File {
+		sortClaims(c.File[i].Meta)
+	}
+	sort.SliceStable(c.NoValue, func(i, j int) bool { return c.NoValue[i].ID < c.NoValue[j].ID })
+	for i := range c.NoValue {
+		sortClaims(c.NoValue[i].Meta)
+	}
+	sort.SliceStable(c.UnknownValue, func(i, j int) bool { return c.UnknownValue[i].ID < c.UnknownValue[j].ID })
+	for i := range c.UnknownValue {
+		sortClaims(c.UnknownValue[i].Meta)
+	}
+	sort.SliceStable(c.Time, func(i, j int) bool { return c.Time[i].ID < c.Time[j].ID })
+	for i := range c.Time {
+		sortClaims(c.Time[i].Meta)
+	}
+	sort.SliceStable(c.TimeRange, func(i, j int) bool { return c.TimeRange[i].ID < c.TimeRange[j].ID })
+	for i := range c.TimeRange {
+		sortClaims(c.TimeRange[i].Meta)
+	}
+}
+
+// canonicalValue normalizes numbers in a decoded JSON value. Integers are kept
+// as they are (so that large integers do not lose precision) while other numbers
+// are converted to float64 so that they are formatted in the same way, with
+// negative zero changed to zero.
+func canonicalValue(value interface{}) (interface{}, errors.E) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, element := range v {
+			c, errE := canonicalValue(element)
+			if errE != nil {
+				return nil, errE
+			}
+			v[key] = c
+		}
+		return v, nil
+	case []interface{}:
+		for i, element := range v {
+			c, errE := canonicalValue(element)
+			if errE != nil {
+				return nil, errE
+			}
+			v[i] = c
+		}
+		return v, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return json.Number(strconv.FormatInt(i, 10)), nil //nolint:gomnd
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if f == 0 {
+			// Negative zero is equal to zero, so this changes it to (positive) zero.
+			f = 0
+		}
+		return f, nil
+	default:
+		return v, nil
+	}
+}
+
+// CanonicalJSON returns a deterministic JSON encoding of the document: the same
+// logical document is always encoded into the same bytes.
+//
+// Claims of every claim type are sorted by their IDs, object keys (including
+// language keys of translatable strings) are sorted, and numbers are normalized.
+// Document ID is not part of the encoding (as it is not part of regular JSON
+// encoding either). The document itself is not modified.
+func (d *Document) CanonicalJSON() ([]byte, errors.E) {
+	data, errE := x.MarshalWithoutEscapeHTML(d)
+	if errE != nil {
+		return nil, errE
+	}
+
+	// We make a copy of the document so that we can sort claims.
+	var document Document
+	errE = x.UnmarshalWithoutUnknownFields(data, &document)
+	if errE != nil {
+		return nil, errE
+	}
+	sortClaims(document.Active)
+	sortClaims(document.Inactive)
+
+	data, errE = x.MarshalWithoutEscapeHTML(&document)
+	if errE != nil {
+		return nil, errE
+	}
+
+	// We decode into generic values so that all object keys get sorted when
+	// encoding them again and so that we can normalize numbers.
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	value, errE = canonicalValue(value)
+	if errE != nil {
+		return nil, errE
+	}
+
+	return x.MarshalWithoutEscapeHTML(value)
+}
+
+// Hash returns a hash of the document's content. It is a SHA-256 hash of
+// the document's canonical JSON encoding, encoded with URL-safe base64.
+//
+// Documents with the same content have the same hash, even if they have
+// different IDs.
+func (d *Document) Hash() (string, errors.E) {
+	data, errE := d.CanonicalJSON()
+	if errE != nil {
+		return "", errE
+	}
+	return hashCanonicalJSON(data), nil
+}
+
+func hashCanonicalJSON(data []byte) string {
+	hash := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
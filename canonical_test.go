@@ -0,0 +1,87 @@
+package search_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/peerdb/search"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	prop := search.DocumentReference{
+		ID:    "8mnDdqkCFYLERqYstB4WCF",
+		Name:  search.Name{"sl": "opis", "en": "description"},
+		Score: 0.5,
+	}
+	doc1 := search.Document{
+		CoreDocument: search.CoreDocument{
+			ID:    "XkbTJqwFCFkfoxMBXow4HU",
+			Name:  search.Name{"sl": "Ime", "en": "Name"},
+			Score: 0.5,
+		},
+		Active: &search.ClaimTypes{
+			Amount: search.AmountClaims{
+				{
+					CoreClaim: search.CoreClaim{ID: "B8uA1GpBmqp9m8HMY1EUYz", Confidence: 1.0},
+					Prop:      prop,
+					Amount:    math.Copysign(0, -1),
+					Unit:      search.AmountUnitMetre,
+				},
+				{
+					CoreClaim: search.CoreClaim{ID: "A8uA1GpBmqp9m8HMY1EUYz", Confidence: 1.0},
+					Prop:      prop,
+					Amount:    1.5,
+					Unit:      search.AmountUnitMetre,
+				},
+			},
+		},
+	}
+	doc2 := search.Document{
+		CoreDocument: search.CoreDocument{
+			ID:    "YkbTJqwFCFkfoxMBXow4HU",
+			Name:  search.Name{"en": "Name", "sl": "Ime"},
+			Score: 0.5,
+		},
+		Active: &search.ClaimTypes{
+			Amount: search.AmountClaims{
+				{
+					CoreClaim: search.CoreClaim{ID: "A8uA1GpBmqp9m8HMY1EUYz", Confidence: 1.0},
+					Prop:      prop,
+					Amount:    1.5,
+					Unit:      search.AmountUnitMetre,
+				},
+				{
+					CoreClaim: search.CoreClaim{ID: "B8uA1GpBmqp9m8HMY1EUYz", Confidence: 1.0},
+					Prop:      prop,
+					Amount:    0,
+					Unit:      search.AmountUnitMetre,
+				},
+			},
+		},
+	}
+
+	data, errE := doc1.CanonicalJSON()
+	require.NoError(t, errE)
+	assert.Equal(t, `{"active":{"amount":[`+
+		`{"_id":"A8uA1GpBmqp9m8HMY1EUYz","amount":1.5,"confidence":1,"prop":{"_id":"8mnDdqkCFYLERqYstB4WCF","name":{"en":"description","sl":"opis"},"score":0.5},"unit":"m"},`+
+		`{"_id":"B8uA1GpBmqp9m8HMY1EUYz","amount":0,"confidence":1,"prop":{"_id":"8mnDdqkCFYLERqYstB4WCF","name":{"en":"description","sl":"opis"},"score":0.5},"unit":"m"}`+
+		`]},"name":{"en":"Name","sl":"Ime"},"score":0.5}`, string(data))
+
+	// The document itself is not modified.
+	assert.Equal(t, search.Identifier("B8uA1GpBmqp9m8HMY1EUYz"), doc1.Active.Amount[0].ID)
+
+	hash1, errE := doc1.Hash()
+	require.NoError(t, errE)
+	hash2, errE := doc2.Hash()
+	require.NoError(t, errE)
+	// Documents have the same content, just different IDs.
+	assert.Equal(t, hash1, hash2)
+
+	doc2.Active.Amount[0].Amount = 2.5
+	hash2, errE = doc2.Hash()
+	require.NoError(t, errE)
+	assert.NotEqual(t, hash1, hash2)
+}
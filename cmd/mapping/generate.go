@@ -4,11 +4,15 @@ import (
 	"bytes"
 	_ "embed"
 	"encoding/json"
-	"io"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"text/template"
 
 	"gitlab.com/tozd/go/errors"
+
+	"gitlab.com/peerdb/search"
 )
 
 //go:embed index.tmpl
@@ -25,388 +29,189 @@ type claimType struct {
 	Fields []field
 }
 
-// TODO: Generate automatically from the Document struct.
-var claimTypes = []claimType{
-	{
-		"id",
-		[]field{
-			{
-				"prop",
-				"_id",
-				`{
-					"properties": {
-						"_id": {
-							"type": "keyword"
-						}
-					}
-				}`,
-			},
-			{
-				"id",
-				"",
-				`{
-					"type": "keyword",
-					"normalizer": "id_normalizer"
-				}`,
-			},
-		},
-	},
-	{
-		"ref",
-		[]field{
-			{
-				"prop",
-				"_id",
-				`{
-					"properties": {
-						"_id": {
-							"type": "keyword"
-						}
-					}
-				}`,
-			},
-			{
-				"iri",
-				"",
-				`{
-					"type": "keyword",
-					"doc_values": false
-				}`,
-			},
-		},
-	},
-	{
-		"text",
-		[]field{
-			{
-				"prop",
-				"_id",
-				`{
-					"properties": {
-						"_id": {
-							"type": "keyword"
-						}
-					}
-				}`,
-			},
-			{
-				"html",
-				"",
-				`{
-					"properties": {
-						"en": {
-							"type": "text",
-							"analyzer": "english_html"
-						}
-					}
-				}`,
-			},
-		},
-	},
-	{
-		"string",
-		[]field{
-			{
-				"prop",
-				"_id",
-				`{
-					"properties": {
-						"_id": {
-							"type": "keyword"
-						}
-					}
-				}`,
-			},
-			{
-				"string",
-				"",
-				`{
-					"type": "keyword"
-				}`,
-			},
-		},
-	},
-	{
-		"amount",
-		[]field{
-			{
-				"prop",
-				"_id",
-				`{
-					"properties": {
-						"_id": {
-							"type": "keyword"
-						}
-					}
-				}`,
-			},
-			{
-				"amount",
-				"",
-				`{
-					"type": "double"
-				}`,
-			},
-			{
-				"unit",
-				"",
-				`{
-					"type": "keyword"
-				}`,
-			},
-		},
-	},
-	{
-		"amountRange",
-		[]field{
-			{
-				"prop",
-				"_id",
-				`{
-					"properties": {
-						"_id": {
-							"type": "keyword"
-						}
-					}
-				}`,
-			},
-			{
-				"lower",
-				"",
-				`{
-					"type": "double"
-				}`,
-			},
-			{
-				"upper",
-				"",
-				`{
-					"type": "double"
-				}`,
-			},
-			{
-				"unit",
-				"",
-				`{
-					"type": "keyword"
-				}`,
-			},
-		},
-	},
-	{
-		"enum",
-		[]field{
-			{
-				"prop",
-				"_id",
-				`{
-					"properties": {
-						"_id": {
-							"type": "keyword"
-						}
-					}
-				}`,
-			},
-			{
-				"enum",
-				"",
-				`{
-					"type": "keyword"
-				}`,
-			},
-		},
-	},
-	{
-		"rel",
-		[]field{
-			{
-				"prop",
-				"_id",
-				`{
-					"properties": {
-						"_id": {
-							"type": "keyword"
-						}
-					}
-				}`,
-			},
-			{
-				"to",
-				"_id",
-				`{
-					"properties": {
-						"_id": {
-							"type": "keyword"
-						}
-					}
-				}`,
-			},
-		},
-	},
-	{
-		"file",
-		[]field{
-			{
-				"prop",
-				"_id",
-				`{
-					"properties": {
-						"_id": {
-							"type": "keyword"
-						}
-					}
-				}`,
-			},
-			{
-				"type",
-				"",
-				`{
-					"type": "keyword"
-				}`,
-			},
-			{
-				"url",
-				"",
-				`{
-					"type": "keyword",
-					"doc_values": false
-				}`,
-			},
-		},
-	},
-	{
-		"none",
-		[]field{
-			{
-				"prop",
-				"_id",
-				`{
-					"properties": {
-						"_id": {
-							"type": "keyword"
-						}
-					}
-				}`,
-			},
-		},
-	},
-	{
-		"unknown",
-		[]field{
-			{
-				"prop",
-				"_id",
-				`{
-					"properties": {
-						"_id": {
-							"type": "keyword"
-						}
-					}
-				}`,
-			},
-		},
-	},
-	{
-		"time",
-		[]field{
-			{
-				"prop",
-				"_id",
-				`{
-					"properties": {
-						"_id": {
-							"type": "keyword"
-						}
-					}
-				}`,
-			},
-			{
-				"timestamp",
-				"",
-				`{
-					"type": "date",
-					"format": "uuuu-MM-dd'T'HH:mm:ssX",
-					"ignore_malformed": true
-				}`,
-			},
-			{
-				"precision",
-				"",
-				`{
-					"type": "keyword"
-				}`,
-			},
-		},
-	},
-	{
-		"timeRange",
-		[]field{
-			{
-				"prop",
-				"_id",
-				`{
-					"properties": {
-						"_id": {
-							"type": "keyword"
-						}
-					}
-				}`,
-			},
-			{
-				"lower",
-				"",
-				`{
-					"type": "date",
-					"format": "uuuu-MM-dd'T'HH:mm:ssX",
-					"ignore_malformed": true
-				}`,
-			},
-			{
-				"upper",
-				"",
-				`{
-					"type": "date",
-					"format": "uuuu-MM-dd'T'HH:mm:ssX",
-					"ignore_malformed": true
-				}`,
-			},
-			{
-				"precision",
-				"",
-				`{
-					"type": "keyword"
-				}`,
-			},
-		},
-	},
+// documentReferenceDefinition is the mapping for DocumentReference fields.
+// Only their IDs are indexed and they are copied to embeddedIds.
+const documentReferenceDefinition = `{
+	"properties": {
+		"_id": {
+			"type": "keyword",
+			"copy_to": "embeddedIds"
+		}
+	}
+}`
+
+var documentReferenceType = reflect.TypeOf(search.DocumentReference{})
+
+// getClaimTypes returns claim types with their fields by reflecting over search.ClaimTypes
+// and claim structs. Fields are mapped based on their "es" struct tag, which contains the
+// ElasticSearch field type followed by comma-separated options (e.g., "keyword,doc_values=false").
+// Option "name" overrides the field name (which is otherwise taken from the "json" struct tag).
+// Fields with "es" struct tag set to "-" are not indexed. Every other field must have the tag,
+// except DocumentReference fields which are mapped to their IDs.
+func getClaimTypes() ([]claimType, errors.E) {
+	claimTypes := []claimType{}
+	t := reflect.TypeOf(search.ClaimTypes{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := getJSONName(f)
+		if f.Type.Kind() != reflect.Slice || f.Type.Elem().Kind() != reflect.Struct {
+			errE := errors.New("claim type field is not a slice of claims")
+			errors.Details(errE)["field"] = f.Name
+			return nil, errE
+		}
+		fields, errE := getFields(f.Type.Elem())
+		if errE != nil {
+			errors.Details(errE)["claimType"] = name
+			return nil, errE
+		}
+		claimTypes = append(claimTypes, claimType{
+			Name:   name,
+			Fields: fields,
+		})
+	}
+	return claimTypes, nil
 }
 
-func generate(config *Config) errors.E {
+func getFields(t reflect.Type) ([]field, errors.E) {
+	fields := []field{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		// Fields of CoreClaim are mapped in the template.
+		if f.Anonymous && f.Type == reflect.TypeOf(search.CoreClaim{}) {
+			continue
+		}
+
+		tag, ok := f.Tag.Lookup("es")
+		if tag == "-" {
+			continue
+		}
+		if !ok {
+			if f.Type == documentReferenceType {
+				fields = append(fields, field{
+					Name:       getJSONName(f),
+					EmbeddedID: "_id",
+					Definition: documentReferenceDefinition,
+				})
+				continue
+			}
+			errE := errors.New(`field is missing "es" struct tag`)
+			errors.Details(errE)["field"] = f.Name
+			return nil, errE
+		}
+
+		name, definition, errE := parseTag(tag)
+		if errE != nil {
+			errors.Details(errE)["field"] = f.Name
+			return nil, errE
+		}
+		if name == "" {
+			name = getJSONName(f)
+		}
+		if name == "" || name == "-" {
+			errE := errors.New("field has no name")
+			errors.Details(errE)["field"] = f.Name
+			return nil, errE
+		}
+		// We index only English translations for now.
+		if f.Type.Kind() == reflect.Map {
+			definition = `{"properties": {"en": ` + definition + `}}`
+		}
+		fields = append(fields, field{
+			Name:       name,
+			EmbeddedID: "",
+			Definition: definition,
+		})
+	}
+	return fields, nil
+}
+
+func getJSONName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+// parseTag parses the "es" struct tag and returns the field name
+// override (if any) and the JSON definition of the field's mapping.
+// Option values "true", "false", and numbers are not quoted.
+func parseTag(tag string) (string, string, errors.E) {
+	parts := strings.Split(tag, ",")
+	if parts[0] == "" {
+		errE := errors.New(`"es" struct tag is missing a type`)
+		errors.Details(errE)["tag"] = tag
+		return "", "", errE
+	}
+
+	name := ""
+	var definition strings.Builder
+	definition.WriteString(`{"type": `)
+	definition.WriteString(strconv.Quote(parts[0]))
+	for _, part := range parts[1:] {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || key == "" {
+			errE := errors.New(`invalid "es" struct tag option`)
+			errors.Details(errE)["tag"] = tag
+			errors.Details(errE)["option"] = part
+			return "", "", errE
+		}
+		if key == "name" {
+			name = value
+			continue
+		}
+		definition.WriteString(", ")
+		definition.WriteString(strconv.Quote(key))
+		definition.WriteString(": ")
+		if _, err := strconv.ParseFloat(value, 64); err == nil || value == "true" || value == "false" {
+			definition.WriteString(value)
+		} else {
+			definition.WriteString(strconv.Quote(value))
+		}
+	}
+	definition.WriteString("}")
+
+	return name, definition.String(), nil
+}
+
+// generateMapping returns the index configuration with mappings for all claim types.
+func generateMapping() ([]byte, errors.E) {
+	claimTypes, errE := getClaimTypes()
+	if errE != nil {
+		return nil, errE
+	}
+
 	t, err := template.New("indexTemplate").Parse(indexTemplate)
 	if err != nil {
-		return errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
 
 	var b bytes.Buffer
 	err = t.Execute(&b, claimTypes)
 	if err != nil {
-		return errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
 
 	var res bytes.Buffer
 	err = json.Indent(&res, b.Bytes(), "", "  ")
 	if err != nil {
-		return errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
 	res.WriteString("\n")
 
+	return res.Bytes(), nil
+}
+
+func generate(config *Config) errors.E {
+	res, errE := generateMapping()
+	if errE != nil {
+		return errE
+	}
+
 	f, err := os.Create(config.Output)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 	defer f.Close()
 
-	_, err = io.Copy(f, &res)
+	_, err = f.Write(res)
 	if err != nil {
 		return errors.WithStack(err)
 	}
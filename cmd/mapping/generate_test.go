@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexJSON(t *testing.T) {
+	generated, errE := generateMapping()
+	require.NoError(t, errE)
+
+	existing, err := os.ReadFile("../../index.json")
+	require.NoError(t, err)
+
+	assert.Equal(t, string(existing), string(generated), "index.json is out of date, regenerate it with: go run ./cmd/mapping")
+}
+
+func TestParseTag(t *testing.T) {
+	name, definition, errE := parseTag("keyword,doc_values=false,name=foo,ignore_above=256,format=uuuu")
+	require.NoError(t, errE)
+	assert.Equal(t, "foo", name)
+	assert.JSONEq(t, `{"type": "keyword", "doc_values": false, "ignore_above": 256, "format": "uuuu"}`, definition)
+
+	_, _, errE = parseTag("")
+	assert.Error(t, errE)
+	_, _, errE = parseTag("keyword,invalid")
+	assert.Error(t, errE)
+}
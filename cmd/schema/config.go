@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/alecthomas/kong"
+
+	"gitlab.com/peerdb/search/internal/cli"
+)
+
+// Config provides configuration.
+// It is used as configuration for Kong command-line parser as well.
+type Config struct {
+	Version kong.VersionFlag `short:"V" help:"Show program's version and exit."`
+	cli.LoggingConfig
+	Output string `short:"o" placeholder:"DIR" type:"existingdir" default:"schema" help:"Directory where to output generated JSON Schema files. Default: ${default}"`
+}
@@ -0,0 +1,557 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"gitlab.com/tozd/go/errors"
+
+	"gitlab.com/peerdb/search"
+)
+
+const (
+	schemaDraft = "https://json-schema.org/draft/2019-09/schema"
+	// Maximum number of enumeration values we try to marshal.
+	maxEnumValues = 1000
+)
+
+// keyValue is a member of a JSON object.
+type keyValue struct {
+	Key   string
+	Value interface{}
+}
+
+// object is a JSON object which is marshaled with members in order.
+type object []keyValue
+
+// marshal marshals v to JSON without escaping HTML characters.
+func marshal(v interface{}, indent string) ([]byte, errors.E) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	err := encoder.Encode(v)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, kv := range o {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		key, errE := marshal(kv.Key, "")
+		if errE != nil {
+			return nil, errE
+		}
+		buf.Write(bytes.TrimSpace(key))
+		buf.WriteString(":")
+		value, errE := marshal(kv.Value, "")
+		if errE != nil {
+			return nil, errE
+		}
+		buf.Write(bytes.TrimSpace(value))
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+// leafDefinitions are definitions for types whose JSON representation
+// cannot be determined by reflection (or has additional constraints).
+var leafDefinitions = map[reflect.Type]struct {
+	Name       string
+	Definition string
+}{
+	reflect.TypeOf(search.Identifier("")): {
+		"identifier",
+		`{
+			"description": "ID is 22 characters from base-58 alphabet. This corresponds roughly to 128 bits.",
+			"type": "string",
+			"minLength": 22,
+			"maxLength": 22,
+			"pattern": "^[123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz]{22}$"
+		}`,
+	},
+	reflect.TypeOf(search.Mnemonic("")): {
+		"mnemonic",
+		`{
+			"type": "string",
+			"pattern": "^[A-Z][A-Z0-9_]*[A-Z0-9]$"
+		}`,
+	},
+	reflect.TypeOf(search.Score(0)): {
+		"score",
+		`{
+			"type": "number",
+			"default": 0.0,
+			"minimum": -1.0,
+			"maximum": 1.0
+		}`,
+	},
+	reflect.TypeOf(search.Timestamp{}): {
+		"timestamp",
+		// We do not use "date-time" format because years can be negative and have more than 4 digits.
+		`{
+			"description": "Timestamp in the proleptic Gregorian calendar in UTC. Years use astronomical year numbering and can have more than 4 digits.",
+			"type": "string",
+			"pattern": "^-?\\d{4,}-\\d{2}-\\d{2}T\\d{2}:\\d{2}:\\d{2}Z$"
+		}`,
+	},
+	reflect.TypeOf(search.TranslatablePlainString{}): {
+		"translatablePlainString",
+		`{
+			"type": "object",
+			"patternProperties": {
+				"^[a-z]{2}(-[A-Z]{2})?$": {
+					"type": "string",
+					"contentMediaType": "text/plain"
+				}
+			},
+			"minProperties": 1,
+			"additionalProperties": false
+		}`,
+	},
+	reflect.TypeOf(search.TranslatableHTMLString{}): {
+		"translatableHtmlString",
+		`{
+			"type": "object",
+			"patternProperties": {
+				"^[a-z]{2}(-[A-Z]{2})?$": {
+					"type": "string",
+					"contentMediaType": "text/html"
+				}
+			},
+			"minProperties": 1,
+			"additionalProperties": false
+		}`,
+	},
+}
+
+const sortableTimestampDefinition = `{
+	"description": "Sortable numeric encoding of a timestamp (year multiplied by the number of seconds in a leap year, plus seconds since the start of the year). Years beyond around ±292 billion saturate. Used for indexing and ignored on input.",
+	"type": "integer"
+}`
+
+// computedDefinitions are definitions of properties which are computed when marshaling.
+// They are described with blank struct fields with "computed" struct tag.
+var computedDefinitions = map[string]struct {
+	Name       string
+	Definition string
+}{
+	"interval": {
+		"timeInterval",
+		`{
+			"description": "Effective interval of time the claim represents, computed from its timestamp(s), precision, and uncertainty. Bounds are sortable numeric encodings of timestamps (year multiplied by the number of seconds in a leap year, plus seconds since the start of the year) and are inclusive. Used for indexing and ignored on input.",
+			"type": "object",
+			"properties": {
+				"gte": {
+					"type": "integer"
+				},
+				"lte": {
+					"type": "integer"
+				}
+			},
+			"required": ["gte", "lte"],
+			"additionalProperties": false
+		}`,
+	},
+	"timestampSortable": {
+		"sortableTimestamp",
+		sortableTimestampDefinition,
+	},
+	"lowerSortable": {
+		"sortableTimestamp",
+		sortableTimestampDefinition,
+	},
+	"upperSortable": {
+		"sortableTimestamp",
+		sortableTimestampDefinition,
+	},
+}
+
+// annotations are additional keywords for definitions and their properties.
+// Keys are definition names, optionally followed by a property name and "items"
+// (for annotations of items of an array property).
+//
+//nolint:lll
+var annotations = map[string]object{
+	"coreClaim.confidence": {
+		{"description", "When the confidence of a claim is >= 0.5 or <= -0.5, it is an active claim, otherwise it is inactive claim. Negative confidence negates the claim."},
+	},
+	"coreClaim.meta": {
+		{"description", "Claims about the claim itself."},
+	},
+	"documentReference.claims": {
+		{"description", "Claims of the referenced document. Populated only when references are expanded at read time and never stored."},
+	},
+	"identifierClaim.id": {
+		{"description", "ID should be represented as a string we want to show. During indexing we might process it in a special way, e.g., remove trailing zeroes."},
+	},
+	"referenceClaim.iri": {
+		{"format", "iri"},
+	},
+	"stringClaim.string": {
+		{"contentMediaType", "text/plain"},
+	},
+	"amountClaim.uncertaintyLower": {
+		{"description", "The lower bound of the amount's uncertainty interval. Inclusive."},
+	},
+	"amountClaim.uncertaintyUpper": {
+		{"description", "The upper bound of the amount's uncertainty interval. Inclusive."},
+	},
+	"amountRangeClaim.lower": {
+		{"description", "The lower bound of the range. Inclusive."},
+	},
+	"amountRangeClaim.upper": {
+		{"description", "The upper bound of the range. Inclusive."},
+	},
+	"amountRangeClaim.uncertaintyLower": {
+		{"description", "The lower bound of the amount's uncertainty interval. Inclusive."},
+	},
+	"amountRangeClaim.uncertaintyUpper": {
+		{"description", "The upper bound of the amount's uncertainty interval. Inclusive."},
+	},
+	"enumerationClaim": {
+		{"description", "Used with properties which define one or more possible constant values to be used with them."},
+	},
+	"fileClaim.type": {
+		{"description", "A media type of the file."},
+	},
+	"fileClaim.url": {
+		{"format", "iri"},
+	},
+	"fileClaim.preview": {
+		{"description", "Preview of the file as images, 256 px wide and/or high."},
+	},
+	"fileClaim.preview.items": {
+		{"format", "iri"},
+	},
+	"noValueClaim": {
+		{"description", "Can be used with any property to mean that we know that the given property has no value, e.g., Elizabeth I of England had no spouse."},
+	},
+	"unknownValueClaim": {
+		{"description", "Can be used with any property to mean that the property has a value, but it is unknown which one, e.g., Pope Linus most certainly had a year of birth, but it is unknown to us."},
+	},
+	"timeClaim.uncertaintyLower": {
+		{"description", "The lower bound of the timestamp's uncertainty interval. Inclusive."},
+	},
+	"timeClaim.uncertaintyUpper": {
+		{"description", "The upper bound of the timestamp's uncertainty interval. Inclusive."},
+	},
+	"timeRangeClaim.lower": {
+		{"description", "The lower bound of the range. Inclusive."},
+	},
+	"timeRangeClaim.upper": {
+		{"description", "The upper bound of the range. Inclusive."},
+	},
+	"timeRangeClaim.uncertaintyLower": {
+		{"description", "The lower bound of the timestamp's uncertainty interval. Inclusive."},
+	},
+	"timeRangeClaim.uncertaintyUpper": {
+		{"description", "The upper bound of the timestamp's uncertainty interval. Inclusive."},
+	},
+	"amountUnit": {
+		{"description", `All amounts for the same quantity should use the same unit so that it is easier to compare values during search. The exception is unit "@" which stands for an unit for which conversion is not yet available or done, the real unit should then be described using an UNIT meta claim. "1" is used unit-less amounts. "/" represents ratio.`},
+	},
+	"timePrecision": {
+		{"description", "See precisions used in Wikidata (https://www.wikidata.org/wiki/Help:Dates)."},
+	},
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// generator collects definitions while reflecting over Go types.
+type generator struct {
+	definitions object
+	seen        map[string]bool
+}
+
+func lowerFirst(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+func ref(prefix, name string) object {
+	return object{{"$ref", prefix + "#/$defs/" + name}}
+}
+
+func withAnnotations(schema object, key string) object {
+	a, ok := annotations[key]
+	if !ok {
+		return schema
+	}
+	res := object{}
+	res = append(res, a...)
+	res = append(res, schema...)
+	return res
+}
+
+// define adds a definition under the name if it has not yet been added.
+// fn is called to make the definition.
+func (g *generator) define(name string, fn func() (interface{}, errors.E)) errors.E {
+	if g.seen[name] {
+		return nil
+	}
+	g.seen[name] = true
+	// We reserve the position so that definitions are in the order they are referenced.
+	i := len(g.definitions)
+	g.definitions = append(g.definitions, keyValue{name, nil})
+	definition, errE := fn()
+	if errE != nil {
+		return errE
+	}
+	if d, ok := definition.(object); ok {
+		definition = withAnnotations(d, name)
+	}
+	g.definitions[i].Value = definition
+	return nil
+}
+
+func (g *generator) defineRaw(name, definition string) errors.E {
+	return g.define(name, func() (interface{}, errors.E) {
+		return json.RawMessage(definition), nil
+	})
+}
+
+// typeSchema returns the schema for the type. Named types are added to definitions
+// and a reference to them is returned.
+func (g *generator) typeSchema(t reflect.Type, key string) (object, errors.E) {
+	if leaf, ok := leafDefinitions[t]; ok {
+		return ref("", leaf.Name), g.defineRaw(leaf.Name, leaf.Definition)
+	}
+
+	switch t.Kind() { //nolint:exhaustive
+	case reflect.Ptr:
+		return g.typeSchema(t.Elem(), key)
+	case reflect.String:
+		return withAnnotations(object{{"type", "string"}}, key), nil
+	case reflect.Float32, reflect.Float64:
+		return withAnnotations(object{{"type", "number"}}, key), nil
+	case reflect.Bool:
+		return withAnnotations(object{{"type", "boolean"}}, key), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !t.Implements(jsonMarshalerType) {
+			return withAnnotations(object{{"type", "integer"}}, key), nil
+		}
+		name := lowerFirst(t.Name())
+		return ref("", name), g.define(name, func() (interface{}, errors.E) {
+			return enumSchema(t)
+		})
+	case reflect.Slice:
+		items, errE := g.typeSchema(t.Elem(), key+".items")
+		if errE != nil {
+			return nil, errE
+		}
+		schema := object{{"type", "array"}, {"items", items}}
+		// Slices of claims are named after their claims.
+		if t.Elem().Kind() == reflect.Struct && t.Elem().Name() != "" {
+			name := lowerFirst(t.Elem().Name()) + "s"
+			return ref("", name), g.define(name, func() (interface{}, errors.E) {
+				return schema, nil
+			})
+		}
+		return withAnnotations(schema, key), nil
+	case reflect.Map:
+		values, errE := g.typeSchema(t.Elem(), key+".values")
+		if errE != nil {
+			return nil, errE
+		}
+		schema := object{{"type", "object"}, {"additionalProperties", values}}
+		if t.Name() != "" {
+			name := lowerFirst(t.Name())
+			return ref("", name), g.define(name, func() (interface{}, errors.E) {
+				return schema, nil
+			})
+		}
+		return withAnnotations(schema, key), nil
+	case reflect.Struct:
+		name := lowerFirst(t.Name())
+		return ref("", name), g.define(name, func() (interface{}, errors.E) {
+			return g.structSchema(t, name, "")
+		})
+	}
+
+	errE := errors.New("unsupported type")
+	errors.Details(errE)["type"] = t.String()
+	return nil, errE
+}
+
+// structSchema returns the schema for the struct. Embedded structs are referenced with
+// allOf and properties not matched by any of the schemas are not allowed, unless
+// the struct is used only for embedding (its name starts with "Core").
+// References to definitions are prefixed with prefix.
+func (g *generator) structSchema(t reflect.Type, name, prefix string) (object, errors.E) {
+	allOf := []object{}
+	properties := object{}
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if f.Anonymous {
+			s, errE := g.typeSchema(f.Type, "")
+			if errE != nil {
+				return nil, errE
+			}
+			allOf = append(allOf, prefixRef(s, prefix))
+			continue
+		}
+
+		if f.Name == "_" {
+			computed := f.Tag.Get("computed")
+			if computed == "" {
+				continue
+			}
+			c, ok := computedDefinitions[computed]
+			if !ok {
+				errE := errors.New("unknown computed property")
+				errors.Details(errE)["property"] = computed
+				errors.Details(errE)["type"] = t.String()
+				return nil, errE
+			}
+			errE := g.defineRaw(c.Name, c.Definition)
+			if errE != nil {
+				return nil, errE
+			}
+			properties = append(properties, keyValue{computed, ref(prefix, c.Name)})
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		jsonName, options, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = f.Name
+		}
+
+		s, errE := g.typeSchema(f.Type, name+"."+jsonName)
+		if errE != nil {
+			errors.Details(errE)["field"] = f.Name
+			return nil, errE
+		}
+		if len(s) == 1 && s[0].Key == "$ref" {
+			s = withAnnotations(prefixRef(s, prefix), name+"."+jsonName)
+		}
+		properties = append(properties, keyValue{jsonName, s})
+
+		if f.Type.Kind() != reflect.Ptr && !strings.Contains(","+options+",", ",omitempty,") {
+			required = append(required, jsonName)
+		}
+	}
+
+	schema := object{{"type", "object"}}
+	if len(allOf) > 0 {
+		schema = append(schema, keyValue{"allOf", allOf})
+	}
+	schema = append(schema, keyValue{"properties", properties})
+	if len(required) > 0 {
+		schema = append(schema, keyValue{"required", required})
+	}
+	if !strings.HasPrefix(t.Name(), "Core") {
+		schema = append(schema, keyValue{"unevaluatedProperties", false})
+	}
+	return schema, nil
+}
+
+// prefixRef prefixes the reference in the schema (if it is a reference) with prefix.
+func prefixRef(schema object, prefix string) object {
+	if prefix == "" || len(schema) != 1 || schema[0].Key != "$ref" {
+		return schema
+	}
+	return object{{"$ref", prefix + schema[0].Value.(string)}} //nolint:forcetypeassert
+}
+
+// enumSchema returns the schema for an enumeration type by marshaling its values,
+// starting with zero, until a value marshals to an empty string.
+func enumSchema(t reflect.Type) (object, errors.E) {
+	values := []string{}
+	for i := 0; i < maxEnumValues; i++ {
+		v := reflect.New(t).Elem()
+		v.SetInt(int64(i))
+		data, err := v.Interface().(json.Marshaler).MarshalJSON() //nolint:forcetypeassert
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		var value string
+		err = json.Unmarshal(data, &value)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if value == "" {
+			return object{{"enum", values}}, nil
+		}
+		values = append(values, value)
+	}
+	errE := errors.New("too many enumeration values")
+	errors.Details(errE)["type"] = t.String()
+	return nil, errE
+}
+
+// generateSchema returns contents of generated JSON Schema files, by their filenames.
+func generateSchema() (map[string][]byte, errors.E) {
+	g := generator{
+		definitions: object{},
+		seen:        map[string]bool{},
+	}
+
+	document, errE := g.structSchema(reflect.TypeOf(search.Document{}), "document", "definitions.json")
+	if errE != nil {
+		return nil, errE
+	}
+
+	doc := object{
+		{"$schema", schemaDraft},
+		{"$id", "doc.json"},
+		{"title", "Document description"},
+	}
+	doc = append(doc, document...)
+
+	definitions := object{
+		{"$schema", schemaDraft},
+		{"$id", "definitions.json"},
+		{"$defs", g.definitions},
+	}
+
+	res := map[string][]byte{}
+	for filename, schema := range map[string]object{"doc.json": doc, "definitions.json": definitions} {
+		data, errE := marshal(schema, "  ")
+		if errE != nil {
+			return nil, errE
+		}
+		res[filename] = data
+	}
+	return res, nil
+}
+
+func generate(config *Config) errors.E {
+	files, errE := generateSchema()
+	if errE != nil {
+		return errE
+	}
+
+	for filename, data := range files {
+		err := os.WriteFile(filepath.Join(config.Output, filename), data, 0o644) //nolint:gosec,gomnd
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	config.Log.Info().Msg("schema generated successfully")
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaJSON(t *testing.T) {
+	generated, errE := generateSchema()
+	require.NoError(t, errE)
+
+	for filename, data := range generated {
+		existing, err := os.ReadFile(filepath.Join("../../schema", filename))
+		require.NoError(t, err)
+
+		assert.Equal(t, string(existing), string(data), "%s is out of date, regenerate it with: go run ./cmd/schema", filename)
+	}
+}
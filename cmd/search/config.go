@@ -11,9 +11,14 @@ import (
 type Config struct {
 	Version kong.VersionFlag `short:"V" help:"Show program's version and exit."`
 	cli.LoggingConfig
-	CertFile    string `short:"c" placeholder:"PATH" required:"" type:"existingfile" help:"A certificate for TLS."`
-	KeyFile     string `short:"k" placeholder:"PATH" required:"" type:"existingfile" help:"A certificate's matching private key."`
-	Elastic     string `short:"e" placeholder:"URL" default:"http://127.0.0.1:9200" help:"URL of the ElasticSearch instance. Default: ${default}"`
-	Development bool   `short:"d" help:"Run in development mode and proxy unknown requests."`
-	ProxyTo     string `placeholder:"URL" default:"http://localhost:3000" help:"Base URL to proxy to in development mode. Default: ${default}"`
+	CertFile    string            `short:"c" placeholder:"PATH" required:"" type:"existingfile" help:"A certificate for TLS."`
+	KeyFile     string            `short:"k" placeholder:"PATH" required:"" type:"existingfile" help:"A certificate's matching private key."`
+	Backend     string            `enum:"elastic,embedded" default:"elastic" help:"Backend to store and search documents with: elastic or embedded. Default: ${default}"`
+	DataDir     string            `placeholder:"DIR" default:"data" type:"path" help:"Where the embedded backend stores its data. Default: ${default}"`
+	Elastic     string            `short:"e" placeholder:"URL" default:"http://127.0.0.1:9200" help:"URL of the ElasticSearch instance. Default: ${default}"`
+	Index       string            `placeholder:"NAME" default:"docs" help:"Name of ElasticSearch index (or its alias) or of embedded index to use. Default: ${default}"`
+	Datasets    map[string]string `placeholder:"PREFIX=INDEX" help:"Additional datasets to serve, each under its path prefix from its index, e.g., staging=docs_staging."`
+	Development bool              `short:"d" help:"Run in development mode and proxy unknown requests."`
+	ProxyTo     string            `placeholder:"URL" default:"http://localhost:3000" help:"Base URL to proxy to in development mode. Default: ${default}"`
+	Properties  string            `placeholder:"PATH" type:"existingfile" help:"Load additional standard properties from a YAML or JSON file."`
 }
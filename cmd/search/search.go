@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"log"
 	"net/http"
+	"sort"
 
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/julienschmidt/httprouter"
+	"github.com/olivere/elastic/v7"
 	"gitlab.com/tozd/go/errors"
 
 	"gitlab.com/peerdb/search"
@@ -17,10 +20,64 @@ const (
 	listenAddr = ":8080"
 )
 
-func listen(config *Config) errors.E {
-	esClient, err := search.GetClient(cleanhttp.DefaultPooledClient(), config.Log, config.Elastic)
+const (
+	backendElastic  = "elastic"
+	backendEmbedded = "embedded"
+)
+
+// openBackend returns the backend configured for the index and a function to close it.
+func openBackend(config *Config, esClient *elastic.Client, index string) (search.Backend, func(), errors.E) {
+	if config.Backend == backendEmbedded {
+		backend, err := search.OpenEmbeddedBackend(config.DataDir, index)
+		if err != nil {
+			return nil, nil, err
+		}
+		return backend, func() {
+			err := backend.Close()
+			if err != nil {
+				config.Log.Error().Err(err).Fields(errors.AllDetails(err)).Str("index", index).Msg("closing backend")
+			}
+		}, nil
+	}
+
+	err := search.CheckIndex(context.Background(), esClient, config.Log, index)
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+	return search.NewElasticBackend(esClient, index), func() {}, nil
+}
+
+func newService(config *Config, backend search.Backend, development, prefix string) (*search.Service, errors.E) {
+	// TODO: Reload the hierarchy when properties change.
+	hierarchy, err := search.LoadPropertyHierarchy(context.Background(), backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return &search.Service{
+		Backend:     backend,
+		Prefix:      prefix,
+		Log:         config.Log,
+		Development: development,
+		Hierarchy:   hierarchy,
+	}, nil
+}
+
+func listen(config *Config) errors.E {
+	if config.Properties != "" {
+		errE := search.StandardProperties.Load(config.Properties)
+		if errE != nil {
+			return errE
+		}
+	}
+
+	var esClient *elastic.Client
+	if config.Backend == backendElastic {
+		var err errors.E
+		esClient, err = search.GetClient(cleanhttp.DefaultPooledClient(), config.Log, config.Elastic)
+		if err != nil {
+			return err
+		}
 	}
 
 	development := config.ProxyTo
@@ -28,10 +85,15 @@ func listen(config *Config) errors.E {
 		development = ""
 	}
 
-	s := &search.Service{
-		ESClient:    esClient,
-		Log:         config.Log,
-		Development: development,
+	backend, closeBackend, err := openBackend(config, esClient, config.Index)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	s, err := newService(config, backend, development, "")
+	if err != nil {
+		return err
 	}
 
 	router := httprouter.New()
@@ -40,6 +102,30 @@ func listen(config *Config) errors.E {
 		return err
 	}
 
+	// We sort prefixes so that routes are registered in a deterministic order.
+	prefixes := make([]string, 0, len(config.Datasets))
+	for prefix := range config.Datasets {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	for _, prefix := range prefixes {
+		backend, closeBackend, err := openBackend(config, esClient, config.Datasets[prefix])
+		if err != nil {
+			return err
+		}
+		defer closeBackend()
+
+		dataset, err := newService(config, backend, development, "/"+prefix)
+		if err != nil {
+			return err
+		}
+		err = dataset.RouteDatasetWith(router)
+		if err != nil {
+			return err
+		}
+	}
+
 	manager := CertificateManager{
 		CertFile: config.CertFile,
 		KeyFile:  config.KeyFile,
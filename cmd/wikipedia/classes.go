@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/x"
+
+	"gitlab.com/peerdb/search"
+	"gitlab.com/peerdb/search/internal/wikipedia"
+)
+
+const (
+	defaultClassesMaxDepth = 50
+)
+
+// ClassesCommand precomputes the transitive closure of Wikidata "instance of" (P31) and
+// "subclass of" (P279) relations and stores it into documents as INSTANCE_OF_CLASS and
+// SUBCLASS_OF_CLASS claims, so that documents can be searched by any of their (super)classes.
+//
+// It should run after PrepareCommand, when references to classes are resolved.
+type ClassesCommand struct {
+	MaxDepth int `placeholder:"INT" default:"50" help:"Maximum number of subclass relations to follow. Default: ${default}."`
+}
+
+func (c *ClassesCommand) Run(globals *Globals) (errE errors.E) {
+	if c.MaxDepth < 0 {
+		errE := errors.New("invalid max depth")
+		errors.Details(errE)["maxDepth"] = c.MaxDepth
+		return errE
+	}
+
+	ctx, cancel, _, backend, writer, _, errE := initializeBackend(globals)
+	if errE != nil {
+		return errE
+	}
+	defer cancel()
+	defer flushWriter(ctx, writer, &errE)
+
+	query := search.Query{Props: [][]search.Identifier{wikipedia.ClassPropertyIDs()}}
+
+	hierarchy := wikipedia.NewClassHierarchy()
+
+	globals.Log.Info().Msg("loading class hierarchy")
+	projection := search.Projection{ClaimTypes: []string{"rel"}, Props: nil, Active: true, Inactive: false, Meta: false}
+	errE = scrollDocuments(ctx, globals, backend, query, projection, func(document *search.Document, _ search.Version) errors.E {
+		hierarchy.AddDocument(document)
+		return nil
+	})
+	if errE != nil {
+		return errE
+	}
+
+	globals.Log.Info().Msg("updating class closure")
+	var cycles, truncated int64
+	errE = scrollDocuments(ctx, globals, backend, query, search.FullProjection(), func(document *search.Document, version search.Version) errors.E {
+		// We compute the hash before the document is changed.
+		hash, errE := document.Hash()
+		if errE != nil {
+			details := errors.AllDetails(errE)
+			details["doc"] = string(document.ID)
+			globals.Log.Error().Err(errE).Fields(details).Send()
+			return nil
+		}
+		closure := hierarchy.Closure(document.ID, c.MaxDepth)
+		if closure.Cycle {
+			cycles++
+			globals.Log.Warn().Str("doc", string(document.ID)).Msg("class is a subclass of itself")
+		}
+		if closure.Truncated {
+			truncated++
+			globals.Log.Warn().Str("doc", string(document.ID)).Int("maxDepth", c.MaxDepth).Msg("class closure truncated")
+		}
+		errE = wikipedia.UpdateClassClosure(document, closure)
+		if errE != nil {
+			details := errors.AllDetails(errE)
+			details["doc"] = string(document.ID)
+			globals.Log.Error().Err(errE).Fields(details).Msg("updating class closure failed")
+			return nil
+		}
+		updateDocument(ctx, globals.Log, writer, version, hash, document)
+		return nil
+	})
+	if errE != nil {
+		return errE
+	}
+
+	globals.Log.Info().Int64("cycles", cycles).Int64("truncated", truncated).Msg("class closure updated")
+
+	return nil
+}
+
+// scrollDocuments calls fn for every document in the backend matching the query, together with its version.
+// Documents include at least parts of documents selected by the projection.
+func scrollDocuments(
+	ctx context.Context, globals *Globals, backend search.Backend, query search.Query, projection search.Projection,
+	fn func(*search.Document, search.Version) errors.E,
+) errors.E {
+	var count x.Counter
+
+	result, errE := backend.Search(ctx, query, search.SearchOptions{Size: 0, Preference: ""})
+	if errE != nil {
+		return errE
+	}
+
+	ticker := x.NewTicker(ctx, &count, result.Total, progressPrintRate)
+	defer ticker.Stop()
+	go func() {
+		for p := range ticker.C {
+			globals.Log.Info().
+				Int64("docs", count.Count()).Str("eta", p.Remaining().Truncate(time.Second).String()).
+				Msgf("progress %0.2f%%", p.Percent())
+		}
+	}()
+
+	return backend.Scroll(ctx, query, projection, func(document *search.Document, version search.Version) errors.E {
+		errE := fn(document, version)
+		if errE != nil {
+			return errE
+		}
+		count.Increment()
+		return nil
+	})
+}
@@ -7,13 +7,13 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
-	"github.com/olivere/elastic/v7"
 	"gitlab.com/tozd/go/errors"
 	"gitlab.com/tozd/go/mediawiki"
 	"gitlab.com/tozd/go/x"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/time/rate"
 
+	"gitlab.com/peerdb/search"
 	"gitlab.com/peerdb/search/internal/wikipedia"
 )
 
@@ -29,9 +29,9 @@ var (
 // It expects documents populated by CommonsFilesCommand.
 //
 // It expects documents populated by WikidataCommand because it might need properties to resolve the data type used
-// with the claim's value. It uses ElasticSearch to obtain documents of those properties.
+// with the claim's value. It uses the backend to obtain documents of those properties.
 //
-// It accesses existing documents in ElasticSearch to load corresponding file's document which is then updated with claims based on
+// It accesses existing documents in the backend to load corresponding file's document which is then updated with claims based on
 // statements and also claims with the following properties: WIKIMEDIA_COMMONS_ENTITY_ID (M prefixed ID),
 // ALSO_KNOWN_AS (for any English labels), DESCRIPTION (for English entity descriptions).
 //
@@ -44,8 +44,8 @@ type CommonsCommand struct {
 	URL          string `placeholder:"URL" help:"URL of Wikimedia Commons entities JSON dump to use. It can be a local file path, too. Default: the latest."`
 }
 
-func (c *CommonsCommand) Run(globals *Globals) errors.E {
-	errE := populateSkippedMap(c.SkippedFiles, &skippedWikimediaCommonsFiles, &skippedWikimediaCommonsFilesCount)
+func (c *CommonsCommand) Run(globals *Globals) (errE errors.E) {
+	errE = populateSkippedMap(c.SkippedFiles, &skippedWikimediaCommonsFiles, &skippedWikimediaCommonsFilesCount)
 	if errE != nil {
 		return errE
 	}
@@ -59,15 +59,15 @@ func (c *CommonsCommand) Run(globals *Globals) errors.E {
 		urlFunc = mediawiki.LatestCommonsEntitiesRun
 	}
 
-	ctx, cancel, _, esClient, processor, cache, config, errE := initializeRun(globals, urlFunc, nil)
+	ctx, cancel, _, backend, writer, cache, config, errE := initializeRun(globals, urlFunc, nil)
 	if errE != nil {
 		return errE
 	}
 	defer cancel()
-	defer processor.Close()
+	defer flushWriter(ctx, writer, &errE)
 
-	errE = mediawiki.ProcessCommonsEntitiesDump(ctx, config, func(ctx context.Context, entity mediawiki.Entity) errors.E {
-		return c.processEntity(ctx, globals, esClient, cache, processor, entity)
+	errE = wikipedia.ProcessCommonsEntitiesDump(ctx, config, func(ctx context.Context, entity mediawiki.Entity) errors.E {
+		return c.processEntity(ctx, globals, backend, cache, writer, entity)
 	})
 	if errE != nil {
 		return errE
@@ -77,7 +77,7 @@ func (c *CommonsCommand) Run(globals *Globals) errors.E {
 }
 
 func (c *CommonsCommand) processEntity(
-	ctx context.Context, globals *Globals, esClient *elastic.Client, cache *wikipedia.Cache, processor *elastic.BulkProcessor, entity mediawiki.Entity,
+	ctx context.Context, globals *Globals, backend search.Backend, cache *wikipedia.Cache, writer *documentWriter, entity mediawiki.Entity,
 ) errors.E {
 	filename := strings.TrimPrefix(entity.Title, "File:")
 	filename = strings.ReplaceAll(filename, " ", "_")
@@ -88,7 +88,7 @@ func (c *CommonsCommand) processEntity(
 		return nil
 	}
 
-	document, hit, err := wikipedia.GetWikimediaCommonsFile(ctx, globals.Index, esClient, filename)
+	document, version, err := wikipedia.GetWikimediaCommonsFile(ctx, backend, filename)
 	if err != nil {
 		details := errors.AllDetails(err)
 		details["file"] = filename
@@ -97,7 +97,10 @@ func (c *CommonsCommand) processEntity(
 		return nil
 	}
 
-	additionalDocument, err := wikipedia.ConvertEntity(ctx, globals.Index, globals.Log, esClient, cache, wikipedia.NameSpaceWikimediaCommonsFile, entity)
+	// We compute the hash before the document is changed.
+	hash := documentHash(globals.Log, document)
+
+	additionalDocument, err := wikipedia.ConvertEntity(ctx, globals.Log, backend, cache, wikipedia.NameSpaceWikimediaCommonsFile, entity)
 	if err != nil {
 		if errors.Is(err, wikipedia.SilentSkippedError) {
 			globals.Log.Debug().Str("doc", string(document.ID)).Str("file", filename).Err(err).Str("entity", entity.ID).Fields(errors.AllDetails(err)).Send()
@@ -131,7 +134,7 @@ func (c *CommonsCommand) processEntity(
 	}
 
 	globals.Log.Debug().Str("doc", string(document.ID)).Str("file", filename).Str("entity", entity.ID).Msg("updating document")
-	updateDocument(processor, globals.Index, *hit.SeqNo, *hit.PrimaryTerm, document)
+	updateDocument(ctx, globals.Log, writer, version, hash, document)
 
 	return nil
 }
@@ -188,26 +191,26 @@ func (c *CommonsFilesCommand) Run(globals *Globals) errors.E {
 //
 // Internal links inside HTML are not yet converted to links to PeerDB documents. This is done in PrepareCommand.
 //
-// It accesses existing documents in ElasticSearch to load corresponding Wikimedia Commons entity's document which is then updated with
+// It accesses existing documents in the backend to load corresponding Wikimedia Commons entity's document which is then updated with
 // claims with the following properties: WIKIMEDIA_COMMONS_PAGE_ID (internal page ID of the file), DESCRIPTION (potentially multiple),
-// ALSO_KNOWN_AS (from redirects pointing to the file), IN_WIKIMEDIA_COMMONS_CATEGORY (for categories the file is in),
+// ALSO_KNOWN_AS and ALIAS (from redirects pointing to the file), IN_WIKIMEDIA_COMMONS_CATEGORY (for categories the file is in),
 // USES_WIKIMEDIA_COMMONS_TEMPLATE (for templates used).
 type CommonsFileDescriptionsCommand struct {
 	SkippedFiles string `placeholder:"PATH" type:"path" help:"Load filenames of skipped Wikimedia Commons files."`
 }
 
-func (c *CommonsFileDescriptionsCommand) Run(globals *Globals) errors.E {
-	errE := populateSkippedMap(c.SkippedFiles, &skippedWikimediaCommonsFiles, &skippedWikimediaCommonsFilesCount)
+func (c *CommonsFileDescriptionsCommand) Run(globals *Globals) (errE errors.E) {
+	errE = populateSkippedMap(c.SkippedFiles, &skippedWikimediaCommonsFiles, &skippedWikimediaCommonsFilesCount)
 	if errE != nil {
 		return errE
 	}
 
-	ctx, cancel, httpClient, esClient, processor, _, _, errE := initializeRun(globals, nil, nil)
+	ctx, cancel, httpClient, backend, writer, _, _, errE := initializeRun(globals, nil, nil)
 	if errE != nil {
 		return errE
 	}
 	defer cancel()
-	defer processor.Close()
+	defer flushWriter(ctx, writer, &errE)
 
 	pages := make(chan wikipedia.AllPagesPage, wikipedia.APILimit)
 	rateLimit := wikipediaRESTRateLimit / wikipediaRESTRatePeriod.Seconds()
@@ -224,9 +227,9 @@ func (c *CommonsFileDescriptionsCommand) Run(globals *Globals) errors.E {
 	defer ticker.Stop()
 	go func() {
 		for p := range ticker.C {
-			stats := processor.Stats()
+			indexed, failed := writer.Stats()
 			globals.Log.Info().
-				Int64("failed", stats.Failed).Int64("indexed", stats.Succeeded).Int64("docs", count.Count()).
+				Int64("failed", failed).Int64("indexed", indexed).Int64("docs", count.Count()).
 				Str("elapsed", p.Elapsed.Truncate(time.Second).String()).
 				Send()
 		}
@@ -250,7 +253,7 @@ func (c *CommonsFileDescriptionsCommand) Run(globals *Globals) errors.E {
 
 				count.Increment()
 
-				errE = c.processPage(ctx, globals, esClient, processor, page, html)
+				errE = c.processPage(ctx, globals, backend, writer, page, html)
 				if errE != nil {
 					return errE
 				}
@@ -263,8 +266,8 @@ func (c *CommonsFileDescriptionsCommand) Run(globals *Globals) errors.E {
 }
 
 func (c *CommonsFileDescriptionsCommand) processPage(
-	ctx context.Context, globals *Globals, esClient *elastic.Client,
-	processor *elastic.BulkProcessor, page wikipedia.AllPagesPage, html string,
+	ctx context.Context, globals *Globals, backend search.Backend,
+	writer *documentWriter, page wikipedia.AllPagesPage, html string,
 ) errors.E {
 	filename := strings.TrimPrefix(page.Title, "File:")
 	// First we make sure we do not have spaces.
@@ -277,7 +280,7 @@ func (c *CommonsFileDescriptionsCommand) processPage(
 		return nil
 	}
 
-	document, hit, err := wikipedia.GetWikimediaCommonsFile(ctx, globals.Index, esClient, filename)
+	document, version, err := wikipedia.GetWikimediaCommonsFile(ctx, backend, filename)
 	if err != nil {
 		details := errors.AllDetails(err)
 		details["file"] = filename
@@ -286,6 +289,9 @@ func (c *CommonsFileDescriptionsCommand) processPage(
 		return nil
 	}
 
+	// We compute the hash before the document is changed.
+	hash := documentHash(globals.Log, document)
+
 	err = wikipedia.SetPageID(wikipedia.NameSpaceWikimediaCommonsFile, "WIKIMEDIA_COMMONS", filename, page.Identifier, document)
 	if err != nil {
 		details := errors.AllDetails(err)
@@ -337,7 +343,7 @@ func (c *CommonsFileDescriptionsCommand) processPage(
 	}
 
 	globals.Log.Debug().Str("doc", string(document.ID)).Str("file", filename).Str("title", page.Title).Msg("updating document")
-	updateDocument(processor, globals.Index, *hit.SeqNo, *hit.PrimaryTerm, document)
+	updateDocument(ctx, globals.Log, writer, version, hash, document)
 
 	return nil
 }
@@ -353,7 +359,7 @@ func (c *CommonsFileDescriptionsCommand) processPage(
 //
 // Internal links inside HTML are not yet converted to links to PeerDB documents. This is done in PrepareCommand.
 //
-// It accesses existing documents in ElasticSearch to load corresponding Wikidata entity's document which is then updated with claims with the
+// It accesses existing documents in the backend to load corresponding Wikidata entity's document which is then updated with claims with the
 // following properties: WIKIMEDIA_COMMONS_PAGE_ID (internal page ID of the category), DESCRIPTION (extracted from Wikimedia Commons' category article),
 // ALSO_KNOWN_AS (from redirects pointing to the category), IN_WIKIMEDIA_COMMONS_CATEGORY (for categories the category is in),
 // USES_WIKIMEDIA_COMMONS_TEMPLATE (for templates used).
@@ -361,18 +367,18 @@ type CommonsCategoriesCommand struct {
 	SkippedEntities string `placeholder:"PATH" type:"path" help:"Load IDs of skipped Wikidata entities."`
 }
 
-func (c *CommonsCategoriesCommand) Run(globals *Globals) errors.E {
-	errE := populateSkippedMap(c.SkippedEntities, &skippedWikidataEntities, &skippedWikidataEntitiesCount)
+func (c *CommonsCategoriesCommand) Run(globals *Globals) (errE errors.E) {
+	errE = populateSkippedMap(c.SkippedEntities, &skippedWikidataEntities, &skippedWikidataEntitiesCount)
 	if errE != nil {
 		return errE
 	}
 
-	ctx, cancel, httpClient, esClient, processor, _, _, errE := initializeRun(globals, nil, nil)
+	ctx, cancel, httpClient, backend, writer, _, _, errE := initializeRun(globals, nil, nil)
 	if errE != nil {
 		return errE
 	}
 	defer cancel()
-	defer processor.Close()
+	defer flushWriter(ctx, writer, &errE)
 
 	pages := make(chan wikipedia.AllPagesPage, wikipedia.APILimit)
 	rateLimit := wikipediaRESTRateLimit / wikipediaRESTRatePeriod.Seconds()
@@ -389,9 +395,9 @@ func (c *CommonsCategoriesCommand) Run(globals *Globals) errors.E {
 	defer ticker.Stop()
 	go func() {
 		for p := range ticker.C {
-			stats := processor.Stats()
+			indexed, failed := writer.Stats()
 			globals.Log.Info().
-				Int64("failed", stats.Failed).Int64("indexed", stats.Succeeded).Int64("docs", count.Count()).
+				Int64("failed", failed).Int64("indexed", indexed).Int64("docs", count.Count()).
 				Str("elapsed", p.Elapsed.Truncate(time.Second).String()).
 				Send()
 		}
@@ -420,7 +426,7 @@ func (c *CommonsCategoriesCommand) Run(globals *Globals) errors.E {
 
 				count.Increment()
 
-				errE = c.processPage(ctx, globals, esClient, processor, page, html)
+				errE = c.processPage(ctx, globals, backend, writer, page, html)
 				if errE != nil {
 					return errE
 				}
@@ -433,7 +439,7 @@ func (c *CommonsCategoriesCommand) Run(globals *Globals) errors.E {
 }
 
 func (c *CommonsCategoriesCommand) processPage(
-	ctx context.Context, globals *Globals, esClient *elastic.Client, processor *elastic.BulkProcessor, page wikipedia.AllPagesPage, html string,
+	ctx context.Context, globals *Globals, backend search.Backend, writer *documentWriter, page wikipedia.AllPagesPage, html string,
 ) errors.E {
 	// We know this is available because we check before calling this method.
 	id := page.Properties["wikibase_item"]
@@ -443,7 +449,7 @@ func (c *CommonsCategoriesCommand) processPage(
 		return nil
 	}
 
-	document, hit, err := wikipedia.GetWikidataItem(ctx, globals.Index, esClient, id)
+	document, version, err := wikipedia.GetWikidataItem(ctx, backend, id)
 	if err != nil {
 		details := errors.AllDetails(err)
 		details["entity"] = id
@@ -456,6 +462,9 @@ func (c *CommonsCategoriesCommand) processPage(
 		return nil
 	}
 
+	// We compute the hash before the document is changed.
+	hash := documentHash(globals.Log, document)
+
 	err = wikipedia.SetPageID(wikipedia.NameSpaceWikidata, "WIKIMEDIA_COMMONS", id, page.Identifier, document)
 	if err != nil {
 		details := errors.AllDetails(err)
@@ -507,7 +516,7 @@ func (c *CommonsCategoriesCommand) processPage(
 	}
 
 	globals.Log.Debug().Str("doc", string(document.ID)).Str("entity", id).Str("title", page.Title).Msg("updating document")
-	updateDocument(processor, globals.Index, *hit.SeqNo, *hit.PrimaryTerm, document)
+	updateDocument(ctx, globals.Log, writer, version, hash, document)
 
 	return nil
 }
@@ -523,7 +532,7 @@ func (c *CommonsCategoriesCommand) processPage(
 //
 // Internal links inside HTML are not yet converted to links to PeerDB documents. This is done in PrepareCommand.
 //
-// It accesses existing documents in ElasticSearch to load corresponding Wikidata entity's document which is then updated with claims with the
+// It accesses existing documents in the backend to load corresponding Wikidata entity's document which is then updated with claims with the
 // following properties: WIKIMEDIA_COMMONS_PAGE_ID (internal page ID of the template or module), DESCRIPTION (extracted from documentation),
 // ALSO_KNOWN_AS (from redirects pointing to the template or module), IN_WIKIMEDIA_COMMONS_CATEGORY (for categories the template or module is in),
 // USES_WIKIMEDIA_COMMONS_TEMPLATE (for templates used).
@@ -11,9 +11,17 @@ import (
 )
 
 const (
-	bulkProcessorWorkers = 2
-	clientRetryWaitMax   = 10 * 60 * time.Second
-	clientRetryMax       = 9
+	writerBatchSize = 1000
+	writerWorkers   = 2
+	// Enough retries to wait for the last pass of reindexing, while writes are blocked.
+	writerRetryMax     = 10
+	writerRetryWaitMin = 1 * time.Second
+	writerRetryWaitMax = 60 * time.Second
+	clientRetryWaitMax = 10 * 60 * time.Second
+	clientRetryMax     = 9
+
+	backendElastic  = "elastic"
+	backendEmbedded = "embedded"
 )
 
 // Globals describes top-level (global) flags.
@@ -21,11 +29,14 @@ type Globals struct {
 	Version kong.VersionFlag `short:"V" help:"Show program's version and exit."`
 	cli.LoggingConfig
 	CacheDir               string `name:"cache" placeholder:"DIR" default:".cache" type:"path" help:"Where to cache files to. Default: ${default}."`
+	Backend                string `enum:"elastic,embedded" default:"elastic" help:"Backend to store documents into: elastic or embedded. Default: ${default}."`
+	DataDir                string `placeholder:"DIR" default:"data" type:"path" help:"Where the embedded backend stores its data. Default: ${default}."`
 	Elastic                string `short:"e" placeholder:"URL" default:"http://127.0.0.1:9200" help:"URL of the ElasticSearch instance. Default: ${default}."`
-	Index                  string `placeholder:"NAME" default:"docs" help:"Name of ElasticSearch index to use. Default: ${default}."`
+	Index                  string `placeholder:"NAME" default:"docs" help:"Name of ElasticSearch index alias or of embedded index to use. Default: ${default}."`
 	DecompressionThreads   int    `placeholder:"INT" default:"0" help:"The number of threads used for decompression. Defaults to the number of available cores."`
 	DecodingThreads        int    `placeholder:"INT" default:"0" help:"The number of threads used for decoding. Defaults to the number of available cores."`
 	ItemsProcessingThreads int    `placeholder:"INT" default:"0" help:"The number of threads used for items processing. Defaults to the number of available cores."`
+	Properties             string `placeholder:"PATH" type:"existingfile" help:"Load additional standard properties from a YAML or JSON file."`
 }
 
 // Config provides configuration.
@@ -51,10 +62,22 @@ type Config struct {
 	CommonsFileDescriptions CommonsFileDescriptionsCommand `cmd:"" name:"commons-file-descriptions" help:"Populate search with Wikimedia Commons file descriptions using API."` //nolint:lll
 	CommonsCategories       CommonsCategoriesCommand       `cmd:"" name:"commons-categories" help:"Populate search with Wikimedia Commons categories using API."`
 	CommonsTemplates        CommonsTemplatesCommand        `cmd:"" name:"commons-templates" help:"Populate search with Wikimedia Commons templates using API."`
+	WikidataRedirects       WikidataRedirectsCommand       `cmd:"" name:"wikidata-redirects" help:"Populate search with aliases of merged Wikidata items using API."`
 
 	Prepare  PrepareCommand  `cmd:"" help:"Prepare populated data for search."`
+	Classes  ClassesCommand  `cmd:"" help:"Compute transitive closure of instance of and subclass of relations."`
+	Slugs    SlugsCommand    `cmd:"" help:"Assign human-readable slugs to documents."`
 	Optimize OptimizeCommand `cmd:"" help:"Optimize search data."`
 
+	// Not part of all passes: it is used to propagate changes after documents have been updated.
+	Refresh RefreshCommand `cmd:"" help:"Update embedded documents only in documents referencing changed documents."`
+
+	// Not part of all passes: it is used to update index configuration of an existing index.
+	Reindex ReindexCommand `cmd:"" help:"Copy documents into a new index with current configuration and switch the index alias to it."`
+
+	// Not part of all passes: it is used to load already prepared documents, e.g., into the embedded backend.
+	Import ImportCommand `cmd:"" help:"Import documents from a JSON Lines file."`
+
 	All AllCommand `cmd:"" default:"" help:"Run all passes in order using latest dumps. Default command."`
 }
 
@@ -106,7 +129,12 @@ func (c *AllCommand) Run(globals *Globals) errors.E {
 		&CommonsFileDescriptionsCommand{},
 		&CommonsCategoriesCommand{},
 		&CommonsTemplatesCommand{},
+		&WikidataRedirectsCommand{},
 		&PrepareCommand{},
+		&ClassesCommand{
+			MaxDepth: defaultClassesMaxDepth,
+		},
+		&SlugsCommand{},
 		&OptimizeCommand{},
 	}
 
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/x"
+
+	"gitlab.com/peerdb/search"
+	"gitlab.com/peerdb/search/identifier"
+)
+
+const (
+	// Maximum size of one line (document) in the input file.
+	importMaxLineSize = 64 * 1024 * 1024
+)
+
+type ImportCommand struct {
+	Input string `placeholder:"PATH" required:"" type:"existingfile" help:"JSON Lines file with documents, in the format returned by the batch API with format=jsonl."`
+}
+
+// importLine is a line in the input file. It matches results of the batch API.
+type importLine struct {
+	ID       string          `json:"_id"`
+	Document json.RawMessage `json:"doc,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// openBackend returns the backend configured with global flags and a function to close it.
+func openBackend(ctx context.Context, globals *Globals, httpClient *http.Client) (search.Backend, func(), errors.E) {
+	if globals.Backend == backendEmbedded {
+		backend, errE := search.OpenEmbeddedBackend(globals.DataDir, globals.Index)
+		if errE != nil {
+			return nil, nil, errE
+		}
+		return backend, func() {
+			errE := backend.Close()
+			if errE != nil {
+				globals.Log.Error().Err(errE).Fields(errors.AllDetails(errE)).Msg("closing backend")
+			}
+		}, nil
+	}
+
+	esClient, errE := search.EnsureIndex(ctx, httpClient, globals.Log, globals.Elastic, globals.Index)
+	if errE != nil {
+		return nil, nil, errE
+	}
+	return search.NewElasticBackend(esClient, globals.Index), func() {}, nil
+}
+
+func (c *ImportCommand) Run(globals *Globals) (errE errors.E) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	backend, closeBackend, errE := openBackend(ctx, globals, cleanhttp.DefaultPooledClient())
+	if errE != nil {
+		return errE
+	}
+	defer closeBackend()
+
+	writer := newDocumentWriter(ctx, backend, globals.Log)
+	defer flushWriter(ctx, writer, &errE)
+
+	// Standard properties are always available, same as after the prepare command.
+	for _, property := range search.StandardProperties.List() {
+		property := property
+		writer.Insert(ctx, &property)
+	}
+
+	file, err := os.Open(c.Input)
+	if err != nil {
+		errE := errors.WithStack(err)
+		errors.Details(errE)["path"] = c.Input
+		return errE
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, importMaxLineSize)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var line importLine
+		errE := x.UnmarshalWithoutUnknownFields(scanner.Bytes(), &line)
+		if errE != nil {
+			errors.Details(errE)["path"] = c.Input
+			return errE
+		}
+		if line.ID == "" && line.Error != "" {
+			// The batch API failed while streaming the response.
+			errE := errors.New("incomplete input")
+			errors.Details(errE)["path"] = c.Input
+			errors.Details(errE)["reason"] = line.Error
+			return errE
+		}
+		if line.Error != "" {
+			globals.Log.Warn().Str("doc", line.ID).Str("reason", line.Error).Msg("skipping document")
+			continue
+		}
+		if !identifier.Valid(line.ID) {
+			errE := errors.New("invalid document ID")
+			errors.Details(errE)["doc"] = line.ID
+			return errE
+		}
+
+		var document search.Document
+		errE = x.UnmarshalWithoutUnknownFields(line.Document, &document)
+		if errE != nil {
+			errors.Details(errE)["doc"] = line.ID
+			return errE
+		}
+		document.ID = search.Identifier(line.ID)
+		writer.Insert(ctx, &document)
+		count++
+	}
+	err = scanner.Err()
+	if err != nil {
+		errE := errors.WithStack(err)
+		errors.Details(errE)["path"] = c.Input
+		return errE
+	}
+
+	errE = writer.Flush(ctx)
+	if errE != nil {
+		return errE
+	}
+
+	globals.Log.Info().Int("count", count).Str("backend", globals.Backend).Msg("imported documents")
+
+	return nil
+}
@@ -4,12 +4,20 @@ import (
 	"github.com/alecthomas/kong"
 	"gitlab.com/tozd/go/errors"
 
+	"gitlab.com/peerdb/search"
 	"gitlab.com/peerdb/search/internal/cli"
 )
 
 func main() {
 	var config Config
 	cli.Run(&config, "", func(ctx *kong.Context) errors.E {
+		if config.Properties != "" {
+			errE := search.StandardProperties.Load(config.Properties)
+			if errE != nil {
+				return errE
+			}
+		}
+
 		return errors.WithStack(ctx.Run(&config.Globals))
 	})
 }
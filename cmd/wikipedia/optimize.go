@@ -7,12 +7,17 @@ import (
 type OptimizeCommand struct{}
 
 func (c *OptimizeCommand) Run(globals *Globals) errors.E {
-	ctx, cancel, _, esClient, processor, _, errE := initializeElasticSearch(globals)
+	if globals.Backend != backendElastic {
+		// Other backends do not have segments to merge.
+		globals.Log.Info().Str("backend", globals.Backend).Msg("nothing to optimize")
+		return nil
+	}
+
+	ctx, cancel, esClient, errE := initializeElasticSearch(globals)
 	if errE != nil {
 		return errE
 	}
 	defer cancel()
-	defer processor.Close()
 
 	_, err := esClient.Forcemerge(globals.Index).Do(ctx)
 	if err != nil {
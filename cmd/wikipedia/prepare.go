@@ -2,11 +2,10 @@ package main
 
 import (
 	"context"
-	"io"
 	"runtime"
+	"sync"
 	"time"
 
-	"github.com/olivere/elastic/v7"
 	"github.com/rs/zerolog"
 	"gitlab.com/tozd/go/errors"
 	"gitlab.com/tozd/go/x"
@@ -18,8 +17,7 @@ import (
 
 const (
 	// Same as go-mediawiki's progressPrintRate.
-	progressPrintRate   = 30 * time.Second
-	scrollingMultiplier = 10
+	progressPrintRate = 30 * time.Second
 )
 
 type PrepareCommand struct {
@@ -27,8 +25,8 @@ type PrepareCommand struct {
 	SkippedWikimediaCommonsFiles string `placeholder:"PATH" type:"path" help:"Load filenames of skipped Wikimedia Commons files."`
 }
 
-func (c *PrepareCommand) Run(globals *Globals) errors.E {
-	errE := populateSkippedMap(c.SkippedWikidataEntities, &skippedWikidataEntities, &skippedWikidataEntitiesCount)
+func (c *PrepareCommand) Run(globals *Globals) (errE errors.E) {
+	errE = populateSkippedMap(c.SkippedWikidataEntities, &skippedWikidataEntities, &skippedWikidataEntitiesCount)
 	if errE != nil {
 		return errE
 	}
@@ -38,106 +36,108 @@ func (c *PrepareCommand) Run(globals *Globals) errors.E {
 		return errE
 	}
 
-	ctx, cancel, _, esClient, processor, cache, errE := initializeElasticSearch(globals)
+	ctx, cancel, _, backend, writer, cache, errE := initializeBackend(globals)
 	if errE != nil {
 		return errE
 	}
 	defer cancel()
-	defer processor.Close()
+	defer flushWriter(ctx, writer, &errE)
 
-	errE = c.saveStandardProperties(ctx, globals, esClient, processor)
+	errE = c.saveStandardProperties(ctx, globals, backend)
 	if errE != nil {
 		return errE
 	}
 
-	return c.updateEmbeddedDocuments(ctx, globals, esClient, processor, cache)
+	return updateEmbeddedDocuments(ctx, globals, backend, writer, cache, search.Query{}, nil)
 }
 
-func (c *PrepareCommand) saveStandardProperties(ctx context.Context, globals *Globals, esClient *elastic.Client, processor *elastic.BulkProcessor) errors.E {
-	for _, property := range search.StandardProperties {
+func (c *PrepareCommand) saveStandardProperties(ctx context.Context, globals *Globals, backend search.Backend) errors.E {
+	properties := []*search.Document{}
+	for _, property := range search.StandardProperties.List() {
 		property := property
 		globals.Log.Debug().Str("doc", string(property.ID)).Str("mnemonic", string(property.Mnemonic)).Msg("saving document")
-		insertOrReplaceDocument(processor, globals.Index, &property)
+		properties = append(properties, &property)
 	}
 
-	// Make sure all just added documents are available for search.
-	err := processor.Flush()
-	if err != nil {
-		return errors.WithStack(err)
+	result, errE := backend.Bulk(ctx, properties)
+	if errE != nil {
+		return errE
 	}
-	_, err = esClient.Refresh(globals.Index).Do(ctx)
-	if err != nil {
-		return errors.WithStack(err)
+	if len(result.Temporary) > 0 || len(result.Failed) > 0 {
+		errE := errors.New("standard properties could not be saved")
+		errors.Details(errE)["count"] = len(result.Temporary) + len(result.Failed)
+		return errE
 	}
 
-	return nil
+	// Refresh makes sure all just added documents are available for search.
+	return backend.Refresh(ctx)
 }
 
-func (c *PrepareCommand) updateEmbeddedDocuments(
-	ctx context.Context, globals *Globals, esClient *elastic.Client, processor *elastic.BulkProcessor, cache *wikipedia.Cache,
+// scrolledDocument is a document read from the backend together with its version.
+type scrolledDocument struct {
+	Document *search.Document
+	Version  search.Version
+}
+
+// updateEmbeddedDocuments updates embedded documents in all documents matching the query.
+// If seen is provided, documents already in it are skipped and all processed documents are stored into it.
+func updateEmbeddedDocuments(
+	ctx context.Context, globals *Globals, backend search.Backend, writer *documentWriter, cache *wikipedia.Cache,
+	query search.Query, seen *sync.Map,
 ) errors.E {
 	// TODO: Make configurable.
 	documentProcessingThreads := runtime.GOMAXPROCS(0)
 
 	var count x.Counter
 
-	total, err := esClient.Count(globals.Index).Do(ctx)
-	if err != nil {
-		return errors.WithStack(err)
+	result, errE := backend.Search(ctx, query, search.SearchOptions{Size: 0, Preference: ""})
+	if errE != nil {
+		return errE
 	}
 
 	g, ctx := errgroup.WithContext(ctx)
 
-	ticker := x.NewTicker(ctx, &count, total, progressPrintRate)
+	ticker := x.NewTicker(ctx, &count, result.Total, progressPrintRate)
 	defer ticker.Stop()
 	go func() {
 		for p := range ticker.C {
-			stats := processor.Stats()
+			indexed, failed := writer.Stats()
 			globals.Log.Info().
-				Int64("failed", stats.Failed).Int64("indexed", stats.Succeeded).Int64("docs", count.Count()).
+				Int64("failed", failed).Int64("indexed", indexed).Int64("docs", count.Count()).
 				Uint64("cacheMiss", cache.MissCount()).Str("eta", p.Remaining().Truncate(time.Second).String()).
 				Msgf("progress %0.2f%%", p.Percent())
 		}
 	}()
 
-	hits := make(chan *elastic.SearchHit, documentProcessingThreads)
+	documents := make(chan scrolledDocument, documentProcessingThreads)
 	g.Go(func() error {
-		defer close(hits)
-
-		scroll := esClient.Scroll(globals.Index).
-			Size(documentProcessingThreads*scrollingMultiplier).
-			Sort("_doc", true).
-			SearchSource(elastic.NewSearchSource().SeqNoAndPrimaryTerm(true))
-		for {
-			results, err := scroll.Do(ctx)
-			if errors.Is(err, io.EOF) {
-				return nil
-			} else if err != nil {
-				return errors.WithStack(err)
-			}
+		defer close(documents)
 
-			for _, hit := range results.Hits.Hits {
-				select {
-				case hits <- hit:
-				case <-ctx.Done():
-					return errors.WithStack(ctx.Err())
+		return backend.Scroll(ctx, query, search.FullProjection(), func(document *search.Document, version search.Version) errors.E {
+			if seen != nil {
+				if _, loaded := seen.LoadOrStore(document.ID, true); loaded {
+					count.Increment()
+					return nil
 				}
 			}
-		}
+			select {
+			case documents <- scrolledDocument{Document: document, Version: version}:
+				return nil
+			case <-ctx.Done():
+				return errors.WithStack(ctx.Err())
+			}
+		})
 	})
 
 	for i := 0; i < documentProcessingThreads; i++ {
 		g.Go(func() error {
 			for {
 				select {
-				case hit, ok := <-hits:
+				case document, ok := <-documents:
 					if !ok {
 						return nil
 					}
-					err := c.updateEmbeddedDocumentsOne(ctx, globals.Index, globals.Log, esClient, processor, cache, hit)
-					if err != nil {
-						return err
-					}
+					updateEmbeddedDocumentsOne(ctx, globals.Log, backend, writer, cache, document.Document, document.Version)
 					count.Increment()
 				case <-ctx.Done():
 					return errors.WithStack(ctx.Err())
@@ -149,37 +149,33 @@ func (c *PrepareCommand) updateEmbeddedDocuments(
 	return errors.WithStack(g.Wait())
 }
 
-func (c *PrepareCommand) updateEmbeddedDocumentsOne(
-	ctx context.Context, index string, log zerolog.Logger, esClient *elastic.Client, processor *elastic.BulkProcessor, cache *wikipedia.Cache, hit *elastic.SearchHit,
-) errors.E {
-	var document search.Document
-	errE := x.UnmarshalWithoutUnknownFields(hit.Source, &document)
+func updateEmbeddedDocumentsOne(
+	ctx context.Context, log zerolog.Logger, backend search.Backend, writer *documentWriter, cache *wikipedia.Cache,
+	document *search.Document, version search.Version,
+) {
+	// We compute the hash before the document is changed.
+	hash, errE := document.Hash()
 	if errE != nil {
 		details := errors.AllDetails(errE)
-		details["doc"] = hit.Id
+		details["doc"] = string(document.ID)
 		log.Error().Err(errE).Fields(details).Send()
-		return nil
+		return
 	}
 
-	// ID is not stored in the document, so we set it here ourselves.
-	document.ID = search.Identifier(hit.Id)
-
 	changed, errE := wikipedia.UpdateEmbeddedDocuments(
-		ctx, index, log, esClient, cache,
+		ctx, log, backend, cache,
 		&skippedWikidataEntities, &skippedWikimediaCommonsFiles,
-		&document,
+		document,
 	)
 	if errE != nil {
 		details := errors.AllDetails(errE)
 		details["doc"] = string(document.ID)
 		log.Error().Err(errE).Fields(details).Msg("updating embedded documents failed")
-		return nil
+		return
 	}
 
 	if changed {
 		log.Debug().Str("doc", string(document.ID)).Msg("updating document")
-		updateDocument(processor, index, *hit.SeqNo, *hit.PrimaryTerm, &document)
+		updateDocument(ctx, log, writer, version, hash, document)
 	}
-
-	return nil
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"gitlab.com/tozd/go/errors"
+
+	"gitlab.com/peerdb/search"
+	"gitlab.com/peerdb/search/identifier"
+)
+
+const (
+	// Maximum number of changed document IDs to search referrers for at once.
+	// It has to be lower than ElasticSearch's index.max_terms_count (65536 by default).
+	refreshBatchSize = 10000
+)
+
+// RefreshCommand updates embedded documents only in documents which reference changed documents.
+//
+// It reads IDs of documents whose name or score changed (one per line) and searches for documents
+// referencing them (with ElasticSearch, using the embeddedIds field into which IDs of all referenced
+// documents are copied at indexing time). Only those documents are then processed again the same way as in PrepareCommand.
+type RefreshCommand struct {
+	SkippedWikidataEntities      string `placeholder:"PATH" type:"path" help:"Load IDs of skipped Wikidata entities."`
+	SkippedWikimediaCommonsFiles string `placeholder:"PATH" type:"path" help:"Load filenames of skipped Wikimedia Commons files."`
+	Changes                      string `placeholder:"PATH" type:"path" required:"" help:"Load IDs of changed documents. Use \"-\" for stdin."`
+}
+
+func (c *RefreshCommand) Run(globals *Globals) (errE errors.E) {
+	errE = populateSkippedMap(c.SkippedWikidataEntities, &skippedWikidataEntities, &skippedWikidataEntitiesCount)
+	if errE != nil {
+		return errE
+	}
+
+	errE = populateSkippedMap(c.SkippedWikimediaCommonsFiles, &skippedWikimediaCommonsFiles, &skippedWikimediaCommonsFilesCount)
+	if errE != nil {
+		return errE
+	}
+
+	changes, errE := readChanges(c.Changes)
+	if errE != nil {
+		return errE
+	}
+
+	ctx, cancel, _, backend, writer, cache, errE := initializeBackend(globals)
+	if errE != nil {
+		return errE
+	}
+	defer cancel()
+	defer flushWriter(ctx, writer, &errE)
+
+	globals.Log.Info().Int("changes", len(changes)).Msg("refreshing referrers of changed documents")
+
+	// Set of already processed referrer document IDs, so that a document
+	// referencing changed documents from multiple batches is processed only once.
+	seen := sync.Map{}
+
+	for start := 0; start < len(changes); start += refreshBatchSize {
+		end := start + refreshBatchSize
+		if end > len(changes) {
+			end = len(changes)
+		}
+
+		ids := make([]search.Identifier, 0, end-start)
+		for _, id := range changes[start:end] {
+			ids = append(ids, search.Identifier(id))
+		}
+
+		errE = updateEmbeddedDocuments(ctx, globals, backend, writer, cache, search.Query{References: ids}, &seen)
+		if errE != nil {
+			return errE
+		}
+	}
+
+	return nil
+}
+
+// readChanges reads unique document IDs from the file at path, one per line.
+// Empty lines are ignored.
+func readChanges(path string) ([]string, errors.E) {
+	var r io.Reader
+	if path == "-" {
+		r = os.Stdin
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	changes := []string{}
+	// Set of already read document IDs.
+	read := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		id := strings.TrimSpace(scanner.Text())
+		if id == "" {
+			continue
+		}
+		if !identifier.Valid(id) {
+			errE := errors.New("invalid document ID")
+			errors.Details(errE)["doc"] = id
+			return nil, errE
+		}
+		if read[id] {
+			continue
+		}
+		read[id] = true
+		changes = append(changes, id)
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return changes, nil
+}
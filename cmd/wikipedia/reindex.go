@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/olivere/elastic/v7"
+	"gitlab.com/tozd/go/errors"
+
+	"gitlab.com/peerdb/search"
+)
+
+// ReindexCommand copies documents into a new index and switches the index alias to it.
+//
+// Other commands can write documents at the same time. Writes are blocked during the last
+// pass of copying and commands retry them until the alias is switched to the new index.
+type ReindexCommand struct {
+	Script string `placeholder:"SCRIPT" help:"Painless script to transform documents while copying them."`
+	Keep   bool   `help:"Keep the old index after reindexing."`
+}
+
+func (c *ReindexCommand) Run(globals *Globals) errors.E {
+	ctx, cancel, esClient, errE := initializeElasticSearch(globals)
+	if errE != nil {
+		return errE
+	}
+	defer cancel()
+
+	var script *elastic.Script
+	if c.Script != "" {
+		script = elastic.NewScript(c.Script)
+	}
+
+	old, index, errE := search.Reindex(ctx, esClient, globals.Log, globals.Index, script)
+	if errE != nil {
+		return errE
+	}
+
+	globals.Log.Info().Str("alias", globals.Index).Str("old", old).Str("new", index).Msg("reindexed")
+
+	if c.Keep || old == globals.Index {
+		// An index with the same name as the alias has already been deleted when swapping the alias.
+		return nil
+	}
+
+	_, err := esClient.DeleteIndex(old).Do(ctx)
+	if err != nil {
+		errE := errors.WithStack(err)
+		errors.Details(errE)["index"] = old
+		return errE
+	}
+
+	return nil
+}
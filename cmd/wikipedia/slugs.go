@@ -0,0 +1,67 @@
+package main
+
+import (
+	"gitlab.com/tozd/go/errors"
+
+	"gitlab.com/peerdb/search"
+)
+
+// SlugsCommand assigns unique human-readable slugs made from English names to documents.
+//
+// Existing slugs are kept if they still match documents' names. Otherwise a new slug is
+// assigned and the existing one is kept as a previous slug, so that URLs using it keep working.
+type SlugsCommand struct{}
+
+func (c *SlugsCommand) Run(globals *Globals) (errE errors.E) {
+	ctx, cancel, _, backend, writer, _, errE := initializeBackend(globals)
+	if errE != nil {
+		return errE
+	}
+	defer cancel()
+	defer flushWriter(ctx, writer, &errE)
+
+	slugs := search.NewSlugs()
+
+	globals.Log.Info().Msg("loading existing slugs")
+	query := search.Query{Props: [][]search.Identifier{{search.GetStandardPropertyID("SLUG"), search.GetStandardPropertyID("PREVIOUS_SLUG")}}}
+	projection := search.Projection{ClaimTypes: []string{"id"}, Props: nil, Active: true, Inactive: false, Meta: false}
+	errE = scrollDocuments(ctx, globals, backend, query, projection, func(document *search.Document, _ search.Version) errors.E {
+		slugs.AddDocument(document)
+		return nil
+	})
+	if errE != nil {
+		return errE
+	}
+
+	globals.Log.Info().Msg("assigning slugs")
+	var changed int64
+	errE = scrollDocuments(ctx, globals, backend, search.Query{}, search.FullProjection(), func(document *search.Document, version search.Version) errors.E {
+		// We compute the hash before the document is changed.
+		hash, errE := document.Hash()
+		if errE != nil {
+			details := errors.AllDetails(errE)
+			details["doc"] = string(document.ID)
+			globals.Log.Error().Err(errE).Fields(details).Send()
+			return nil
+		}
+		ok, errE := slugs.Assign(document)
+		if errE != nil {
+			details := errors.AllDetails(errE)
+			details["doc"] = string(document.ID)
+			globals.Log.Error().Err(errE).Fields(details).Msg("assigning slug failed")
+			return nil
+		}
+		if ok {
+			changed++
+			updateDocument(ctx, globals.Log, writer, version, hash, document)
+		}
+		return nil
+	})
+	if errE != nil {
+		return errE
+	}
+
+	globals.Log.Info().Int64("changed", changed).Msg("slugs assigned")
+
+	return nil
+}
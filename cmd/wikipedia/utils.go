@@ -20,6 +20,7 @@ import (
 
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/go-retryablehttp"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/olivere/elastic/v7"
 	"github.com/rs/zerolog"
 	"golang.org/x/sync/errgroup"
@@ -38,16 +39,324 @@ const (
 	lruCacheSize = 1000000
 )
 
+// indexedDocuments maps IDs of recently indexed documents to hashes of their content.
+// It is used to skip indexing the same document again (e.g., when it is present
+// multiple times in a dump or when it has been processed already).
+// Entries are invalidated when documents are updated through updateDocument
+// and when documents fail to be written.
+var indexedDocuments, _ = lru.New(lruCacheSize)
+
+// documentWriter writes documents to the backend in batches, using multiple workers.
+//
+// Batches (or only documents in them) which the backend could not accept at the moment
+// are retried with exponential backoff. Errors are logged and counted, but otherwise
+// ignored, so that one failed batch does not stop the whole pass. Flush reports
+// if any document failed to be written.
+//
+// It is safe for concurrent use.
+type documentWriter struct {
+	backend search.Backend
+	log     zerolog.Logger
+
+	mu        sync.Mutex
+	documents []*search.Document
+	updates   []search.Update
+	flushed   bool
+
+	batches chan writerBatch
+	workers sync.WaitGroup
+
+	indexed int64
+	failed  int64
+}
+
+// writerBatch is a batch of documents to insert or a batch of updates, but not both.
+type writerBatch struct {
+	documents []*search.Document
+	updates   []search.Update
+}
+
+func (b writerBatch) len() int {
+	return len(b.documents) + len(b.updates)
+}
+
+func (b writerBatch) ids() []search.Identifier {
+	ids := make([]search.Identifier, 0, b.len())
+	for _, document := range b.documents {
+		ids = append(ids, document.ID)
+	}
+	for _, update := range b.updates {
+		ids = append(ids, update.Document.ID)
+	}
+	return ids
+}
+
+// only returns a batch with only documents with IDs.
+func (b writerBatch) only(ids []search.Identifier) writerBatch {
+	set := make(map[search.Identifier]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	batch := writerBatch{documents: nil, updates: nil}
+	for _, document := range b.documents {
+		if set[document.ID] {
+			batch.documents = append(batch.documents, document)
+		}
+	}
+	for _, update := range b.updates {
+		if set[update.Document.ID] {
+			batch.updates = append(batch.updates, update)
+		}
+	}
+	return batch
+}
+
+// newDocumentWriter returns a new writer with workers running until Flush is called
+// (or until the context is canceled).
+func newDocumentWriter(ctx context.Context, backend search.Backend, log zerolog.Logger) *documentWriter {
+	w := &documentWriter{
+		backend:   backend,
+		log:       log,
+		mu:        sync.Mutex{},
+		documents: []*search.Document{},
+		updates:   []search.Update{},
+		flushed:   false,
+		batches:   make(chan writerBatch, writerWorkers),
+		workers:   sync.WaitGroup{},
+		indexed:   0,
+		failed:    0,
+	}
+
+	for i := 0; i < writerWorkers; i++ {
+		w.workers.Add(1)
+		go func() {
+			defer w.workers.Done()
+			// Loop ends when batches is closed, which happens in Flush.
+			for batch := range w.batches {
+				w.write(ctx, batch)
+			}
+		}()
+	}
+
+	return w
+}
+
+// Insert queues the document to be inserted or to replace an existing document with the same ID.
+func (w *documentWriter) Insert(ctx context.Context, doc *search.Document) {
+	w.mu.Lock()
+	w.documents = append(w.documents, doc)
+	var documents []*search.Document
+	if len(w.documents) >= writerBatchSize {
+		documents = w.documents
+		w.documents = []*search.Document{}
+	}
+	w.mu.Unlock()
+
+	w.queue(ctx, writerBatch{documents: documents, updates: nil})
+}
+
+// Update queues the document to replace the existing document with the same ID,
+// if the existing document has not changed since it has been read (based on its version).
+func (w *documentWriter) Update(ctx context.Context, version search.Version, doc *search.Document) {
+	w.mu.Lock()
+	w.updates = append(w.updates, search.Update{Document: doc, Version: version})
+	var updates []search.Update
+	if len(w.updates) >= writerBatchSize {
+		updates = w.updates
+		w.updates = []search.Update{}
+	}
+	w.mu.Unlock()
+
+	w.queue(ctx, writerBatch{documents: nil, updates: updates})
+}
+
+// Flush writes all queued documents, waits for workers to finish, and refreshes
+// the backend so that written documents are visible. It returns an error if any
+// document failed to be written. The writer cannot be used after Flush and
+// calling Flush again does nothing.
+func (w *documentWriter) Flush(ctx context.Context) errors.E {
+	w.mu.Lock()
+	if w.flushed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.flushed = true
+	documents := w.documents
+	updates := w.updates
+	w.documents = []*search.Document{}
+	w.updates = []search.Update{}
+	w.mu.Unlock()
+
+	w.queue(ctx, writerBatch{documents: documents, updates: nil})
+	w.queue(ctx, writerBatch{documents: nil, updates: updates})
+
+	close(w.batches)
+	w.workers.Wait()
+
+	errE := w.backend.Refresh(ctx)
+	if errE != nil {
+		return errE
+	}
+
+	indexed, failed := w.Stats()
+	if failed > 0 {
+		errE := errors.New("some documents failed to be written")
+		errors.Details(errE)["failed"] = failed
+		errors.Details(errE)["indexed"] = indexed
+		return errE
+	}
+
+	return nil
+}
+
+// Stats returns the number of written documents and the number of documents
+// which failed to be written.
+func (w *documentWriter) Stats() (int64, int64) {
+	return atomic.LoadInt64(&w.indexed), atomic.LoadInt64(&w.failed)
+}
+
+func (w *documentWriter) queue(ctx context.Context, batch writerBatch) {
+	if batch.len() == 0 {
+		return
+	}
+
+	select {
+	case w.batches <- batch:
+	case <-ctx.Done():
+		w.log.Error().Err(ctx.Err()).Int("count", batch.len()).Msg("indexing error")
+		w.fail(batch.ids())
+	}
+}
+
+// write writes the batch, retrying the whole batch if the backend returns an error,
+// or only documents which the backend could not accept at the moment.
+func (w *documentWriter) write(ctx context.Context, batch writerBatch) {
+	for retry := 0; ; retry++ {
+		var result *search.BulkResult
+		var errE errors.E
+		if batch.documents != nil {
+			result, errE = w.backend.Bulk(ctx, batch.documents)
+		} else {
+			result, errE = w.backend.BulkUpdate(ctx, batch.updates)
+		}
+		if errE != nil {
+			if retry < writerRetryMax && w.wait(ctx, retry) {
+				w.log.Warn().Err(errE).Fields(errors.AllDetails(errE)).Int("count", batch.len()).Int("retry", retry+1).Msg("indexing error, retrying")
+				continue
+			}
+			w.log.Error().Err(errE).Fields(errors.AllDetails(errE)).Int("count", batch.len()).Msg("indexing error")
+			w.fail(batch.ids())
+			return
+		}
+
+		for _, id := range result.Conflicts {
+			w.log.Error().Str("doc", string(id)).Msg("indexing error: document changed since it has been read")
+		}
+		failed := []search.Identifier{}
+		for id, reason := range result.Failed {
+			w.log.Error().Str("doc", string(id)).Str("reason", reason).Msg("indexing error")
+			failed = append(failed, id)
+		}
+		w.fail(result.Conflicts)
+		w.fail(failed)
+		atomic.AddInt64(&w.indexed, int64(batch.len()-len(result.Conflicts)-len(result.Failed)-len(result.Temporary)))
+
+		if len(result.Temporary) == 0 {
+			return
+		}
+
+		batch = batch.only(result.Temporary)
+		if retry >= writerRetryMax || !w.wait(ctx, retry) {
+			for _, id := range result.Temporary {
+				w.log.Error().Str("doc", string(id)).Msg("indexing error: backend did not accept the document")
+			}
+			w.fail(result.Temporary)
+			return
+		}
+	}
+}
+
+// fail records that documents with IDs failed to be written. They are removed from
+// indexedDocuments so that they are not skipped if they are inserted again.
+func (w *documentWriter) fail(ids []search.Identifier) {
+	for _, id := range ids {
+		indexedDocuments.Remove(id)
+	}
+	atomic.AddInt64(&w.failed, int64(len(ids)))
+}
+
+// wait waits before the retry with exponential backoff. It returns false if the context
+// has been canceled in the meantime.
+func (w *documentWriter) wait(ctx context.Context, retry int) bool {
+	wait := writerRetryWaitMin << retry
+	if wait > writerRetryWaitMax {
+		wait = writerRetryWaitMax
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// flushWriter flushes the writer. It is meant to be deferred and it stores the error from
+// flushing into errE, unless errE already contains an error.
+func flushWriter(ctx context.Context, writer *documentWriter, errE *errors.E) {
+	err := writer.Flush(ctx)
+	if err != nil && *errE == nil {
+		*errE = err
+	}
+}
+
 // insertOrReplaceDocument inserts or replaces the document based on its ID.
-func insertOrReplaceDocument(processor *elastic.BulkProcessor, index string, doc *search.Document) {
-	req := elastic.NewBulkIndexRequest().Index(index).Id(string(doc.ID)).Doc(doc)
-	processor.Add(req)
+// It does nothing if the same document has just recently been inserted.
+func insertOrReplaceDocument(ctx context.Context, log zerolog.Logger, writer *documentWriter, doc *search.Document) {
+	hash, errE := doc.Hash()
+	if errE != nil {
+		log.Warn().Str("doc", string(doc.ID)).Err(errE).Fields(errors.AllDetails(errE)).Msg("unable to hash document")
+	} else {
+		if h, ok := indexedDocuments.Get(doc.ID); ok && h.(string) == hash {
+			log.Debug().Str("doc", string(doc.ID)).Msg("document already indexed")
+			return
+		}
+		indexedDocuments.Add(doc.ID, hash)
+	}
+
+	writer.Insert(ctx, doc)
+}
+
+// documentHash returns the hash of the document. It should be called before the document is changed,
+// so that updateDocument can determine if the document changed. It returns an empty string if the
+// document cannot be hashed.
+func documentHash(log zerolog.Logger, doc *search.Document) string {
+	hash, errE := doc.Hash()
+	if errE != nil {
+		log.Warn().Str("doc", string(doc.ID)).Err(errE).Fields(errors.AllDetails(errE)).Msg("unable to hash document")
+		return ""
+	}
+	return hash
 }
 
-// updateDocument updates the document in the index, if it has not changed in the database since it was fetched (based on seqNo and primaryTerm).
-func updateDocument(processor *elastic.BulkProcessor, index string, seqNo, primaryTerm int64, doc *search.Document) {
-	req := elastic.NewBulkIndexRequest().Index(index).Id(string(doc.ID)).IfSeqNo(seqNo).IfPrimaryTerm(primaryTerm).Doc(&doc)
-	processor.Add(req)
+// updateDocument updates the document in the backend, if it has not changed in the backend since it was read
+// (based on the version of the document as it was read). It does nothing if the document is the same as the
+// read one (based on the hash of the document as it was read, see documentHash).
+func updateDocument(ctx context.Context, log zerolog.Logger, writer *documentWriter, version search.Version, hash string, doc *search.Document) {
+	// The document might have been changed since it was inserted (or it might be changed now),
+	// so we cannot know anymore if the cached hash matches the indexed document.
+	indexedDocuments.Remove(doc.ID)
+
+	newHash, errE := doc.Hash()
+	if errE != nil {
+		log.Warn().Str("doc", string(doc.ID)).Err(errE).Fields(errors.AllDetails(errE)).Msg("unable to determine if document changed")
+	} else if hash != "" && newHash == hash {
+		log.Debug().Str("doc", string(doc.ID)).Msg("document unchanged")
+		return
+	}
+
+	writer.Update(ctx, version, doc)
 }
 
 func populateSkippedMap(path string, skippedMap *sync.Map, count *int64) errors.E {
@@ -149,10 +458,8 @@ func (a retryableHTTPLoggerAdapter) Warn(msg string, keysAndValues ...interface{
 
 var _ retryablehttp.LeveledLogger = (*retryableHTTPLoggerAdapter)(nil)
 
-func initializeElasticSearch(globals *Globals) (
-	context.Context, context.CancelFunc, *http.Client, *elastic.Client,
-	*elastic.BulkProcessor, *wikipedia.Cache, errors.E,
-) {
+// newContext returns a context which is canceled on SIGINT or SIGTERM signal.
+func newContext() (context.Context, context.CancelFunc) {
 	ctx := context.Background()
 
 	// We call cancel on SIGINT or SIGTERM signal.
@@ -175,38 +482,56 @@ func initializeElasticSearch(globals *Globals) (
 		}
 	}()
 
+	return ctx, cancel
+}
+
+// initializeElasticSearch is used by commands which manage the ElasticSearch index itself
+// and have nothing to do with other backends.
+func initializeElasticSearch(globals *Globals) (context.Context, context.CancelFunc, *elastic.Client, errors.E) {
+	if globals.Backend != backendElastic {
+		errE := errors.New("command supports only elastic backend")
+		errors.Details(errE)["backend"] = globals.Backend
+		return nil, nil, nil, errE
+	}
+
+	ctx, cancel := newContext()
+
+	esClient, errE := search.EnsureIndex(ctx, cleanhttp.DefaultPooledClient(), globals.Log, globals.Elastic, globals.Index)
+	if errE != nil {
+		cancel()
+		return nil, nil, nil, errE
+	}
+
+	return ctx, cancel, esClient, nil
+}
+
+// initializeBackend opens the backend configured with global flags. The returned
+// cancel function cancels the returned context and closes the backend.
+func initializeBackend(globals *Globals) (
+	context.Context, context.CancelFunc, *http.Client, search.Backend,
+	*documentWriter, *wikipedia.Cache, errors.E,
+) {
+	ctx, cancel := newContext()
+
 	httpClient := cleanhttp.DefaultPooledClient()
 
-	esClient, errE := search.EnsureIndex(ctx, httpClient, globals.Log, globals.Elastic, globals.Index)
+	backend, closeBackend, errE := openBackend(ctx, globals, httpClient)
 	if errE != nil {
+		cancel()
 		return nil, nil, nil, nil, nil, nil, errE
 	}
 
 	cache, errE := wikipedia.NewCache(lruCacheSize)
 	if errE != nil {
+		closeBackend()
+		cancel()
 		return nil, nil, nil, nil, nil, nil, errE
 	}
 
-	// TODO: Make number of workers configurable.
-	processor, err := esClient.BulkProcessor().Workers(bulkProcessorWorkers).Stats(true).After(
-		func(executionId int64, requests []elastic.BulkableRequest, response *elastic.BulkResponse, err error) {
-			if err != nil {
-				globals.Log.Error().Err(err).Msg("indexing error")
-			} else if failed := response.Failed(); len(failed) > 0 {
-				for _, f := range failed {
-					globals.Log.Error().
-						Str("id", f.Id).Int("code", f.Status).
-						Str("reason", f.Error.Reason).Str("type", f.Error.Type).
-						Msg("indexing error")
-				}
-			}
-		},
-	).Do(ctx)
-	if err != nil {
-		return nil, nil, nil, nil, nil, nil, errors.WithStack(err)
-	}
-
-	return ctx, cancel, httpClient, esClient, processor, cache, nil
+	return ctx, func() {
+		closeBackend()
+		cancel()
+	}, httpClient, backend, newDocumentWriter(ctx, backend, globals.Log), cache, nil
 }
 
 func initializeRun(
@@ -214,10 +539,10 @@ func initializeRun(
 	urlFunc func(context.Context, *retryablehttp.Client) (string, errors.E),
 	count *int64,
 ) (
-	context.Context, context.CancelFunc, *retryablehttp.Client, *elastic.Client,
-	*elastic.BulkProcessor, *wikipedia.Cache, *mediawiki.ProcessDumpConfig, errors.E,
+	context.Context, context.CancelFunc, *retryablehttp.Client, search.Backend,
+	*documentWriter, *wikipedia.Cache, *mediawiki.ProcessDumpConfig, errors.E,
 ) {
-	ctx, cancel, simpleHTTPClient, esClient, processor, cache, errE := initializeElasticSearch(globals)
+	ctx, cancel, simpleHTTPClient, backend, writer, cache, errE := initializeBackend(globals)
 	if errE != nil {
 		return nil, nil, nil, nil, nil, nil, nil, errE
 	}
@@ -237,6 +562,7 @@ func initializeRun(
 	if urlFunc != nil {
 		url, errE := urlFunc(ctx, httpClient)
 		if errE != nil {
+			cancel()
 			return nil, nil, nil, nil, nil, nil, nil, errE
 		}
 
@@ -250,7 +576,7 @@ func initializeRun(
 			url = ""
 		}
 
-		return ctx, cancel, httpClient, esClient, processor, cache, &mediawiki.ProcessDumpConfig{
+		return ctx, cancel, httpClient, backend, writer, cache, &mediawiki.ProcessDumpConfig{
 			URL:                    url,
 			Path:                   dumpPath,
 			Client:                 httpClient,
@@ -258,9 +584,9 @@ func initializeRun(
 			DecodingThreads:        globals.DecodingThreads,
 			ItemsProcessingThreads: globals.ItemsProcessingThreads,
 			Progress: func(ctx context.Context, p x.Progress) {
-				stats := processor.Stats()
+				indexed, failed := writer.Stats()
 				e := globals.Log.Info().
-					Int64("failed", stats.Failed).Int64("indexed", stats.Succeeded).
+					Int64("failed", failed).Int64("indexed", indexed).
 					Uint64("cacheMiss", cache.MissCount()).Str("eta", p.Remaining().Truncate(time.Second).String())
 				if count != nil {
 					e = e.Int64("skipped", atomic.LoadInt64(count))
@@ -270,21 +596,21 @@ func initializeRun(
 		}, nil
 	}
 
-	return ctx, cancel, httpClient, esClient, processor, cache, nil, nil
+	return ctx, cancel, httpClient, backend, writer, cache, nil, nil
 }
 
-func templatesCommandRun(globals *Globals, site, skippedWikidataEntitiesPath, mnemonicPrefix, from string) errors.E {
-	errE := populateSkippedMap(skippedWikidataEntitiesPath, &skippedWikidataEntities, &skippedWikidataEntitiesCount)
+func templatesCommandRun(globals *Globals, site, skippedWikidataEntitiesPath, mnemonicPrefix, from string) (errE errors.E) {
+	errE = populateSkippedMap(skippedWikidataEntitiesPath, &skippedWikidataEntities, &skippedWikidataEntitiesCount)
 	if errE != nil {
 		return errE
 	}
 
-	ctx, cancel, httpClient, esClient, processor, _, _, errE := initializeRun(globals, nil, nil)
+	ctx, cancel, httpClient, backend, writer, _, _, errE := initializeRun(globals, nil, nil)
 	if errE != nil {
 		return errE
 	}
 	defer cancel()
-	defer processor.Close()
+	defer flushWriter(ctx, writer, &errE)
 
 	pages := make(chan wikipedia.AllPagesPage, wikipedia.APILimit)
 	rateLimit := wikipediaRESTRateLimit / wikipediaRESTRatePeriod.Seconds()
@@ -301,9 +627,9 @@ func templatesCommandRun(globals *Globals, site, skippedWikidataEntitiesPath, mn
 	defer ticker.Stop()
 	go func() {
 		for p := range ticker.C {
-			stats := processor.Stats()
+			indexed, failed := writer.Stats()
 			globals.Log.Info().
-				Int64("failed", stats.Failed).Int64("indexed", stats.Succeeded).Int64("docs", count.Count()).
+				Int64("failed", failed).Int64("indexed", indexed).Int64("docs", count.Count()).
 				Str("elapsed", p.Elapsed.Truncate(time.Second).String()).
 				Send()
 		}
@@ -348,7 +674,7 @@ func templatesCommandRun(globals *Globals, site, skippedWikidataEntitiesPath, mn
 
 				count.Increment()
 
-				errE = templatesCommandProcessPage(ctx, globals, esClient, processor, page, html, mnemonicPrefix, from)
+				errE = templatesCommandProcessPage(ctx, globals, backend, writer, page, html, mnemonicPrefix, from)
 				if errE != nil {
 					return errE
 				}
@@ -361,7 +687,7 @@ func templatesCommandRun(globals *Globals, site, skippedWikidataEntitiesPath, mn
 }
 
 func templatesCommandProcessPage(
-	ctx context.Context, globals *Globals, esClient *elastic.Client, processor *elastic.BulkProcessor,
+	ctx context.Context, globals *Globals, backend search.Backend, writer *documentWriter,
 	page wikipedia.AllPagesPage, html, mnemonicPrefix, from string,
 ) errors.E {
 	// We know this is available because we check before calling this method.
@@ -372,7 +698,7 @@ func templatesCommandProcessPage(
 		return nil
 	}
 
-	document, hit, err := wikipedia.GetWikidataItem(ctx, globals.Index, esClient, id)
+	document, version, err := wikipedia.GetWikidataItem(ctx, backend, id)
 	if err != nil {
 		details := errors.AllDetails(err)
 		details["entity"] = id
@@ -385,6 +711,9 @@ func templatesCommandProcessPage(
 		return nil
 	}
 
+	// We compute the hash before the document is changed.
+	hash := documentHash(globals.Log, document)
+
 	err = wikipedia.SetPageID(wikipedia.NameSpaceWikidata, mnemonicPrefix, id, page.Identifier, document)
 	if err != nil {
 		details := errors.AllDetails(err)
@@ -436,7 +765,7 @@ func templatesCommandProcessPage(
 	}
 
 	globals.Log.Debug().Str("doc", string(document.ID)).Str("entity", id).Str("title", page.Title).Msg("updating document")
-	updateDocument(processor, globals.Index, *hit.SeqNo, *hit.PrimaryTerm, document)
+	updateDocument(ctx, globals.Log, writer, version, hash, document)
 
 	return nil
 }
@@ -446,13 +775,13 @@ func filesCommandRun(
 	urlFunc func(context.Context, *retryablehttp.Client) (string, errors.E),
 	token string, apiLimit int, saveSkipped string, skippedMap *sync.Map, skippedCount *int64,
 	convertImage func(context.Context, zerolog.Logger, *retryablehttp.Client, string, int, wikipedia.Image) (*search.Document, errors.E),
-) errors.E {
-	ctx, cancel, httpClient, _, processor, _, config, errE := initializeRun(globals, urlFunc, skippedCount)
+) (errE errors.E) {
+	ctx, cancel, httpClient, _, writer, _, config, errE := initializeRun(globals, urlFunc, skippedCount)
 	if errE != nil {
 		return errE
 	}
 	defer cancel()
-	defer processor.Close()
+	defer flushWriter(ctx, writer, &errE)
 
 	errE = mediawiki.Process(ctx, &mediawiki.ProcessConfig[wikipedia.Image]{
 		URL:                    config.URL,
@@ -463,7 +792,7 @@ func filesCommandRun(
 		ItemsProcessingThreads: config.ItemsProcessingThreads,
 		Process: func(ctx context.Context, i wikipedia.Image) errors.E {
 			return filesCommandProcessImage(
-				ctx, globals, httpClient, processor, token, apiLimit, skippedMap, skippedCount, i, convertImage,
+				ctx, globals, httpClient, writer, token, apiLimit, skippedMap, skippedCount, i, convertImage,
 			)
 		},
 		Progress:    config.Progress,
@@ -483,7 +812,7 @@ func filesCommandRun(
 }
 
 func filesCommandProcessImage(
-	ctx context.Context, globals *Globals, httpClient *retryablehttp.Client, processor *elastic.BulkProcessor,
+	ctx context.Context, globals *Globals, httpClient *retryablehttp.Client, writer *documentWriter,
 	token string, apiLimit int, skippedMap *sync.Map, skippedCount *int64, image wikipedia.Image,
 	convertImage func(context.Context, zerolog.Logger, *retryablehttp.Client, string, int, wikipedia.Image) (*search.Document, errors.E),
 ) errors.E {
@@ -506,7 +835,7 @@ func filesCommandProcessImage(
 	}
 
 	globals.Log.Debug().Str("doc", string(document.ID)).Str("file", image.Name).Msg("saving document")
-	insertOrReplaceDocument(processor, globals.Index, document)
+	insertOrReplaceDocument(ctx, globals.Log, writer, document)
 
 	return nil
 }
@@ -4,15 +4,23 @@ import (
 	"context"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
-	"github.com/olivere/elastic/v7"
 	"gitlab.com/tozd/go/errors"
 	"gitlab.com/tozd/go/mediawiki"
+	"gitlab.com/tozd/go/x"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
 
+	"gitlab.com/peerdb/search"
 	"gitlab.com/peerdb/search/internal/wikipedia"
 )
 
+const (
+	itemsWikidataNamespace = 0
+)
+
 var (
 	// Set of document IDs.
 	skippedWikidataEntities      = sync.Map{}
@@ -39,7 +47,7 @@ type WikidataCommand struct {
 	URL         string `placeholder:"URL" help:"URL of Wikidata entities JSON dump to use. It can be a local file path, too. Default: the latest."`
 }
 
-func (c *WikidataCommand) Run(globals *Globals) errors.E {
+func (c *WikidataCommand) Run(globals *Globals) (errE errors.E) {
 	var urlFunc func(_ context.Context, _ *retryablehttp.Client) (string, errors.E)
 	if c.URL != "" {
 		urlFunc = func(_ context.Context, _ *retryablehttp.Client) (string, errors.E) {
@@ -49,15 +57,15 @@ func (c *WikidataCommand) Run(globals *Globals) errors.E {
 		urlFunc = mediawiki.LatestWikidataEntitiesRun
 	}
 
-	ctx, cancel, _, esClient, processor, cache, config, errE := initializeRun(globals, urlFunc, &skippedWikidataEntitiesCount)
+	ctx, cancel, _, backend, writer, cache, config, errE := initializeRun(globals, urlFunc, &skippedWikidataEntitiesCount)
 	if errE != nil {
 		return errE
 	}
 	defer cancel()
-	defer processor.Close()
+	defer flushWriter(ctx, writer, &errE)
 
-	errE = mediawiki.ProcessWikidataDump(ctx, config, func(ctx context.Context, entity mediawiki.Entity) errors.E {
-		return c.processEntity(ctx, globals, esClient, cache, processor, entity)
+	errE = wikipedia.ProcessWikidataDump(ctx, config, func(ctx context.Context, entity mediawiki.Entity) errors.E {
+		return c.processEntity(ctx, globals, backend, cache, writer, entity)
 	})
 	if errE != nil {
 		return errE
@@ -72,9 +80,9 @@ func (c *WikidataCommand) Run(globals *Globals) errors.E {
 }
 
 func (c *WikidataCommand) processEntity(
-	ctx context.Context, globals *Globals, esClient *elastic.Client, cache *wikipedia.Cache, processor *elastic.BulkProcessor, entity mediawiki.Entity,
+	ctx context.Context, globals *Globals, backend search.Backend, cache *wikipedia.Cache, writer *documentWriter, entity mediawiki.Entity,
 ) errors.E {
-	document, err := wikipedia.ConvertEntity(ctx, globals.Index, globals.Log, esClient, cache, wikipedia.NameSpaceWikimediaCommonsFile, entity)
+	document, err := wikipedia.ConvertEntity(ctx, globals.Log, backend, cache, wikipedia.NameSpaceWikimediaCommonsFile, entity)
 	if err != nil {
 		if errors.Is(err, wikipedia.SilentSkippedError) {
 			globals.Log.Debug().Str("entity", entity.ID).Err(err).Fields(errors.AllDetails(err)).Send()
@@ -92,7 +100,118 @@ func (c *WikidataCommand) processEntity(
 	}
 
 	globals.Log.Debug().Str("doc", string(document.ID)).Str("entity", entity.ID).Msg("saving document")
-	insertOrReplaceDocument(processor, globals.Index, document)
+	insertOrReplaceDocument(ctx, globals.Log, writer, document)
+
+	return nil
+}
+
+// WikidataRedirectsCommand uses Wikidata API as input to obtain redirects between Wikidata items, which are made when items are merged,
+// and adds ALIAS claims to documents of items other items were merged into. Requests for documents of merged items are then
+// redirected to documents of items they were merged into.
+//
+// It expects documents populated by WikidataCommand.
+//
+// Wikidata entities dump does not contain redirects, so this command lists all items which are targets of redirects using the API.
+type WikidataRedirectsCommand struct {
+	SkippedEntities string `placeholder:"PATH" type:"path" help:"Load IDs of skipped Wikidata entities."`
+}
+
+func (c *WikidataRedirectsCommand) Run(globals *Globals) (errE errors.E) {
+	errE = populateSkippedMap(c.SkippedEntities, &skippedWikidataEntities, &skippedWikidataEntitiesCount)
+	if errE != nil {
+		return errE
+	}
+
+	ctx, cancel, httpClient, backend, writer, _, _, errE := initializeRun(globals, nil, nil)
+	if errE != nil {
+		return errE
+	}
+	defer cancel()
+	defer flushWriter(ctx, writer, &errE)
+
+	pages := make(chan wikipedia.AllPagesPage, wikipedia.APILimit)
+	rateLimit := wikipediaRESTRateLimit / wikipediaRESTRatePeriod.Seconds()
+	limiter := rate.NewLimiter(rate.Limit(rateLimit), wikipediaRESTRateLimit)
+	g, ctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		defer close(pages)
+		return wikipedia.ListAllRedirectTargets(ctx, httpClient, []int{itemsWikidataNamespace}, "www.wikidata.org", limiter, pages)
+	})
+
+	var count x.Counter
+	ticker := x.NewTicker(ctx, &count, 0, progressPrintRate)
+	defer ticker.Stop()
+	go func() {
+		for p := range ticker.C {
+			indexed, failed := writer.Stats()
+			globals.Log.Info().
+				Int64("failed", failed).Int64("indexed", indexed).Int64("docs", count.Count()).
+				Str("elapsed", p.Elapsed.Truncate(time.Second).String()).
+				Send()
+		}
+	}()
+
+	for i := 0; i < int(rateLimit); i++ {
+		g.Go(func() error {
+			// Loop ends with pages is closed, which happens when context is cancelled, too.
+			for page := range pages {
+				if page.Missing {
+					globals.Log.Debug().Str("title", page.Title).Msg("redirect to a missing item")
+					continue
+				}
+
+				count.Increment()
+
+				errE := c.processPage(ctx, globals, backend, writer, page)
+				if errE != nil {
+					return errE
+				}
+			}
+			return nil
+		})
+	}
+
+	return errors.WithStack(g.Wait())
+}
+
+func (c *WikidataRedirectsCommand) processPage(
+	ctx context.Context, globals *Globals, backend search.Backend, writer *documentWriter, page wikipedia.AllPagesPage,
+) errors.E {
+	// Titles of items are their IDs.
+	id := page.Title
+
+	if _, ok := skippedWikidataEntities.Load(string(wikipedia.GetWikidataDocumentID(id))); ok {
+		globals.Log.Debug().Str("entity", id).Msg("skipped entity")
+		return nil
+	}
+
+	document, version, err := wikipedia.GetWikidataItem(ctx, backend, id)
+	if err != nil {
+		details := errors.AllDetails(err)
+		details["entity"] = id
+		if errors.Is(err, wikipedia.NotFoundError) {
+			globals.Log.Warn().Err(err).Fields(details).Send()
+		} else {
+			globals.Log.Error().Err(err).Fields(details).Send()
+		}
+		return nil
+	}
+
+	// We compute the hash before the document is changed.
+	hash := documentHash(globals.Log, document)
+
+	err = wikipedia.ConvertWikidataRedirects(globals.Log, id, page, document)
+	if err != nil {
+		details := errors.AllDetails(err)
+		details["doc"] = string(document.ID)
+		details["entity"] = id
+		globals.Log.Error().Err(err).Fields(details).Send()
+		return nil
+	}
+
+	globals.Log.Debug().Str("doc", string(document.ID)).Str("entity", id).Msg("updating document")
+	updateDocument(ctx, globals.Log, writer, version, hash, document)
 
 	return nil
 }
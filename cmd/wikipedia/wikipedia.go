@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
-	"github.com/olivere/elastic/v7"
 	"gitlab.com/tozd/go/errors"
 	"gitlab.com/tozd/go/mediawiki"
 
@@ -101,17 +100,17 @@ func (c *WikipediaFilesCommand) Run(globals *Globals) errors.E {
 //
 // Internal links inside HTML are not yet converted to links to PeerDB documents. This is done in PrepareCommand.
 //
-// It accesses existing documents in ElasticSearch to load corresponding file's document which is then updated with claims with the
+// It accesses existing documents in the backend to load corresponding file's document which is then updated with claims with the
 // following properties: ENGLISH_WIKIPEDIA_PAGE_ID (internal page ID of the file), DESCRIPTION (potentially multiple),
-// ALSO_KNOWN_AS (from redirects pointing to the file), IN_ENGLISH_WIKIPEDIA_CATEGORY (for categories the file is in),
+// ALSO_KNOWN_AS and ALIAS (from redirects pointing to the file), IN_ENGLISH_WIKIPEDIA_CATEGORY (for categories the file is in),
 // USES_ENGLISH_WIKIPEDIA_TEMPLATE (for templates used).
 type WikipediaFileDescriptionsCommand struct {
 	SkippedFiles string `placeholder:"PATH" type:"path" help:"Load filenames of skipped Wikipedia files."`
 	URL          string `placeholder:"URL" help:"URL of Wikipedia file descriptions HTML dump to use. It can be a local file path, too. Default: the latest."`
 }
 
-func (c *WikipediaFileDescriptionsCommand) Run(globals *Globals) errors.E {
-	errE := populateSkippedMap(c.SkippedFiles, &skippedWikipediaFiles, &skippedWikipediaFilesCount)
+func (c *WikipediaFileDescriptionsCommand) Run(globals *Globals) (errE errors.E) {
+	errE = populateSkippedMap(c.SkippedFiles, &skippedWikipediaFiles, &skippedWikipediaFilesCount)
 	if errE != nil {
 		return errE
 	}
@@ -127,15 +126,15 @@ func (c *WikipediaFileDescriptionsCommand) Run(globals *Globals) errors.E {
 		}
 	}
 
-	ctx, cancel, _, esClient, processor, _, config, errE := initializeRun(globals, urlFunc, nil)
+	ctx, cancel, _, backend, writer, _, config, errE := initializeRun(globals, urlFunc, nil)
 	if errE != nil {
 		return errE
 	}
 	defer cancel()
-	defer processor.Close()
+	defer flushWriter(ctx, writer, &errE)
 
 	errE = mediawiki.ProcessWikipediaDump(ctx, config, func(ctx context.Context, article mediawiki.Article) errors.E {
-		return c.processArticle(ctx, globals, esClient, processor, article)
+		return c.processArticle(ctx, globals, backend, writer, article)
 	})
 	if errE != nil {
 		return errE
@@ -145,7 +144,7 @@ func (c *WikipediaFileDescriptionsCommand) Run(globals *Globals) errors.E {
 }
 
 func (c *WikipediaFileDescriptionsCommand) processArticle(
-	ctx context.Context, globals *Globals, esClient *elastic.Client, processor *elastic.BulkProcessor, article mediawiki.Article,
+	ctx context.Context, globals *Globals, backend search.Backend, writer *documentWriter, article mediawiki.Article,
 ) errors.E {
 	filename := strings.TrimPrefix(article.Name, "File:")
 	// First we make sure we do not have spaces.
@@ -161,7 +160,7 @@ func (c *WikipediaFileDescriptionsCommand) processArticle(
 	// Dump contains descriptions of Wikipedia files and of Wikimedia Commons files (used on Wikipedia).
 	// We want to use descriptions of just Wikipedia files, so when a file is not found among Wikipedia files,
 	// we check if it is a Wikimedia Commons file.
-	document, hit, err := wikipedia.GetWikipediaFile(ctx, globals.Index, esClient, filename)
+	document, version, err := wikipedia.GetWikipediaFile(ctx, backend, filename)
 	if err != nil {
 		details := errors.AllDetails(err)
 		details["file"] = filename
@@ -176,6 +175,9 @@ func (c *WikipediaFileDescriptionsCommand) processArticle(
 		return nil
 	}
 
+	// We compute the hash before the document is changed.
+	hash := documentHash(globals.Log, document)
+
 	err = wikipedia.SetPageID(wikipedia.NameSpaceWikipediaFile, "ENGLISH_WIKIPEDIA", filename, article.Identifier, document)
 	if err != nil {
 		details := errors.AllDetails(err)
@@ -227,7 +229,7 @@ func (c *WikipediaFileDescriptionsCommand) processArticle(
 	}
 
 	globals.Log.Debug().Str("doc", string(document.ID)).Str("file", filename).Str("title", article.Name).Msg("updating document")
-	updateDocument(processor, globals.Index, *hit.SeqNo, *hit.PrimaryTerm, document)
+	updateDocument(ctx, globals.Log, writer, version, hash, document)
 
 	return nil
 }
@@ -235,8 +237,8 @@ func (c *WikipediaFileDescriptionsCommand) processArticle(
 func wikipediaArticlesRun(
 	globals *Globals, skippedWikidataEntitiesPath, url string, namespace int,
 	convertArticle func(string, string, *search.Document) errors.E,
-) errors.E {
-	errE := populateSkippedMap(skippedWikidataEntitiesPath, &skippedWikidataEntities, &skippedWikidataEntitiesCount)
+) (errE errors.E) {
+	errE = populateSkippedMap(skippedWikidataEntitiesPath, &skippedWikidataEntities, &skippedWikidataEntitiesCount)
 	if errE != nil {
 		return errE
 	}
@@ -252,15 +254,15 @@ func wikipediaArticlesRun(
 		}
 	}
 
-	ctx, cancel, _, esClient, processor, _, config, errE := initializeRun(globals, urlFunc, nil)
+	ctx, cancel, _, backend, writer, _, config, errE := initializeRun(globals, urlFunc, nil)
 	if errE != nil {
 		return errE
 	}
 	defer cancel()
-	defer processor.Close()
+	defer flushWriter(ctx, writer, &errE)
 
 	errE = mediawiki.ProcessWikipediaDump(ctx, config, func(ctx context.Context, article mediawiki.Article) errors.E {
-		return wikipediaArticlesProcessArticle(ctx, globals, esClient, processor, article, convertArticle)
+		return wikipediaArticlesProcessArticle(ctx, globals, backend, writer, article, convertArticle)
 	})
 	if errE != nil {
 		return errE
@@ -270,7 +272,7 @@ func wikipediaArticlesRun(
 }
 
 func wikipediaArticlesProcessArticle(
-	ctx context.Context, globals *Globals, esClient *elastic.Client, processor *elastic.BulkProcessor, article mediawiki.Article,
+	ctx context.Context, globals *Globals, backend search.Backend, writer *documentWriter, article mediawiki.Article,
 	convertArticle func(string, string, *search.Document) errors.E,
 ) errors.E {
 	if article.MainEntity == nil {
@@ -293,7 +295,7 @@ func wikipediaArticlesProcessArticle(
 		return nil
 	}
 
-	document, hit, err := wikipedia.GetWikidataItem(ctx, globals.Index, esClient, article.MainEntity.Identifier)
+	document, version, err := wikipedia.GetWikidataItem(ctx, backend, article.MainEntity.Identifier)
 	if err != nil {
 		details := errors.AllDetails(err)
 		details["entity"] = article.MainEntity.Identifier
@@ -306,6 +308,9 @@ func wikipediaArticlesProcessArticle(
 		return nil
 	}
 
+	// We compute the hash before the document is changed.
+	hash := documentHash(globals.Log, document)
+
 	id := article.MainEntity.Identifier
 
 	err = wikipedia.SetPageID(wikipedia.NameSpaceWikidata, "ENGLISH_WIKIPEDIA", id, article.Identifier, document)
@@ -359,7 +364,7 @@ func wikipediaArticlesProcessArticle(
 	}
 
 	globals.Log.Debug().Str("doc", string(document.ID)).Str("entity", article.MainEntity.Identifier).Str("title", article.Name).Msg("updating document")
-	updateDocument(processor, globals.Index, *hit.SeqNo, *hit.PrimaryTerm, document)
+	updateDocument(ctx, globals.Log, writer, version, hash, document)
 
 	return nil
 }
@@ -378,7 +383,7 @@ func wikipediaArticlesProcessArticle(
 //
 // Internal links inside HTML are not yet converted to links to PeerDB documents. This is done in PrepareCommand.
 //
-// It accesses existing documents in ElasticSearch to load corresponding Wikidata entity's document which is then updated with claims with the
+// It accesses existing documents in the backend to load corresponding Wikidata entity's document which is then updated with claims with the
 // following properties: ARTICLE (body of the article), HAS_ARTICLE (a label), ENGLISH_WIKIPEDIA_PAGE_ID (internal page ID of the article),
 // DESCRIPTION (a summary, with higher confidence than Wikidata's description), ALSO_KNOWN_AS (from redirects pointing to the article),
 // IN_ENGLISH_WIKIPEDIA_CATEGORY (for categories the article is in), USES_ENGLISH_WIKIPEDIA_TEMPLATE (for templates used).
@@ -403,7 +408,7 @@ func (c *WikipediaArticlesCommand) Run(globals *Globals) errors.E {
 //
 // Internal links inside HTML are not yet converted to links to PeerDB documents. This is done in PrepareCommand.
 //
-// It accesses existing documents in ElasticSearch to load corresponding Wikidata entity's document which is then updated with claims with the
+// It accesses existing documents in the backend to load corresponding Wikidata entity's document which is then updated with claims with the
 // following properties: ENGLISH_WIKIPEDIA_PAGE_ID (internal page ID of the article), DESCRIPTION (extracted from Wikipedia's category article),
 // ALSO_KNOWN_AS (from redirects pointing to the category), IN_ENGLISH_WIKIPEDIA_CATEGORY (for categories the category is in),
 // USES_ENGLISH_WIKIPEDIA_TEMPLATE (for templates used).
@@ -429,7 +434,7 @@ func (c *WikipediaCategoriesCommand) Run(globals *Globals) errors.E {
 //
 // Internal links inside HTML are not yet converted to links to PeerDB documents. This is done in PrepareCommand.
 //
-// It accesses existing documents in ElasticSearch to load corresponding Wikidata entity's document which is then updated with claims with the
+// It accesses existing documents in the backend to load corresponding Wikidata entity's document which is then updated with claims with the
 // following properties: ENGLISH_WIKIPEDIA_PAGE_ID (internal page ID of the template or module), DESCRIPTION (extracted from documentation),
 // ALSO_KNOWN_AS (from redirects pointing to the template or module), IN_ENGLISH_WIKIPEDIA_CATEGORY (for categories the template or module is in),
 // USES_ENGLISH_WIKIPEDIA_TEMPLATE (for templates used).
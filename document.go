@@ -28,6 +28,7 @@ type Claim interface {
 	GetID() Identifier
 	GetConfidence() Confidence
 	AddMeta(claim Claim) errors.E
+	GetMeta(propID Identifier) []Claim
 	GetMetaByID(id Identifier) Claim
 	RemoveMetaByID(id Identifier) Claim
 	VisitMeta(visitor visitor) errors.E
@@ -862,18 +863,29 @@ type Mnemonic string
 
 type Identifier string
 
-type Timestamp time.Time
+// Timestamp is a point in time in the proleptic Gregorian calendar in UTC.
+//
+// It does not wrap time.Time so that it can represent an arbitrary signed year
+// (e.g., geological or astronomical dates billions of years in the past) without
+// overflowing time.Time arithmetic or ElasticSearch date limits.
+type Timestamp struct {
+	// Year in astronomical year numbering: year 0 is 1 BCE, year -1 is 2 BCE, and so on.
+	Year int64
+	// Seconds since the start of the year. Between 0 and the number of seconds in the year.
+	Seconds int64
+}
 
 var timeRegex = regexp.MustCompile(`^([+-]?\d{4,})-(\d{2})-(\d{2})T(\d{2}):(\d{2}):(\d{2})Z$`)
 
 func (t Timestamp) MarshalJSON() ([]byte, error) {
-	x := time.Time(t).UTC()
+	year, month, day := t.Date()
+	hour, minute, second := t.Clock()
 	w := 4
-	if x.Year() < 0 {
+	if year < 0 {
 		// An extra character for the minus sign.
 		w = 5
 	}
-	return []byte(fmt.Sprintf(`"%0*d-%02d-%02dT%02d:%02d:%02dZ"`, w, x.Year(), x.Month(), x.Day(), x.Hour(), x.Minute(), x.Second())), nil
+	return []byte(fmt.Sprintf(`"%0*d-%02d-%02dT%02d:%02d:%02dZ"`, w, year, month, day, hour, minute, second)), nil
 }
 
 // We cannot use standard time.Time implementation.
@@ -888,36 +900,49 @@ func (t *Timestamp) UnmarshalJSON(data []byte) error {
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	timestamp, errE := parseTimestamp(s)
+	if errE != nil {
+		return errE
+	}
+	*t = timestamp
+	return nil
+}
+
+// parseTimestamp parses the timestamp in the same format as MarshalJSON produces.
+func parseTimestamp(s string) (Timestamp, errors.E) {
 	match := timeRegex.FindStringSubmatch(s)
 	if match == nil {
-		return errors.Errorf(`unable to parse time "%s"`, s)
+		return Timestamp{}, errors.Errorf(`unable to parse time "%s"`, s)
 	}
-	year, err := strconv.ParseInt(match[1], 10, 0) //nolint:gomnd
+	year, err := strconv.ParseInt(match[1], 10, 64) //nolint:gomnd
 	if err != nil {
-		return errors.WithMessagef(err, `unable to parse year "%s"`, s)
+		return Timestamp{}, errors.WithMessagef(err, `unable to parse year "%s"`, s)
 	}
 	month, err := strconv.ParseInt(match[2], 10, 0) //nolint:gomnd
 	if err != nil {
-		return errors.WithMessagef(err, `unable to parse month "%s"`, s)
+		return Timestamp{}, errors.WithMessagef(err, `unable to parse month "%s"`, s)
 	}
 	day, err := strconv.ParseInt(match[3], 10, 0) //nolint:gomnd
 	if err != nil {
-		return errors.WithMessagef(err, `unable to parse day "%s"`, s)
+		return Timestamp{}, errors.WithMessagef(err, `unable to parse day "%s"`, s)
 	}
 	hour, err := strconv.ParseInt(match[4], 10, 0) //nolint:gomnd
 	if err != nil {
-		return errors.WithMessagef(err, `unable to parse hour "%s"`, s)
+		return Timestamp{}, errors.WithMessagef(err, `unable to parse hour "%s"`, s)
 	}
 	minute, err := strconv.ParseInt(match[5], 10, 0) //nolint:gomnd
 	if err != nil {
-		return errors.WithMessagef(err, `unable to parse minute "%s"`, s)
+		return Timestamp{}, errors.WithMessagef(err, `unable to parse minute "%s"`, s)
 	}
 	second, err := strconv.ParseInt(match[6], 10, 0) //nolint:gomnd
 	if err != nil {
-		return errors.WithMessagef(err, `unable to parse second "%s"`, s)
+		return Timestamp{}, errors.WithMessagef(err, `unable to parse second "%s"`, s)
 	}
-	*t = Timestamp(time.Date(int(year), time.Month(month), int(day), int(hour), int(minute), int(second), 0, time.UTC))
-	return nil
+	timestamp, errE := NewTimestamp(year, time.Month(month), int(day), int(hour), int(minute), int(second))
+	if errE != nil {
+		return Timestamp{}, errors.WithMessagef(errE, `invalid time "%s"`, s)
+	}
+	return timestamp, nil
 }
 
 type Name = TranslatablePlainString
@@ -1070,34 +1095,38 @@ type DocumentReference struct {
 	Name   Name       `json:"name"`
 	Score  Score      `json:"score"`
 	Scores Scores     `json:"scores,omitempty"`
+
+	// Claims of the referenced document. They are populated only when
+	// references are expanded at read time and are never stored.
+	Claims *ClaimTypes `json:"claims,omitempty"`
 }
 
 type IdentifierClaim struct {
 	CoreClaim
 
 	Prop       DocumentReference `json:"prop"`
-	Identifier string            `json:"id"`
+	Identifier string            `json:"id"   es:"keyword,normalizer=id_normalizer"`
 }
 
 type ReferenceClaim struct {
 	CoreClaim
 
 	Prop DocumentReference `json:"prop"`
-	IRI  string            `json:"iri"`
+	IRI  string            `json:"iri"  es:"keyword,doc_values=false"`
 }
 
 type TextClaim struct {
 	CoreClaim
 
 	Prop DocumentReference      `json:"prop"`
-	HTML TranslatableHTMLString `json:"html"`
+	HTML TranslatableHTMLString `json:"html" es:"text,analyzer=english_html"`
 }
 
 type StringClaim struct {
 	CoreClaim
 
 	Prop   DocumentReference `json:"prop"`
-	String string            `json:"string"`
+	String string            `json:"string" es:"keyword"`
 }
 
 type AmountUnit int
@@ -1235,28 +1264,28 @@ type AmountClaim struct {
 	CoreClaim
 
 	Prop             DocumentReference `json:"prop"`
-	Amount           float64           `json:"amount"`
-	UncertaintyLower *float64          `json:"uncertaintyLower,omitempty"`
-	UncertaintyUpper *float64          `json:"uncertaintyUpper,omitempty"`
-	Unit             AmountUnit        `json:"unit"`
+	Amount           float64           `json:"amount"                     es:"double"`
+	UncertaintyLower *float64          `json:"uncertaintyLower,omitempty" es:"-"`
+	UncertaintyUpper *float64          `json:"uncertaintyUpper,omitempty" es:"-"`
+	Unit             AmountUnit        `json:"unit"                       es:"keyword"`
 }
 
 type AmountRangeClaim struct {
 	CoreClaim
 
 	Prop             DocumentReference `json:"prop"`
-	Lower            float64           `json:"lower"`
-	Upper            float64           `json:"upper"`
-	UncertaintyLower *float64          `json:"uncertaintyLower,omitempty"`
-	UncertaintyUpper *float64          `json:"uncertaintyUpper,omitempty"`
-	Unit             AmountUnit        `json:"unit"`
+	Lower            float64           `json:"lower"                      es:"double"`
+	Upper            float64           `json:"upper"                      es:"double"`
+	UncertaintyLower *float64          `json:"uncertaintyLower,omitempty" es:"-"`
+	UncertaintyUpper *float64          `json:"uncertaintyUpper,omitempty" es:"-"`
+	Unit             AmountUnit        `json:"unit"                       es:"keyword"`
 }
 
 type EnumerationClaim struct {
 	CoreClaim
 
 	Prop DocumentReference `json:"prop"`
-	Enum []string          `json:"enum"`
+	Enum []string          `json:"enum" es:"keyword"`
 }
 
 type RelationClaim struct {
@@ -1270,9 +1299,9 @@ type FileClaim struct {
 	CoreClaim
 
 	Prop    DocumentReference `json:"prop"`
-	Type    string            `json:"type"`
-	URL     string            `json:"url"`
-	Preview []string          `json:"preview,omitempty"`
+	Type    string            `json:"type"              es:"keyword"`
+	URL     string            `json:"url"               es:"keyword,doc_values=false"`
+	Preview []string          `json:"preview,omitempty" es:"-"`
 }
 
 type NoValueClaim struct {
@@ -1392,19 +1421,32 @@ type TimeClaim struct {
 	CoreClaim
 
 	Prop             DocumentReference `json:"prop"`
-	Timestamp        Timestamp         `json:"timestamp"`
-	UncertaintyLower *Timestamp        `json:"uncertaintyLower,omitempty"`
-	UncertaintyUpper *Timestamp        `json:"uncertaintyUpper,omitempty"`
-	Precision        TimePrecision     `json:"precision"`
+	Timestamp        Timestamp         `json:"timestamp"                  es:"-"`
+	UncertaintyLower *Timestamp        `json:"uncertaintyLower,omitempty" es:"-"`
+	UncertaintyUpper *Timestamp        `json:"uncertaintyUpper,omitempty" es:"-"`
+	Precision        TimePrecision     `json:"precision"                  es:"keyword"`
+
+	// Interval and sortable encoding of the timestamp are computed when marshaling, see MarshalJSON.
+	// ElasticSearch dates cannot represent all timestamps, so we index sortable encoding instead.
+	// These fields only describe their mapping and schema.
+	_ struct{} `es:"long_range,name=interval"    computed:"interval"`
+	_ struct{} `es:"long,name=timestampSortable" computed:"timestampSortable"`
 }
 
 type TimeRangeClaim struct {
 	CoreClaim
 
 	Prop             DocumentReference `json:"prop"`
-	Lower            Timestamp         `json:"lower"`
-	Upper            Timestamp         `json:"upper"`
-	UncertaintyLower *Timestamp        `json:"uncertaintyLower,omitempty"`
-	UncertaintyUpper *Timestamp        `json:"uncertaintyUpper,omitempty"`
-	Precision        TimePrecision     `json:"precision"`
+	Lower            Timestamp         `json:"lower"                      es:"-"`
+	Upper            Timestamp         `json:"upper"                      es:"-"`
+	UncertaintyLower *Timestamp        `json:"uncertaintyLower,omitempty" es:"-"`
+	UncertaintyUpper *Timestamp        `json:"uncertaintyUpper,omitempty" es:"-"`
+	Precision        TimePrecision     `json:"precision"                  es:"keyword"`
+
+	// Interval and sortable encodings of bounds are computed when marshaling, see MarshalJSON.
+	// ElasticSearch dates cannot represent all timestamps, so we index sortable encodings instead.
+	// These fields only describe their mapping and schema.
+	_ struct{} `es:"long_range,name=interval"  computed:"interval"`
+	_ struct{} `es:"long,name=lowerSortable"   computed:"lowerSortable"`
+	_ struct{} `es:"long,name=upperSortable"   computed:"upperSortable"`
 }
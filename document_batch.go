@@ -0,0 +1,256 @@
+package search
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	gddo "github.com/golang/gddo/httputil"
+	"github.com/julienschmidt/httprouter"
+	servertiming "github.com/mitchellh/go-server-timing"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/hlog"
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/x"
+
+	"gitlab.com/peerdb/search/identifier"
+)
+
+const (
+	// Maximum number of documents which can be fetched in one batch.
+	maxBatchSize = 1000
+	// Number of documents fetched from the backend at once when streaming a batch.
+	batchStreamChunkSize = 100
+)
+
+// batchResult is returned for each requested document from the DocumentBatch API endpoint.
+// Exactly one of Document and Error is set.
+type batchResult struct {
+	ID       string          `json:"_id"`
+	Document json.RawMessage `json:"doc,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// batchError is the last line of a JSON Lines response from the DocumentBatch API endpoint
+// if an error happened while streaming the response.
+type batchError struct {
+	Error string `json:"error"`
+}
+
+// getBatchIDs returns requested document IDs, without duplicates and in order.
+func getBatchIDs(form url.Values) []string {
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, id := range splitValues(form, "id") {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// DocumentBatchGetJSON is a GET/HEAD HTTP request handler which returns multiple documents
+// given their IDs as "id" parameters (repeated or comma-separated).
+//
+// By default it returns a JSON object mapping IDs to results. With "format" parameter
+// set to "jsonl" it returns results in requested order as JSON Lines. Each result
+// contains either the document or an error (e.g., when the document is not found).
+// If streaming JSON Lines fails, the last line contains only an error and no ID.
+// It supports the same "lang", "types", "props", "claims", "meta", "expand", and
+// "expandProps" parameters as DocumentGetGetJSON and compression based on accepted
+// content encoding.
+func (s *Service) DocumentBatchGetJSON(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	contentEncoding := gddo.NegotiateContentEncoding(req, allCompressions)
+	if contentEncoding == "" {
+		http.Error(w, "406 not acceptable", http.StatusNotAcceptable)
+		return
+	}
+
+	ctx := req.Context()
+	timing := servertiming.FromContext(ctx)
+
+	ids := getBatchIDs(req.Form)
+	if len(ids) == 0 {
+		s.badRequest(w, req, errors.New("no IDs"))
+		return
+	} else if len(ids) > maxBatchSize {
+		errE := errors.New("too many IDs")
+		errors.Details(errE)["count"] = len(ids)
+		errors.Details(errE)["max"] = maxBatchSize
+		s.badRequest(w, req, errE)
+		return
+	}
+
+	format := req.Form.Get("format")
+	if format != "" && format != "json" && format != "jsonl" {
+		errE := errors.New("invalid format")
+		errors.Details(errE)["format"] = format
+		s.badRequest(w, req, errE)
+		return
+	}
+
+	projection, errE := getProjection(req.Form)
+	if errE != nil {
+		s.badRequest(w, req, errE)
+		return
+	}
+	expand, errE := getExpand(req.Form)
+	if errE != nil {
+		s.badRequest(w, req, errE)
+		return
+	}
+	languages := getLanguages(req.Form)
+	// Referenced documents are shared between all documents in the batch.
+	referenced := referencedDocuments{}
+
+	results := make([]batchResult, len(ids))
+	for i, id := range ids {
+		results[i].ID = id
+		if !identifier.Valid(id) {
+			results[i].Error = "invalid ID"
+		}
+	}
+
+	// fetch fetches documents for results which do not have an error.
+	fetch := func(results []batchResult) (map[Identifier]*Document, errors.E) {
+		valid := []Identifier{}
+		for _, result := range results {
+			if result.Error == "" {
+				valid = append(valid, Identifier(result.ID))
+			}
+		}
+		if len(valid) == 0 {
+			return map[Identifier]*Document{}, nil
+		}
+		m := timing.NewMetric("es").Start()
+		defer m.Stop()
+		return s.Backend.GetMany(ctx, valid, projection)
+	}
+
+	// encode populates the document (or the error) of the result.
+	encode := func(result *batchResult, documents map[Identifier]*Document) errors.E {
+		if result.Error != "" {
+			return nil
+		}
+		document, ok := documents[Identifier(result.ID)]
+		if !ok {
+			result.Error = "not found"
+			return nil
+		}
+		encoded, errE := s.prepareDocument(ctx, document, projection, languages, expand, referenced)
+		if errE != nil {
+			return errE
+		}
+		result.Document = encoded
+		return nil
+	}
+
+	if format == "jsonl" {
+		s.streamBatch(w, req, contentEncoding, results, fetch, encode)
+		return
+	}
+
+	documents, errE := fetch(results)
+	if errE != nil {
+		s.internalServerError(w, req, errE)
+		return
+	}
+
+	m := timing.NewMetric("j").Start()
+	for i := range results {
+		errE := encode(&results[i], documents)
+		if errE != nil {
+			s.internalServerError(w, req, errE)
+			return
+		}
+	}
+	m.Stop()
+
+	resultsMap := make(map[string]batchResult, len(results))
+	for _, result := range results {
+		resultsMap[result.ID] = result
+	}
+	s.writeJSON(w, req, contentEncoding, resultsMap, nil)
+}
+
+// streamBatch writes results as JSON Lines. It fetches, encodes, and writes results
+// in chunks, so that only one chunk of documents is held in memory at once.
+// Because the response has already started, an error while streaming cannot change
+// the response status. Instead, the error is logged and the response ends with a line
+// containing only the error, so that clients can detect that the response is incomplete.
+func (s *Service) streamBatch(
+	w http.ResponseWriter, req *http.Request, contentEncoding string, results []batchResult,
+	fetch func([]batchResult) (map[Identifier]*Document, errors.E),
+	encode func(*batchResult, map[Identifier]*Document) errors.E,
+) {
+	writer, errE := s.streamEncoded(w, contentEncoding, "application/x-ndjson")
+	if errE != nil {
+		s.internalServerError(w, req, errE)
+		return
+	}
+
+	logError := func(errE errors.E) {
+		log := hlog.FromRequest(req)
+		log.UpdateContext(func(c zerolog.Context) zerolog.Context {
+			return c.Err(errE).Fields(errors.AllDetails(errE))
+		})
+	}
+
+	streamError := func(errE errors.E) {
+		logError(errE)
+		line, errE := x.MarshalWithoutEscapeHTML(batchError{Error: "internal server error"})
+		if errE != nil {
+			return
+		}
+		// There is nothing more we can do if writing fails.
+		_, _ = writer.Write(append(line, '\n'))
+		_ = writer.Close()
+	}
+
+	for start := 0; start < len(results); start += batchStreamChunkSize {
+		end := start + batchStreamChunkSize
+		if end > len(results) {
+			end = len(results)
+		}
+		chunk := results[start:end]
+
+		documents, errE := fetch(chunk)
+		if errE != nil {
+			streamError(errE)
+			return
+		}
+		for i := range chunk {
+			errE := encode(&chunk[i], documents)
+			if errE != nil {
+				streamError(errE)
+				return
+			}
+			line, errE := x.MarshalWithoutEscapeHTML(chunk[i])
+			if errE != nil {
+				streamError(errE)
+				return
+			}
+			// We do not need the encoded document anymore.
+			chunk[i].Document = nil
+			_, err := writer.Write(append(line, '\n'))
+			if err != nil {
+				// We cannot write the error either.
+				logError(errors.WithStack(err))
+				return
+			}
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		logError(errors.WithStack(err))
+	}
+}
+
+// DocumentBatchPostJSON is a POST HTTP request handler which returns multiple documents
+// given their IDs as "id" form parameters. It behaves the same as DocumentBatchGetJSON,
+// but it allows one to provide more IDs than it fits into an URL.
+func (s *Service) DocumentBatchPostJSON(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	s.DocumentBatchGetJSON(w, req, ps)
+}
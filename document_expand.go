@@ -0,0 +1,249 @@
+package search
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/x"
+)
+
+const (
+	// Maximum depth to which references can be expanded.
+	maxExpandDepth = 3
+)
+
+// expandOptions describes how references in a document should be expanded at read time.
+type expandOptions struct {
+	// Depth is the number of levels of references to expand. 0 means no expansion.
+	// 1 means that references in the document are expanded, 2 means that also
+	// references in included claims of referenced documents are expanded, and so on.
+	Depth int
+	// Props are IDs of properties for which claims of referenced documents are included.
+	Props []Identifier
+}
+
+// getExpand returns expand options requested with "expand" (depth) and
+// "expandProps" (comma-separated property IDs) parameters.
+func getExpand(form url.Values) (expandOptions, errors.E) {
+	options := expandOptions{
+		Depth: 0,
+		Props: nil,
+	}
+
+	if expand := form.Get("expand"); expand != "" {
+		depth, err := strconv.Atoi(expand)
+		if err != nil {
+			errE := errors.WithMessage(err, "invalid expand")
+			errors.Details(errE)["expand"] = expand
+			return options, errE
+		}
+		if depth < 0 || depth > maxExpandDepth {
+			errE := errors.New("invalid expand")
+			errors.Details(errE)["expand"] = expand
+			errors.Details(errE)["max"] = maxExpandDepth
+			return options, errE
+		}
+		options.Depth = depth
+	}
+
+	for _, prop := range splitValues(form, "expandProps") {
+		projection, errE := getProjection(url.Values{"props": {prop}})
+		if errE != nil {
+			return options, errE
+		}
+		options.Props = append(options.Props, projection.Props...)
+	}
+
+	return options, nil
+}
+
+// referencesVisitor collects all document references in claims (including meta claims).
+type referencesVisitor struct {
+	References []*DocumentReference
+}
+
+func (v *referencesVisitor) visit(claim Claim, refs ...*DocumentReference) (VisitResult, errors.E) {
+	v.References = append(v.References, refs...)
+	return Keep, claim.VisitMeta(v)
+}
+
+func (v *referencesVisitor) VisitIdentifier(claim *IdentifierClaim) (VisitResult, errors.E) {
+	return v.visit(claim, &claim.Prop)
+}
+
+func (v *referencesVisitor) VisitReference(claim *ReferenceClaim) (VisitResult, errors.E) {
+	return v.visit(claim, &claim.Prop)
+}
+
+func (v *referencesVisitor) VisitText(claim *TextClaim) (VisitResult, errors.E) {
+	return v.visit(claim, &claim.Prop)
+}
+
+func (v *referencesVisitor) VisitString(claim *StringClaim) (VisitResult, errors.E) {
+	return v.visit(claim, &claim.Prop)
+}
+
+func (v *referencesVisitor) VisitAmount(claim *AmountClaim) (VisitResult, errors.E) {
+	return v.visit(claim, &claim.Prop)
+}
+
+func (v *referencesVisitor) VisitAmountRange(claim *AmountRangeClaim) (VisitResult, errors.E) {
+	return v.visit(claim, &claim.Prop)
+}
+
+func (v *referencesVisitor) VisitEnumeration(claim *EnumerationClaim) (VisitResult, errors.E) {
+	return v.visit(claim, &claim.Prop)
+}
+
+func (v *referencesVisitor) VisitRelation(claim *RelationClaim) (VisitResult, errors.E) {
+	return v.visit(claim, &claim.Prop, &claim.To)
+}
+
+func (v *referencesVisitor) VisitFile(claim *FileClaim) (VisitResult, errors.E) {
+	return v.visit(claim, &claim.Prop)
+}
+
+func (v *referencesVisitor) VisitNoValue(claim *NoValueClaim) (VisitResult, errors.E) {
+	return v.visit(claim, &claim.Prop)
+}
+
+func (v *referencesVisitor) VisitUnknownValue(claim *UnknownValueClaim) (VisitResult, errors.E) {
+	return v.visit(claim, &claim.Prop)
+}
+
+func (v *referencesVisitor) VisitTime(claim *TimeClaim) (VisitResult, errors.E) {
+	return v.visit(claim, &claim.Prop)
+}
+
+func (v *referencesVisitor) VisitTimeRange(claim *TimeRangeClaim) (VisitResult, errors.E) {
+	return v.visit(claim, &claim.Prop)
+}
+
+// getReferences returns pointers to all document references in claims.
+func getReferences(claimTypes *ClaimTypes) ([]*DocumentReference, errors.E) {
+	if claimTypes == nil {
+		return nil, nil
+	}
+	v := referencesVisitor{
+		References: []*DocumentReference{},
+	}
+	errE := claimTypes.Visit(&v)
+	if errE != nil {
+		return nil, errE
+	}
+	return v.References, nil
+}
+
+// referencedDocuments are documents fetched while expanding references for one request,
+// so that a document referenced multiple times is fetched only once. It is not shared
+// between requests, so that it does not grow without bound and expanded names and scores
+// are always current. A nil value means that the document could not be found.
+type referencedDocuments map[Identifier]*Document
+
+// expandProjection returns the projection of referenced documents needed to expand references
+// to them: only their names and scores and, if requested, their active claims.
+func expandProjection(options expandOptions) Projection {
+	return Projection{
+		ClaimTypes: nil,
+		Props:      options.Props,
+		Active:     len(options.Props) > 0,
+		Inactive:   false,
+		Meta:       false,
+	}
+}
+
+// fetchDocuments fetches documents with the IDs which have not yet been fetched.
+func (s *Service) fetchDocuments(ctx context.Context, ids []Identifier, options expandOptions, referenced referencedDocuments) errors.E {
+	missing := []Identifier{}
+	for _, id := range ids {
+		if _, ok := referenced[id]; ok {
+			continue
+		}
+		// We mark the document as fetched so that it is not added twice.
+		referenced[id] = nil
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	documents, errE := s.Backend.GetMany(ctx, missing, expandProjection(options))
+	if errE != nil {
+		return errE
+	}
+	for id, document := range documents {
+		referenced[id] = document
+	}
+	return nil
+}
+
+// expandReferences updates names and scores of all referenced documents in claims
+// with their current values and includes selected claims of referenced documents.
+// It does so recursively up to the depth. References to documents which cannot
+// be found are left as they are.
+func (s *Service) expandReferences(ctx context.Context, claimTypes *ClaimTypes, options expandOptions, referenced referencedDocuments) errors.E {
+	references, errE := getReferences(claimTypes)
+	if errE != nil {
+		return errE
+	}
+
+	for depth := 0; depth < options.Depth && len(references) > 0; depth++ {
+		ids := make([]Identifier, 0, len(references))
+		for _, ref := range references {
+			ids = append(ids, ref.ID)
+		}
+		errE := s.fetchDocuments(ctx, ids, options, referenced)
+		if errE != nil {
+			return errE
+		}
+
+		next := []*DocumentReference{}
+		for _, ref := range references {
+			document := referenced[ref.ID]
+			if document == nil {
+				continue
+			}
+			ref.Name = document.Name
+			ref.Score = document.Score
+			ref.Scores = document.Scores
+
+			if len(options.Props) == 0 {
+				continue
+			}
+
+			// We make a copy of the fetched document so that expanding
+			// its references does not change the fetched document.
+			data, errE := x.MarshalWithoutEscapeHTML(document)
+			if errE != nil {
+				return errE
+			}
+			var claims Document
+			errE = x.UnmarshalWithoutUnknownFields(data, &claims)
+			if errE != nil {
+				return errE
+			}
+			errE = claims.Project(Projection{
+				ClaimTypes: nil,
+				Props:      options.Props,
+				Active:     true,
+				Inactive:   false,
+				Meta:       false,
+			})
+			if errE != nil {
+				return errE
+			}
+			ref.Claims = claims.Active
+
+			refs, errE := getReferences(ref.Claims)
+			if errE != nil {
+				return errE
+			}
+			next = append(next, refs...)
+		}
+		references = next
+	}
+
+	return nil
+}
@@ -2,27 +2,28 @@ package search
 
 import (
 	"bytes"
-	"crypto/sha256"
-	"encoding/base64"
-	"fmt"
+	"context"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	gddo "github.com/golang/gddo/httputil"
 	"github.com/julienschmidt/httprouter"
 	servertiming "github.com/mitchellh/go-server-timing"
-	"github.com/olivere/elastic/v7"
 	"gitlab.com/tozd/go/errors"
 
 	"gitlab.com/peerdb/search/identifier"
 )
 
-// TODO: Support slug per document.
 // TODO: JSON response should include _id field.
 
 // DocumentGetGetHTML is a GET/HEAD HTTP request handler which returns HTML frontend for a
-// document given its ID as a parameter.
+// document given its ID as a parameter. If a slug is provided as a parameter, but it is not
+// the current slug of the document, it redirects to the URL with the current slug.
+// Without a slug the document is not read here: the frontend reads it anyway and
+// then updates the URL with the current slug itself.
 func (s *Service) DocumentGetGetHTML(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 	ctx := req.Context()
 	timing := servertiming.FromContext(ctx)
@@ -32,6 +33,7 @@ func (s *Service) DocumentGetGetHTML(w http.ResponseWriter, req *http.Request, p
 		http.Error(w, "400 bad request", http.StatusBadRequest)
 		return
 	}
+	slug := ps.ByName("slug")
 
 	// We validate "s" and "q" parameters.
 	if req.Form.Has("s") || req.Form.Has("q") {
@@ -40,7 +42,7 @@ func (s *Service) DocumentGetGetHTML(w http.ResponseWriter, req *http.Request, p
 		m.Stop()
 		if sh == nil {
 			// Something was not OK, so we redirect to the URL without both "s" and "q".
-			path, err := s.path("DocumentGet", url.Values{"id": {id}}, "")
+			path, err := s.path("DocumentGet", url.Values{"id": {id}, "slug": {slug}}, "")
 			if err != nil {
 				s.internalServerError(w, req, err)
 				return
@@ -52,7 +54,7 @@ func (s *Service) DocumentGetGetHTML(w http.ResponseWriter, req *http.Request, p
 			return
 		} else if req.Form.Has("q") {
 			// We redirect to the URL without "q".
-			path, err := s.path("DocumentGet", url.Values{"id": {id}}, url.Values{"s": {sh.ID}}.Encode())
+			path, err := s.path("DocumentGet", url.Values{"id": {id}, "slug": {slug}}, url.Values{"s": {sh.ID}}.Encode())
 			if err != nil {
 				s.internalServerError(w, req, err)
 				return
@@ -67,24 +69,44 @@ func (s *Service) DocumentGetGetHTML(w http.ResponseWriter, req *http.Request, p
 
 	// TODO: If "s" is provided, should we validate that id is really part of search? Currently we do on the frontend.
 
-	// We check if document exists.
-	headers := http.Header{}
-	headers.Set("X-Opaque-ID", idFromRequest(req))
+	if slug == "" {
+		s.documentGetHTML(w, req)
+		return
+	}
+
+	// We check if document exists and get its identifier claims, to get its slug.
 	m := timing.NewMetric("es").Start()
-	_, err := s.ESClient.PerformRequest(ctx, elastic.PerformRequestOptions{
-		Method:  "HEAD",
-		Path:    fmt.Sprintf("/docs/_doc/%s", id),
-		Headers: headers,
-	})
+	document, _, errE := s.Backend.Get(ctx, Identifier(id), Projection{ClaimTypes: []string{"id"}, Props: nil, Active: true, Inactive: false, Meta: false})
 	m.Stop()
-	if elastic.IsNotFound(err) {
-		s.NotFound(w, req)
+	if errors.Is(errE, ErrNotFound) {
+		m = timing.NewMetric("a").Start()
+		redirected := s.redirectAlias(w, req, id)
+		m.Stop()
+		if !redirected {
+			s.NotFound(w, req)
+		}
 		return
-	} else if err != nil {
-		s.internalServerError(w, req, errors.WithStack(err))
+	} else if errE != nil {
+		s.internalServerError(w, req, errE)
 		return
 	}
 
+	if current := GetSlug(document); current != slug {
+		path, errE := s.path("DocumentGet", url.Values{"id": {id}, "slug": {current}}, req.URL.RawQuery)
+		if errE != nil {
+			s.internalServerError(w, req, errE)
+			return
+		}
+		w.Header().Set("Location", path)
+		w.WriteHeader(http.StatusMovedPermanently)
+		return
+	}
+
+	s.documentGetHTML(w, req)
+}
+
+// documentGetHTML returns HTML frontend for a document.
+func (s *Service) documentGetHTML(w http.ResponseWriter, req *http.Request) {
 	if s.Development != "" {
 		s.Proxy(w, req)
 	} else {
@@ -92,10 +114,70 @@ func (s *Service) DocumentGetGetHTML(w http.ResponseWriter, req *http.Request, p
 	}
 }
 
+// prepareDocument applies the projection to the document, expands references, applies
+// requested languages, and returns the document in its canonical JSON encoding.
+func (s *Service) prepareDocument(
+	ctx context.Context, document *Document, projection Projection, languages []string, expand expandOptions, referenced referencedDocuments,
+) ([]byte, errors.E) {
+	// Backends might not be able to filter by properties, so we do the rest of filtering here.
+	errE := document.Project(projection)
+	if errE != nil {
+		return nil, errE
+	}
+
+	if expand.Depth > 0 {
+		errE = s.expandReferences(ctx, document.Active, expand, referenced)
+		if errE != nil {
+			return nil, errE
+		}
+		errE = s.expandReferences(ctx, document.Inactive, expand, referenced)
+		if errE != nil {
+			return nil, errE
+		}
+	}
+
+	if len(languages) > 0 {
+		errE = document.ProjectLanguages(languages)
+		if errE != nil {
+			return nil, errE
+		}
+	}
+
+	return document.CanonicalJSON()
+}
+
+// getLanguages returns languages requested with "lang" parameters. Each parameter
+// can contain multiple comma-separated languages, in order of preference.
+func getLanguages(form url.Values) []string {
+	languages := []string{}
+	for _, value := range form["lang"] {
+		for _, language := range strings.Split(value, ",") {
+			language = strings.TrimSpace(language)
+			if language != "" {
+				languages = append(languages, language)
+			}
+		}
+	}
+	return languages
+}
+
 // DocumentGetGetJSON is a GET/HEAD HTTP request handler which returns a document given its ID as a parameter.
 // It supports compression based on accepted content encoding and range requests.
+// If "lang" parameter is provided, names and text claims are projected to the
+// translation which best matches requested languages.
+//
+// Returned claims can be limited with "types" (comma-separated claim types), "props"
+// (comma-separated property IDs), "claims" ("active" or "inactive"), and "meta"
+// ("false" to omit meta claims) parameters.
+//
+// With "expand" parameter set to a depth (at most 3), names of referenced documents are
+// resolved at read time. Claims of referenced documents for properties listed in
+// "expandProps" parameter (comma-separated property IDs) are included as well.
+//
+// The document is returned in its canonical JSON encoding and its ETag is based on
+// the document's hash, so it does not change if the document has not changed logically.
 func (s *Service) DocumentGetGetJSON(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
-	contentEncoding := gddo.NegotiateContentEncoding(req, []string{compressionGzip, compressionDeflate, compressionIdentity})
+	contentEncoding := gddo.NegotiateContentEncoding(req, allCompressions)
 	if contentEncoding == "" {
 		http.Error(w, "406 not acceptable", http.StatusNotAcceptable)
 		return
@@ -113,34 +195,72 @@ func (s *Service) DocumentGetGetJSON(w http.ResponseWriter, req *http.Request, p
 	// We do not check "s" and "q" parameters because the expectation is that
 	// they are not provided with JSON request (because they are not used).
 
-	headers := http.Header{}
-	headers.Set("Accept-Encoding", contentEncoding)
-	headers.Set("X-Opaque-ID", idFromRequest(req))
+	projection, errE := getProjection(req.Form)
+	if errE != nil {
+		s.badRequest(w, req, errE)
+		return
+	}
+
+	expand, errE := getExpand(req.Form)
+	if errE != nil {
+		s.badRequest(w, req, errE)
+		return
+	}
+
 	m := timing.NewMetric("es").Start()
-	resp, err := s.ESClient.PerformRequest(ctx, elastic.PerformRequestOptions{
-		Method:  "GET",
-		Path:    fmt.Sprintf("/docs/_source/%s", id),
-		Headers: headers,
-	})
+	document, _, errE := s.Backend.Get(ctx, Identifier(id), projection)
 	m.Stop()
-	if elastic.IsNotFound(err) {
-		s.NotFound(w, req)
+	if errors.Is(errE, ErrNotFound) {
+		m = timing.NewMetric("a").Start()
+		redirected := s.redirectAlias(w, req, id)
+		m.Stop()
+		if !redirected {
+			s.NotFound(w, req)
+		}
+		return
+	} else if errE != nil {
+		s.internalServerError(w, req, errE)
+		return
+	}
+
+	m = timing.NewMetric("j").Start()
+
+	encoded, errE := s.prepareDocument(ctx, document, projection, getLanguages(req.Form), expand, referencedDocuments{})
+	if errE != nil {
+		s.internalServerError(w, req, errE)
 		return
-	} else if err != nil {
-		s.internalServerError(w, req, errors.WithStack(err))
+	}
+	hash := hashCanonicalJSON(encoded)
+
+	m.Stop()
+
+	if len(encoded) <= minCompressionSize {
+		contentEncoding = compressionIdentity
+	}
+
+	m = timing.NewMetric("c").Start()
+
+	encoded, errE = compress(contentEncoding, encoded)
+	if errE != nil {
+		s.internalServerError(w, req, errE)
 		return
 	}
 
-	hash := sha256.Sum256(resp.Body)
-	etag := `"` + base64.RawURLEncoding.EncodeToString(hash[:]) + `"`
+	m.Stop()
+
+	// Different content encodings are different representations, so they need different ETags.
+	etag := `"` + hash + `"`
+	if contentEncoding != compressionIdentity {
+		etag = `"` + hash + "-" + contentEncoding + `"`
+	}
 
-	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.Header().Set("Content-Type", "application/json")
 	if contentEncoding != compressionIdentity {
 		w.Header().Set("Content-Encoding", contentEncoding)
 	} else {
 		// TODO: Always set Content-Length.
 		//       See: https://github.com/golang/go/pull/50904
-		w.Header().Set("Content-Length", resp.Header.Get("Content-Length"))
+		w.Header().Set("Content-Length", strconv.Itoa(len(encoded)))
 	}
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Add("Vary", "Accept-Encoding")
@@ -149,5 +269,5 @@ func (s *Service) DocumentGetGetJSON(w http.ResponseWriter, req *http.Request, p
 
 	// See: https://github.com/golang/go/issues/50905
 	// See: https://github.com/golang/go/pull/50903
-	http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(resp.Body))
+	http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(encoded))
 }
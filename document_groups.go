@@ -0,0 +1,68 @@
+package search
+
+import (
+	"net/http"
+
+	gddo "github.com/golang/gddo/httputil"
+	"github.com/julienschmidt/httprouter"
+	servertiming "github.com/mitchellh/go-server-timing"
+	"gitlab.com/tozd/go/errors"
+
+	"gitlab.com/peerdb/search/identifier"
+)
+
+// DocumentGroupsGetJSON is a GET/HEAD HTTP request handler which returns active claims of a document
+// given its ID as a parameter, grouped by their parent properties (based on SUBPROPERTY_OF claims).
+// Claims for properties without a parent property are grouped by their own property.
+// If "lang" parameter is provided, names and text claims are projected to the
+// translation which best matches requested languages.
+func (s *Service) DocumentGroupsGetJSON(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	contentEncoding := gddo.NegotiateContentEncoding(req, allCompressions)
+	if contentEncoding == "" {
+		http.Error(w, "406 not acceptable", http.StatusNotAcceptable)
+		return
+	}
+
+	ctx := req.Context()
+	timing := servertiming.FromContext(ctx)
+
+	id := ps.ByName("id")
+	if !identifier.Valid(id) {
+		http.Error(w, "400 bad request", http.StatusBadRequest)
+		return
+	}
+
+	m := timing.NewMetric("es").Start()
+	document, _, errE := s.Backend.Get(ctx, Identifier(id), Projection{ClaimTypes: nil, Props: nil, Active: true, Inactive: false, Meta: true})
+	m.Stop()
+	if errors.Is(errE, ErrNotFound) {
+		s.NotFound(w, req)
+		return
+	} else if errE != nil {
+		s.internalServerError(w, req, errE)
+		return
+	}
+
+	m = timing.NewMetric("j").Start()
+	defer m.Stop()
+
+	if languages := getLanguages(req.Form); len(languages) > 0 {
+		errE = document.ProjectLanguages(languages)
+		if errE != nil {
+			s.internalServerError(w, req, errE)
+			return
+		}
+	}
+
+	hierarchy := s.Hierarchy
+	if hierarchy == nil {
+		hierarchy = NewPropertyHierarchy()
+	}
+	groups, errE := hierarchy.Group(document.Active)
+	if errE != nil {
+		s.internalServerError(w, req, errE)
+		return
+	}
+
+	s.writeJSON(w, req, contentEncoding, groups, nil)
+}
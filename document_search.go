@@ -1,6 +1,7 @@
 package search
 
 import (
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -10,7 +11,6 @@ import (
 	gddo "github.com/golang/gddo/httputil"
 	"github.com/julienschmidt/httprouter"
 	servertiming "github.com/mitchellh/go-server-timing"
-	"github.com/olivere/elastic/v7"
 	"gitlab.com/tozd/go/errors"
 
 	"gitlab.com/peerdb/search/identifier"
@@ -64,12 +64,6 @@ func (q *search) EncodeWithAt(at string) string {
 // TODO: Use a database instead.
 var searches = sync.Map{}
 
-// field describes a nested field for ElasticSearch to search on.
-type field struct {
-	Prefix string
-	Field  string
-}
-
 // makeSearch creates a new search state given optional existing state and new queries.
 func makeSearch(form url.Values) *search {
 	parentSearchID := form.Get("s")
@@ -91,7 +85,7 @@ func makeSearch(form url.Values) *search {
 		}
 	}
 	sh := &search{
-		ID:       identifier.NewRandom(),
+		ID:       identifier.NewTimeOrdered(),
 		ParentID: parentSearchID,
 		Text:     textQuery,
 	}
@@ -116,7 +110,7 @@ func getOrMakeSearch(form url.Values) (*search, bool) {
 	// We allow there to not be "q" so that it is easier to use as an API.
 	if form.Has("q") && ss.Text != textQuery {
 		ss = &search{
-			ID:       identifier.NewRandom(),
+			ID:       identifier.NewTimeOrdered(),
 			ParentID: searchID,
 			Text:     textQuery,
 		}
@@ -145,6 +139,138 @@ func getSearch(form url.Values) *search {
 	return ss
 }
 
+// getSubproperties returns the "subprops" parameter, which tells whether
+// property filters should match subproperties as well.
+func getSubproperties(form url.Values) (bool, errors.E) {
+	switch subprops := form.Get("subprops"); subprops {
+	case "", "false":
+		return false, nil
+	case "true":
+		return true, nil
+	default:
+		errE := errors.New("invalid subprops")
+		errors.Details(errE)["subprops"] = subprops
+		return false, errE
+	}
+}
+
+// parseTimeBound parses a bound of a time filter, which is either a timestamp or a year.
+// A year as the lower bound means the start of the year and as the upper bound the end
+// of the year. An empty bound means that the interval is unbounded on that side.
+func parseTimeBound(bound string, upper bool) (Timestamp, errors.E) {
+	if bound == "" {
+		if upper {
+			return Timestamp{Year: math.MaxInt64, Seconds: 0}, nil
+		}
+		return Timestamp{Year: math.MinInt64, Seconds: 0}, nil
+	}
+	if year, err := strconv.ParseInt(bound, 10, 64); err == nil { //nolint:gomnd
+		interval := Timestamp{Year: year, Seconds: 0}.Interval(TimePrecisionYear)
+		if upper {
+			return interval.Upper, nil
+		}
+		return interval.Lower, nil
+	}
+	return parseTimestamp(bound)
+}
+
+// parseTimeFilter parses a time filter in the "<prop>:<from>..<to>" format.
+func parseTimeFilter(value string) (Identifier, TimeInterval, errors.E) {
+	prop, bounds, ok := strings.Cut(value, ":")
+	if !ok {
+		return "", TimeInterval{}, errors.New("missing prop")
+	}
+	if !identifier.Valid(prop) {
+		errE := errors.New("invalid prop")
+		errors.Details(errE)["prop"] = prop
+		return "", TimeInterval{}, errE
+	}
+	from, to, ok := strings.Cut(bounds, "..")
+	if !ok {
+		return "", TimeInterval{}, errors.New("missing interval")
+	}
+	lower, errE := parseTimeBound(from, false)
+	if errE != nil {
+		return "", TimeInterval{}, errE
+	}
+	upper, errE := parseTimeBound(to, true)
+	if errE != nil {
+		return "", TimeInterval{}, errE
+	}
+	if upper.Before(lower) {
+		return "", TimeInterval{}, errors.New("empty interval")
+	}
+	return Identifier(prop), TimeInterval{Lower: lower, Upper: upper}, nil
+}
+
+// getFilters returns a query with filters requested with request parameters.
+//
+// Props are a set of property IDs for every property requested with "prop" parameters
+// (comma-separated property IDs). Documents match if they have an active claim for any property
+// in every set. If "subprops" parameter is "true", sets include subproperties as well.
+//
+// Classes are IDs of classes requested with "class" parameters (comma-separated
+// document IDs), matching documents which are instances of the class or any of its subclasses.
+//
+// Times are time filters requested with "time" parameters in the "<prop>:<from>..<to>" format,
+// where bounds are timestamps or years and can be empty. Documents match if they have an active
+// time or time range claim for the property (or its subproperties, if "subprops" parameter is
+// "true") with the effective interval intersecting the requested interval.
+func (s *Service) getFilters(form url.Values) (Query, errors.E) {
+	subprops, errE := getSubproperties(form)
+	if errE != nil {
+		return Query{}, errE
+	}
+
+	withSubproperties := func(prop Identifier) []Identifier {
+		ids := []Identifier{prop}
+		if subprops && s.Hierarchy != nil {
+			ids = append(ids, s.Hierarchy.Descendants(prop)...)
+		}
+		return ids
+	}
+
+	props := [][]Identifier{}
+	for _, prop := range splitValues(form, "prop") {
+		if !identifier.Valid(prop) {
+			errE := errors.New("invalid prop")
+			errors.Details(errE)["prop"] = prop
+			return Query{}, errE
+		}
+		props = append(props, withSubproperties(Identifier(prop)))
+	}
+
+	classes := []Identifier{}
+	for _, class := range splitValues(form, "class") {
+		if !identifier.Valid(class) {
+			errE := errors.New("invalid class")
+			errors.Details(errE)["class"] = class
+			return Query{}, errE
+		}
+		classes = append(classes, Identifier(class))
+	}
+
+	times := []TimeFilter{}
+	for _, value := range splitValues(form, "time") {
+		prop, interval, errE := parseTimeFilter(value)
+		if errE != nil {
+			errors.Details(errE)["time"] = value
+			return Query{}, errE
+		}
+		times = append(times, TimeFilter{
+			Props:    withSubproperties(prop),
+			Interval: interval,
+		})
+	}
+
+	return Query{
+		Text:    "",
+		Props:   props,
+		Classes: classes,
+		Times:   times,
+	}, nil
+}
+
 // searchResult is returned from the searchGet API endpoint.
 type searchResult struct {
 	ID string `json:"_id"`
@@ -192,10 +318,16 @@ func (s *Service) DocumentSearchGetHTML(w http.ResponseWriter, req *http.Request
 	}
 }
 
-// DocumentSearchGetJSON is a GET/HEAD HTTP request handler and it searches ElasticSearch index using provided
+// DocumentSearchGetJSON is a GET/HEAD HTTP request handler and it searches the backend using provided
 // search state and returns to the client a JSON with an array of IDs of found documents. If search state is
 // invalid, it returns correct query parameters as JSON. It supports compression based on accepted content
 // encoding and range requests. It returns search metadata (e.g., total results) as PeerDB HTTP response headers.
+//
+// Results can be filtered to documents with claims for properties listed in "prop" parameters
+// (comma-separated property IDs). If "subprops" parameter is "true", claims for their subproperties match, too.
+// Results can be filtered to instances of classes (or any of their subclasses) listed in "class" parameters.
+// Results can be filtered to documents with time claims intersecting intervals listed in "time" parameters
+// (in the "<prop>:<from>..<to>" format).
 func (s *Service) DocumentSearchGetJSON(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	contentEncoding := gddo.NegotiateContentEncoding(req, allCompressions)
 	if contentEncoding == "" {
@@ -217,45 +349,31 @@ func (s *Service) DocumentSearchGetJSON(w http.ResponseWriter, req *http.Request
 		return
 	}
 
-	// TODO: Determine which operator should be the default?
-	// TODO: Make sure right analyzers are used for all fields.
-	// TODO: Limit allowed syntax for simple queries (disable fuzzy matching).
-	searchService := s.ESClient.Search("docs").FetchSource(false).Preference(getHost(req.RemoteAddr)).
-		Header("X-Opaque-ID", idFromRequest(req)).From(0).Size(1000).TrackTotalHits(true) //nolint:gomnd
-	if sh.Text == "" {
-		matchQuery := elastic.NewMatchAllQuery()
-		searchService = searchService.Query(matchQuery)
-	} else {
-		boolQuery := elastic.NewBoolQuery()
-		// TODO: Check which analyzer is used.
-		boolQuery = boolQuery.Should(elastic.NewSimpleQueryStringQuery(sh.Text).Field("name.en").DefaultOperator("AND"))
-		for _, field := range []field{
-			{"active.id", "id"},
-			{"active.ref", "iri"},
-			{"active.text", "html.en"},
-			{"active.string", "string"},
-		} {
-			// TODO: Can we use simple query for keyword fields? Which analyzer is used?
-			q := elastic.NewSimpleQueryStringQuery(sh.Text).Field(field.Prefix + "." + field.Field).DefaultOperator("AND")
-			boolQuery = boolQuery.Should(elastic.NewNestedQuery(field.Prefix, q))
-		}
-		searchService = searchService.Query(boolQuery)
+	query, errE := s.getFilters(req.Form)
+	if errE != nil {
+		s.badRequest(w, req, errE)
+		return
 	}
+	query.Text = sh.Text
+
 	m = timing.NewMetric("es").Start()
-	res, err := searchService.Do(ctx)
+	res, errE := s.Backend.Search(ctx, query, SearchOptions{
+		Size:       1000, //nolint:gomnd
+		Preference: getHost(req.RemoteAddr),
+	})
 	m.Stop()
-	if err != nil {
-		s.internalServerError(w, req, errors.WithStack(err))
+	if errE != nil {
+		s.internalServerError(w, req, errE)
 		return
 	}
 
-	results := make([]searchResult, len(res.Hits.Hits))
-	for i, hit := range res.Hits.Hits {
-		results[i] = searchResult{ID: hit.Id}
+	results := make([]searchResult, len(res.IDs))
+	for i, id := range res.IDs {
+		results[i] = searchResult{ID: string(id)}
 	}
 
-	total := strconv.FormatInt(res.Hits.TotalHits.Value, 10) //nolint:gomnd
-	if res.Hits.TotalHits.Relation == "gte" {
+	total := strconv.FormatInt(res.Total, 10) //nolint:gomnd
+	if res.TotalIsLowerBound {
 		total += "+"
 	}
 
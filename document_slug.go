@@ -0,0 +1,53 @@
+package search
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/julienschmidt/httprouter"
+	servertiming "github.com/mitchellh/go-server-timing"
+)
+
+// DocumentSlugGetHTML is a GET/HEAD HTTP request handler which redirects to the HTML frontend
+// for a document given its current or a previous slug as a parameter.
+func (s *Service) DocumentSlugGetHTML(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	s.redirectSlug(w, req, ps)
+}
+
+// DocumentSlugGetJSON is a GET/HEAD HTTP request handler which redirects to the document
+// given its current or a previous slug as a parameter.
+func (s *Service) DocumentSlugGetJSON(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	s.redirectSlug(w, req, ps)
+}
+
+func (s *Service) redirectSlug(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	ctx := req.Context()
+	timing := servertiming.FromContext(ctx)
+
+	slug := ps.ByName("slug")
+	if slug == "" {
+		http.Error(w, "400 bad request", http.StatusBadRequest)
+		return
+	}
+
+	m := timing.NewMetric("es").Start()
+	id, errE := ResolveSlug(ctx, s.Backend, slug)
+	m.Stop()
+	if errE != nil {
+		s.internalServerError(w, req, errE)
+		return
+	}
+	if id == "" {
+		s.NotFound(w, req)
+		return
+	}
+
+	// DocumentGet redirects further if the slug is not the current slug.
+	path, errE := s.path("DocumentGet", url.Values{"id": {string(id)}, "slug": {slug}}, req.URL.RawQuery)
+	if errE != nil {
+		s.internalServerError(w, req, errE)
+		return
+	}
+	w.Header().Set("Location", path)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
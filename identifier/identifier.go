@@ -9,10 +9,12 @@ import (
 
 	"github.com/btcsuite/btcutil/base58"
 	"github.com/google/uuid"
+	"gitlab.com/tozd/go/errors"
 )
 
 const (
-	idLength = 22
+	idLength   = 22
+	uuidLength = 16
 )
 
 var idRegex = regexp.MustCompile(`^[123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz]{22}$`)
@@ -26,6 +28,35 @@ func FromUUID(data uuid.UUID) string {
 	return res
 }
 
+// ToUUID decodes a PeerDB identifier back to an UUID. It is the inverse of FromUUID.
+//
+// Not every valid PeerDB identifier can be decoded to an UUID, e.g., random
+// identifiers from NewRandom can encode values larger than 128 bits.
+func ToUUID(id string) (uuid.UUID, errors.E) {
+	if !Valid(id) {
+		errE := errors.New("invalid identifier")
+		errors.Details(errE)["id"] = id
+		return uuid.Nil, errE
+	}
+
+	// Every leading "1" decodes to a leading zero byte, both those which encode leading
+	// zero bytes of the UUID and those added by FromUUID as padding. So we remove all
+	// extra leading zero bytes beyond the UUID's length.
+	data := base58.Decode(id)
+	for len(data) > uuidLength && data[0] == 0 {
+		data = data[1:]
+	}
+	if len(data) != uuidLength {
+		errE := errors.New("identifier does not encode an UUID")
+		errors.Details(errE)["id"] = id
+		return uuid.Nil, errE
+	}
+
+	var res uuid.UUID
+	copy(res[:], data)
+	return res, nil
+}
+
 // NewRandom returns a new random PeerDB identifier.
 func NewRandom() string {
 	return NewRandomFromReader(rand.Reader)
@@ -5,6 +5,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"gitlab.com/peerdb/search/identifier"
 )
@@ -25,3 +26,60 @@ func TestFromRandom(t *testing.T) {
 		assert.Len(t, i, 22)
 	}
 }
+
+func TestToUUID(t *testing.T) {
+	for i := 0; i < 100000; i++ {
+		u := uuid.New()
+		id := identifier.FromUUID(u)
+		res, errE := identifier.ToUUID(id)
+		require.NoError(t, errE)
+		assert.Equal(t, u, res)
+	}
+
+	for _, u := range []uuid.UUID{
+		uuid.Nil,
+		uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		uuid.MustParse("0000ffff-0000-0000-0000-000000000000"),
+		uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff"),
+	} {
+		id := identifier.FromUUID(u)
+		assert.Len(t, id, 22)
+		res, errE := identifier.ToUUID(id)
+		require.NoError(t, errE, u.String())
+		assert.Equal(t, u, res)
+	}
+
+	_, errE := identifier.ToUUID("invalid")
+	assert.Error(t, errE)
+
+	// Largest valid identifier does not fit into an UUID.
+	_, errE = identifier.ToUUID("zzzzzzzzzzzzzzzzzzzzzz")
+	assert.Error(t, errE)
+}
+
+func TestNamespace(t *testing.T) {
+	namespace := identifier.RegisterNamespace("Test", uuid.MustParse("2c5e4a0e-5b3d-4d3f-8f3a-7f9a4f5a9b6e"))
+
+	// Registering the same namespace again is allowed.
+	assert.Equal(t, namespace, identifier.RegisterNamespace("Test", namespace))
+	assert.Panics(t, func() {
+		identifier.RegisterNamespace("Test", uuid.New())
+	})
+
+	n, ok := identifier.Namespace("Test")
+	assert.True(t, ok)
+	assert.Equal(t, namespace, n)
+	_, ok = identifier.Namespace("Missing")
+	assert.False(t, ok)
+
+	assert.Contains(t, identifier.Namespaces(), "Test")
+
+	id := identifier.FromNamespace(namespace, "foo", 42)
+	assert.True(t, identifier.Valid(id))
+	assert.Equal(t, id, identifier.FromNamespace(namespace, "foo", 42))
+	assert.NotEqual(t, id, identifier.FromNamespace(namespace, "foo", 43))
+	assert.Equal(t, identifier.FromUUID(namespace), identifier.FromNamespace(namespace))
+
+	assert.Equal(t, []string{"Test"}, identifier.MatchNamespace(id, "foo", 42))
+	assert.Empty(t, identifier.MatchNamespace(id, "foo", 43))
+}
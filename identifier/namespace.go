@@ -0,0 +1,76 @@
+package identifier
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	namespacesMu = sync.RWMutex{}
+	// A map from a namespace name to its UUID.
+	namespaces = map[string]uuid.UUID{}
+)
+
+// FromNamespace returns a PeerDB identifier deterministically derived from the
+// namespace and arguments. Arguments are formatted with fmt.Sprint and each
+// of them is used in turn to derive a name-based (SHA-1) UUID.
+func FromNamespace(namespace uuid.UUID, args ...interface{}) string {
+	res := namespace
+	for _, arg := range args {
+		res = uuid.NewSHA1(res, []byte(fmt.Sprint(arg)))
+	}
+	return FromUUID(res)
+}
+
+// RegisterNamespace registers a namespace under the name and returns it.
+// It panics if a different namespace is already registered under the same name,
+// so it is meant to be used when initializing package-level variables.
+func RegisterNamespace(name string, namespace uuid.UUID) uuid.UUID {
+	namespacesMu.Lock()
+	defer namespacesMu.Unlock()
+
+	if existing, ok := namespaces[name]; ok && existing != namespace {
+		panic(fmt.Sprintf(`namespace "%s" is already registered`, name))
+	}
+	namespaces[name] = namespace
+	return namespace
+}
+
+// Namespace returns the namespace registered under the name.
+func Namespace(name string) (uuid.UUID, bool) {
+	namespacesMu.RLock()
+	defer namespacesMu.RUnlock()
+
+	namespace, ok := namespaces[name]
+	return namespace, ok
+}
+
+// Namespaces returns names of all registered namespaces, sorted.
+func Namespaces() []string {
+	namespacesMu.RLock()
+	defer namespacesMu.RUnlock()
+
+	names := make([]string, 0, len(namespaces))
+	for name := range namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MatchNamespace returns names of registered namespaces from which the identifier
+// can be derived with FromNamespace using args. This is useful for debugging, to
+// determine where an identifier comes from.
+func MatchNamespace(id string, args ...interface{}) []string {
+	matches := []string{}
+	for _, name := range Namespaces() {
+		namespace, ok := Namespace(name)
+		if ok && FromNamespace(namespace, args...) == id {
+			matches = append(matches, name)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,93 @@
+package identifier
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/tozd/go/errors"
+)
+
+const (
+	timeOrderedVersion = 7
+	// Maximum value of the 12-bit sequence stored after the timestamp.
+	maxSequence = 0xfff
+)
+
+var (
+	timeOrderedMu      = sync.Mutex{}
+	lastTimeOrderedMs  int64
+	lastTimeOrderedSeq uint16
+)
+
+// NewTimeOrdered returns a new time-ordered PeerDB identifier.
+//
+// The identifier encodes an UUIDv7-style value: 48 bits of Unix time in milliseconds,
+// followed by a 12-bit sequence number and 62 random bits. Because FromUUID pads identifiers
+// to a fixed length and the base58 alphabet is sorted, identifiers compare (as strings) in the
+// order in which they were made. This holds also for identifiers made in the same millisecond
+// or when the clock goes backwards, as long as they are made by the same process.
+func NewTimeOrdered() string {
+	return NewTimeOrderedFromReader(rand.Reader)
+}
+
+// NewTimeOrderedFromReader returns a new time-ordered PeerDB identifier using r as
+// a source of randomness.
+func NewTimeOrderedFromReader(r io.Reader) string {
+	var data uuid.UUID
+	_, err := io.ReadFull(r, data[8:])
+	if err != nil {
+		panic(err)
+	}
+
+	ms, seq := nextTimeOrdered()
+
+	// 48 bits of the timestamp. We write 64 bits and then overwrite the lower 16 bits.
+	binary.BigEndian.PutUint64(data[0:8], uint64(ms)<<16) //nolint:gomnd
+	// 4 bits of the version and 12 bits of the sequence.
+	binary.BigEndian.PutUint16(data[6:8], timeOrderedVersion<<12|seq) //nolint:gomnd
+	// RFC 4122 variant.
+	data[8] = data[8]&0x3f | 0x80 //nolint:gomnd
+
+	return FromUUID(data)
+}
+
+// nextTimeOrdered returns the timestamp and the sequence number for the next
+// time-ordered identifier, making sure that they are larger than the previous ones.
+func nextTimeOrdered() (int64, uint16) {
+	timeOrderedMu.Lock()
+	defer timeOrderedMu.Unlock()
+
+	ms := time.Now().UnixMilli()
+	if ms > lastTimeOrderedMs {
+		lastTimeOrderedMs = ms
+		lastTimeOrderedSeq = 0
+	} else if lastTimeOrderedSeq < maxSequence {
+		// The same millisecond or the clock went backwards.
+		lastTimeOrderedSeq++
+	} else {
+		// Sequence overflow, we borrow the next millisecond.
+		lastTimeOrderedMs++
+		lastTimeOrderedSeq = 0
+	}
+	return lastTimeOrderedMs, lastTimeOrderedSeq
+}
+
+// Timestamp returns the time at which a time-ordered PeerDB identifier was made,
+// with millisecond precision. It returns an error for other identifiers.
+func Timestamp(id string) (time.Time, errors.E) {
+	data, errE := ToUUID(id)
+	if errE != nil {
+		return time.Time{}, errE
+	}
+	if data.Version() != timeOrderedVersion || data.Variant() != uuid.RFC4122 {
+		errE := errors.New("identifier is not time-ordered")
+		errors.Details(errE)["id"] = id
+		return time.Time{}, errE
+	}
+	ms := int64(binary.BigEndian.Uint64(data[0:8]) >> 16) //nolint:gomnd
+	return time.UnixMilli(ms).UTC(), nil
+}
@@ -0,0 +1,81 @@
+package identifier_test
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/peerdb/search/identifier"
+)
+
+func TestNewTimeOrdered(t *testing.T) {
+	before := time.Now().Truncate(time.Millisecond)
+	id := identifier.NewTimeOrdered()
+	after := time.Now()
+
+	assert.Len(t, id, 22)
+	assert.True(t, identifier.Valid(id))
+
+	_, errE := identifier.ToUUID(id)
+	require.NoError(t, errE)
+
+	timestamp, errE := identifier.Timestamp(id)
+	require.NoError(t, errE)
+	// The timestamp can be in the future if the sequence overflowed in other tests.
+	assert.False(t, timestamp.Before(before), "%s < %s", timestamp, before)
+	assert.WithinDuration(t, after, timestamp, time.Second)
+
+	_, errE = identifier.Timestamp(identifier.NewRandom())
+	assert.Error(t, errE)
+	_, errE = identifier.Timestamp("invalid")
+	assert.Error(t, errE)
+}
+
+func TestNewTimeOrderedMonotonic(t *testing.T) {
+	const goroutines = 16
+	const perGoroutine = 10000
+
+	results := make([][]string, goroutines)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		g := g
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids := make([]string, perGoroutine)
+			for i := range ids {
+				ids[i] = identifier.NewTimeOrdered()
+			}
+			results[g] = ids
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool, goroutines*perGoroutine)
+	all := make([]string, 0, goroutines*perGoroutine)
+	for _, ids := range results {
+		for i, id := range ids {
+			if i > 0 {
+				// Identifiers made by the same goroutine are strictly increasing.
+				require.Less(t, ids[i-1], id)
+			}
+			require.False(t, seen[id], id)
+			seen[id] = true
+			all = append(all, id)
+		}
+	}
+
+	// Sorting identifiers sorts them by their timestamps as well.
+	sort.Strings(all)
+	var previous time.Time
+	for _, id := range all {
+		timestamp, errE := identifier.Timestamp(id)
+		require.NoError(t, errE)
+		require.False(t, timestamp.Before(previous))
+		previous = timestamp
+	}
+}
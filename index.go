@@ -2,20 +2,413 @@ package search
 
 import (
 	"context"
+	"crypto/sha256"
 	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/olivere/elastic/v7"
 	"github.com/rs/zerolog"
 
 	"gitlab.com/tozd/go/errors"
+
+	"gitlab.com/peerdb/search/identifier"
 )
 
-// TODO: Generate index configuration automatically from document structs?
+// index.json is generated with cmd/mapping from document structs and their "es" struct tags.
 
 //go:embed index.json
 var indexConfiguration string
 
+const (
+	// How often is the reindexing task polled for its status.
+	reindexPollInterval = 10 * time.Second
+	// The maximum number of passes copying documents written to the old index while reindexing.
+	reindexCatchUpPasses = 5
+)
+
+// IndexConfigurationHash returns a hash of the current index configuration.
+//
+// The hash is stored into mapping's metadata of every index created and is used to
+// detect if an existing index has been created with a different configuration.
+func IndexConfigurationHash() string {
+	h := sha256.Sum256([]byte(indexConfiguration))
+	return hex.EncodeToString(h[:])
+}
+
+// versionedIndexConfiguration returns the current index configuration with its hash
+// stored into mapping's metadata.
+func versionedIndexConfiguration() (string, errors.E) {
+	var configuration map[string]interface{}
+	err := json.Unmarshal([]byte(indexConfiguration), &configuration)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	mappings, ok := configuration["mappings"].(map[string]interface{})
+	if !ok {
+		return "", errors.New("index configuration without mappings")
+	}
+	mappings["_meta"] = map[string]interface{}{
+		"configuration": IndexConfigurationHash(),
+	}
+	data, err := json.Marshal(configuration)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return string(data), nil
+}
+
+// newIndexName returns a new name for a physical index of the alias.
+//
+// Names are suffixed with a time-ordered identifier, so they sort in the order in which they
+// were made and do not collide even when made in quick succession. ElasticSearch index names
+// have to be lowercase, so we use the hex encoding of the identifier.
+func newIndexName(alias string) (string, errors.E) {
+	id, errE := identifier.ToUUID(identifier.NewTimeOrdered())
+	if errE != nil {
+		return "", errE
+	}
+	return fmt.Sprintf("%s_%s", alias, hex.EncodeToString(id[:])), nil
+}
+
+// CreateIndex creates a new physical index for the alias using the current index configuration.
+// It does not point the alias to the new index. It returns the name of the new index.
+func CreateIndex(ctx context.Context, esClient *elastic.Client, alias string) (string, errors.E) {
+	configuration, errE := versionedIndexConfiguration()
+	if errE != nil {
+		return "", errE
+	}
+
+	index, errE := newIndexName(alias)
+	if errE != nil {
+		return "", errE
+	}
+	createIndex, err := esClient.CreateIndex(index).BodyString(configuration).Do(ctx)
+	if err != nil {
+		errE := errors.WithStack(err)
+		errors.Details(errE)["index"] = index
+		return "", errE
+	}
+	if !createIndex.Acknowledged {
+		// TODO: Wait for acknowledgment using Task API?
+		errE := errors.New("create index not acknowledged")
+		errors.Details(errE)["index"] = index
+		return "", errE
+	}
+
+	return index, nil
+}
+
+// GetAliasIndex returns the name of the physical index the alias points to.
+// It returns an empty string if the alias does not exist.
+func GetAliasIndex(ctx context.Context, esClient *elastic.Client, alias string) (string, errors.E) {
+	aliases, err := esClient.Aliases().Alias(alias).Do(ctx)
+	if elastic.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		errE := errors.WithStack(err)
+		errors.Details(errE)["alias"] = alias
+		return "", errE
+	}
+
+	indices := aliases.IndicesByAlias(alias)
+	if len(indices) == 0 {
+		return "", nil
+	} else if len(indices) > 1 {
+		errE := errors.New("alias points to multiple indices")
+		errors.Details(errE)["alias"] = alias
+		errors.Details(errE)["indices"] = indices
+		return "", errE
+	}
+
+	return indices[0], nil
+}
+
+// IndexDrift returns true if the index has been created with a different
+// index configuration than the current one.
+func IndexDrift(ctx context.Context, esClient *elastic.Client, index string) (bool, errors.E) {
+	mappings, err := esClient.GetMapping().Index(index).Do(ctx)
+	if err != nil {
+		errE := errors.WithStack(err)
+		errors.Details(errE)["index"] = index
+		return false, errE
+	}
+
+	var hash string
+	// Response is keyed by the name of the physical index,
+	// which is different from the index name we queried if it is an alias.
+	for _, m := range mappings {
+		i, _ := m.(map[string]interface{})
+		mapping, _ := i["mappings"].(map[string]interface{})
+		meta, _ := mapping["_meta"].(map[string]interface{})
+		hash, _ = meta["configuration"].(string)
+	}
+
+	return hash != IndexConfigurationHash(), nil
+}
+
+// CheckIndex logs a warning if the index has been created with a different index configuration
+// than the current one, suggesting to reindex.
+func CheckIndex(ctx context.Context, esClient *elastic.Client, logger zerolog.Logger, index string) errors.E {
+	drift, errE := IndexDrift(ctx, esClient, index)
+	if errE != nil {
+		return errE
+	}
+	if drift {
+		logger.Warn().Str("index", index).Msg("index configuration differs from the current one, reindex to update it")
+	}
+	return nil
+}
+
+// SwapAlias atomically points the alias to the index. If the alias currently points
+// to another index, it is removed from it. If an index with the same name as the alias
+// exists (an index created before indices were versioned), the index is deleted.
+func SwapAlias(ctx context.Context, esClient *elastic.Client, alias, index string) errors.E {
+	old, errE := GetAliasIndex(ctx, esClient, alias)
+	if errE != nil {
+		return errE
+	}
+
+	actions := []elastic.AliasAction{elastic.NewAliasAddAction(alias).Index(index)}
+	if old != "" {
+		actions = append(actions, elastic.NewAliasRemoveAction(alias).Index(old))
+	} else {
+		exists, err := esClient.IndexExists(alias).Do(ctx)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if exists {
+			actions = append(actions, elastic.NewAliasRemoveIndexAction(alias))
+		}
+	}
+
+	result, err := esClient.Alias().Action(actions...).Do(ctx)
+	if err != nil {
+		errE := errors.WithStack(err)
+		errors.Details(errE)["alias"] = alias
+		errors.Details(errE)["index"] = index
+		return errE
+	}
+	if !result.Acknowledged {
+		errE := errors.New("alias update not acknowledged")
+		errors.Details(errE)["alias"] = alias
+		errors.Details(errE)["index"] = index
+		return errE
+	}
+
+	return nil
+}
+
+// reindexTask is the status of a reindexing task as returned by the Task Management API.
+type reindexTask struct {
+	Completed bool                               `json:"completed"`
+	Task      elastic.TaskInfo                   `json:"task"`
+	Response  *elastic.BulkIndexByScrollResponse `json:"response,omitempty"`
+	Error     *elastic.ErrorDetails              `json:"error,omitempty"`
+}
+
+// waitForReindexTask polls the reindexing task until it completes and returns its response.
+// If the context is canceled, the task is canceled as well.
+func waitForReindexTask(ctx context.Context, esClient *elastic.Client, logger zerolog.Logger, taskID string) (*elastic.BulkIndexByScrollResponse, errors.E) {
+	ticker := time.NewTicker(reindexPollInterval)
+	defer ticker.Stop()
+
+	for {
+		resp, err := esClient.PerformRequest(ctx, elastic.PerformRequestOptions{
+			Method: "GET",
+			Path:   fmt.Sprintf("/_tasks/%s", url.PathEscape(taskID)),
+		})
+		if err != nil {
+			errE := errors.WithStack(err)
+			errors.Details(errE)["task"] = taskID
+			return nil, errE
+		}
+		var task reindexTask
+		err = json.Unmarshal(resp.Body, &task)
+		if err != nil {
+			errE := errors.WithStack(err)
+			errors.Details(errE)["task"] = taskID
+			return nil, errE
+		}
+
+		if task.Completed {
+			if task.Error != nil {
+				errE := errors.New("reindex task failed")
+				errors.Details(errE)["task"] = taskID
+				errors.Details(errE)["type"] = task.Error.Type
+				errors.Details(errE)["reason"] = task.Error.Reason
+				return nil, errE
+			} else if task.Response == nil {
+				errE := errors.New("reindex task without response")
+				errors.Details(errE)["task"] = taskID
+				return nil, errE
+			}
+			return task.Response, nil
+		}
+
+		logger.Info().Str("task", taskID).Interface("status", task.Task.Status).Msg("reindexing")
+
+		select {
+		case <-ctx.Done():
+			// We use a new context because the existing one has been canceled.
+			_, err := esClient.TasksCancel().TaskId(taskID).Do(context.Background())
+			if err != nil {
+				logger.Error().Str("task", taskID).Err(err).Msg("unable to cancel reindex task")
+			}
+			return nil, errors.WithStack(ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// copyDocuments copies documents from the source index into the destination index
+// (transforming them with the script, if provided) and returns the number of documents
+// created or updated in the destination index.
+//
+// Versions of documents are preserved and only documents missing from the destination
+// index or with an older version there are copied, so copying again copies only
+// documents written to the source index in the meantime.
+func copyDocuments(ctx context.Context, esClient *elastic.Client, logger zerolog.Logger, source, destination string, script *elastic.Script) (int64, errors.E) {
+	reindex := esClient.Reindex().
+		Source(elastic.NewReindexSource().Index(source)).
+		Destination(elastic.NewReindexDestination().Index(destination).VersionType("external")).
+		// Documents which have not changed since they were copied are reported as version conflicts.
+		ProceedOnVersionConflict().
+		WaitForCompletion(false)
+	if script != nil {
+		reindex = reindex.Script(script)
+	}
+	task, err := reindex.DoAsync(ctx)
+	if err != nil {
+		errE := errors.WithStack(err)
+		errors.Details(errE)["source"] = source
+		errors.Details(errE)["destination"] = destination
+		return 0, errE
+	}
+
+	response, errE := waitForReindexTask(ctx, esClient, logger, task.TaskId)
+	if errE != nil {
+		errors.Details(errE)["source"] = source
+		errors.Details(errE)["destination"] = destination
+		return 0, errE
+	}
+	if len(response.Failures) > 0 || response.TimedOut {
+		errE := errors.New("reindex failed")
+		errors.Details(errE)["source"] = source
+		errors.Details(errE)["destination"] = destination
+		errors.Details(errE)["failures"] = len(response.Failures)
+		errors.Details(errE)["timedOut"] = response.TimedOut
+		return 0, errE
+	}
+
+	logger.Info().Str("source", source).Str("destination", destination).
+		Int64("created", response.Created).Int64("updated", response.Updated).Int64("unchanged", response.VersionConflicts).
+		Msg("documents copied")
+
+	return response.Created + response.Updated, nil
+}
+
+// setWriteBlock blocks or unblocks writes to the index.
+func setWriteBlock(ctx context.Context, esClient *elastic.Client, index string, block bool) errors.E {
+	_, err := esClient.IndexPutSettings(index).BodyJson(map[string]interface{}{
+		"index.blocks.write": block,
+	}).Do(ctx)
+	if err != nil {
+		errE := errors.WithStack(err)
+		errors.Details(errE)["index"] = index
+		return errE
+	}
+	return nil
+}
+
+// Reindex creates a new physical index for the alias using the current index configuration,
+// copies all documents from the index the alias currently points to into the new index
+// (transforming them with the script, if provided), and atomically points the alias to
+// the new index. It returns the names of the old and the new index.
+//
+// Copying runs as an ElasticSearch task which is polled until it completes. Documents
+// written to the old index while copying are copied afterwards in additional passes.
+// Writes to the old index are blocked only during the last pass and until the alias
+// is pointed to the new index, so that no writes are lost. Writes which fail meanwhile
+// are reported by ElasticBackend as temporary failures (see BulkResult.Temporary)
+// so that concurrent writers can retry them, which then write to the new index.
+func Reindex(ctx context.Context, esClient *elastic.Client, logger zerolog.Logger, alias string, script *elastic.Script) (string, string, errors.E) {
+	old, errE := GetAliasIndex(ctx, esClient, alias)
+	if errE != nil {
+		return "", "", errE
+	}
+	if old == "" {
+		// An index created before indices were versioned.
+		exists, err := esClient.IndexExists(alias).Do(ctx)
+		if err != nil {
+			return "", "", errors.WithStack(err)
+		}
+		if !exists {
+			errE := errors.New("index does not exist")
+			errors.Details(errE)["alias"] = alias
+			return "", "", errE
+		}
+		old = alias
+	}
+
+	index, errE := CreateIndex(ctx, esClient, alias)
+	if errE != nil {
+		return "", "", errE
+	}
+
+	_, errE = copyDocuments(ctx, esClient, logger, old, index, script)
+	if errE != nil {
+		return "", "", errE
+	}
+
+	// We catch up with documents written while copying, while there are any.
+	for i := 0; i < reindexCatchUpPasses; i++ {
+		copied, errE := copyDocuments(ctx, esClient, logger, old, index, script)
+		if errE != nil {
+			return "", "", errE
+		}
+		if copied == 0 {
+			break
+		}
+	}
+
+	// The last pass with writes blocked, so that no writes are lost before the alias is swapped.
+	errE = setWriteBlock(ctx, esClient, old, true)
+	if errE != nil {
+		return "", "", errE
+	}
+	// We use a new context so that writes are unblocked even if the context has been canceled.
+	defer func() {
+		errE := setWriteBlock(context.Background(), esClient, old, false)
+		if errE != nil && old != alias {
+			logger.Error().Str("index", old).Err(errE).Fields(errors.AllDetails(errE)).Msg("unable to unblock writes")
+		}
+	}()
+
+	_, errE = copyDocuments(ctx, esClient, logger, old, index, script)
+	if errE != nil {
+		return "", "", errE
+	}
+
+	_, err := esClient.Refresh(index).Do(ctx)
+	if err != nil {
+		errE := errors.WithStack(err)
+		errors.Details(errE)["index"] = index
+		return "", "", errE
+	}
+
+	errE = SwapAlias(ctx, esClient, alias, index)
+	if errE != nil {
+		return "", "", errE
+	}
+
+	return old, index, nil
+}
+
 type loggerAdapter struct {
 	log   zerolog.Logger
 	level zerolog.Level
@@ -40,29 +433,38 @@ func GetClient(httpClient *http.Client, logger zerolog.Logger, url string) (*ela
 }
 
 // EnsureIndex creates an instance of the ElasticSearch client and makes sure
-// the index for PeerDB documents exists. If not, it creates it.
+// the index for PeerDB documents exists. If not, it creates a physical index
+// and an alias named index pointing to it.
 // It does not update configuration of an existing index if it is different from
-// what current implementation of EnsureIndex would otherwise create.
+// what current implementation of EnsureIndex would otherwise create, but it logs
+// a warning. Use Reindex to update it.
 func EnsureIndex(ctx context.Context, httpClient *http.Client, logger zerolog.Logger, url, index string) (*elastic.Client, errors.E) {
 	esClient, errE := GetClient(httpClient, logger, url)
 	if errE != nil {
 		return nil, errE
 	}
 
+	// This checks for both aliases and indices.
 	exists, err := esClient.IndexExists(index).Do(ctx)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	if !exists {
-		createIndex, err := esClient.CreateIndex(index).BodyString(indexConfiguration).Do(ctx)
-		if err != nil {
-			return nil, errors.WithStack(err)
-		}
-		if !createIndex.Acknowledged {
-			// TODO: Wait for acknowledgment using Task API?
-			return nil, errors.New("create index not acknowledged")
+	if exists {
+		errE = CheckIndex(ctx, esClient, logger, index)
+		if errE != nil {
+			return nil, errE
 		}
+		return esClient, nil
+	}
+
+	physical, errE := CreateIndex(ctx, esClient, index)
+	if errE != nil {
+		return nil, errE
+	}
+	errE = SwapAlias(ctx, esClient, index, physical)
+	if errE != nil {
+		return nil, errE
 	}
 
 	return esClient, nil
@@ -35,6 +35,7 @@ type AllPagesPage struct {
 	Categories []PageReference   `json:"categories,omitempty"`
 	Templates  []PageReference   `json:"templates,omitempty"`
 	Redirects  []PageReference   `json:"redirects,omitempty"`
+	Missing    bool              `json:"missing,omitempty"`
 }
 
 type allPagesAPIResponse struct {
@@ -77,139 +78,184 @@ func ListAllPages(
 		baseData.Set("tllimit", strconv.Itoa(APILimit))
 		baseData.Set("rdlimit", strconv.Itoa(APILimit))
 
-		// Make a copy.
-		data := shallowCopy(baseData)
+		errE := listPages(ctx, httpClient, site, localLimiter, limiter, baseData, output)
+		if errE != nil {
+			return errE
+		}
+	}
 
-		var batch []AllPagesPage
+	return nil
+}
 
-		// Used for debugging.
-		previousURL := ""
+// ListAllRedirectTargets lists all pages in namespaces which are targets of redirects,
+// together with their redirects. Pages have only their redirects populated.
+//
+// This is much faster than listing all pages with ListAllPages when only a small
+// fraction of pages are targets of redirects (e.g., on Wikidata).
+func ListAllRedirectTargets(
+	ctx context.Context, httpClient *retryablehttp.Client, namespaces []int, site string, limiter *rate.Limiter, output chan<- AllPagesPage,
+) errors.E {
+	// We still want to make sure we are contacting query API only once every second.
+	localLimiter := rate.NewLimiter(rate.Every(time.Second), 1)
 
-		for {
-			err := localLimiter.Wait(ctx)
-			if err != nil {
-				// Context has been canceled.
-				return errors.WithStack(err)
-			}
+	for _, namespace := range namespaces {
+		baseData := url.Values{}
+		baseData.Set("action", "query")
+		baseData.Set("format", "json")
+		baseData.Set("formatversion", "2")
+		baseData.Set("generator", "allredirects")
+		baseData.Set("garnamespace", strconv.Itoa(namespace))
+		baseData.Set("garunique", "1")
+		baseData.Set("prop", "redirects")
+		baseData.Set("garlimit", strconv.Itoa(APILimit))
+		baseData.Set("rdlimit", strconv.Itoa(APILimit))
 
-			err = limiter.Wait(ctx)
-			if err != nil {
-				// Context has been canceled.
-				return errors.WithStack(err)
-			}
+		errE := listPages(ctx, httpClient, site, localLimiter, limiter, baseData, output)
+		if errE != nil {
+			return errE
+		}
+	}
 
-			encodedData := data.Encode()
-			apiURL := fmt.Sprintf("https://%s/w/api.php?%s", site, encodedData)
-			req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
-			if err != nil {
-				errE := errors.WithStack(err)
-				errors.Details(errE)["url"] = apiURL
-				if previousURL != "" {
-					errors.Details(errE)["previous"] = previousURL
-				}
-				return errE
+	return nil
+}
+
+// listPages lists all pages generated by the query API with baseData, merging
+// properties of pages across continuations, and sends them to output.
+func listPages(
+	ctx context.Context, httpClient *retryablehttp.Client, site string, localLimiter, limiter *rate.Limiter, baseData url.Values, output chan<- AllPagesPage,
+) errors.E {
+	// Make a copy.
+	data := shallowCopy(baseData)
+
+	var batch []AllPagesPage
+
+	// Used for debugging.
+	previousURL := ""
+
+	for {
+		err := localLimiter.Wait(ctx)
+		if err != nil {
+			// Context has been canceled.
+			return errors.WithStack(err)
+		}
+
+		err = limiter.Wait(ctx)
+		if err != nil {
+			// Context has been canceled.
+			return errors.WithStack(err)
+		}
+
+		encodedData := data.Encode()
+		apiURL := fmt.Sprintf("https://%s/w/api.php?%s", site, encodedData)
+		req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+		if err != nil {
+			errE := errors.WithStack(err)
+			errors.Details(errE)["url"] = apiURL
+			if previousURL != "" {
+				errors.Details(errE)["previous"] = previousURL
 			}
-			resp, err := httpClient.Do(req)
-			if err != nil {
-				errE := errors.WithStack(err)
-				errors.Details(errE)["url"] = apiURL
-				if previousURL != "" {
-					errors.Details(errE)["previous"] = previousURL
-				}
-				return errE
+			return errE
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			errE := errors.WithStack(err)
+			errors.Details(errE)["url"] = apiURL
+			if previousURL != "" {
+				errors.Details(errE)["previous"] = previousURL
 			}
-			defer resp.Body.Close()
-			if resp.StatusCode != http.StatusOK {
-				body, _ := io.ReadAll(resp.Body)
-				errE := errors.New("bad response status")
-				errors.Details(errE)["url"] = apiURL
-				errors.Details(errE)["code"] = resp.StatusCode
-				errors.Details(errE)["body"] = strings.TrimSpace(string(body))
-				if previousURL != "" {
-					errors.Details(errE)["previous"] = previousURL
-				}
-				return errE
+			return errE
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			errE := errors.New("bad response status")
+			errors.Details(errE)["url"] = apiURL
+			errors.Details(errE)["code"] = resp.StatusCode
+			errors.Details(errE)["body"] = strings.TrimSpace(string(body))
+			if previousURL != "" {
+				errors.Details(errE)["previous"] = previousURL
 			}
+			return errE
+		}
 
-			var apiResp allPagesAPIResponse
-			decoder := json.NewDecoder(resp.Body)
-			decoder.DisallowUnknownFields()
-			err = decoder.Decode(&apiResp)
-			if err != nil {
-				errE := errors.WithStack(err)
-				errors.Details(errE)["url"] = apiURL
-				if previousURL != "" {
-					errors.Details(errE)["previous"] = previousURL
-				}
-				return errE
+		var apiResp allPagesAPIResponse
+		decoder := json.NewDecoder(resp.Body)
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&apiResp)
+		if err != nil {
+			errE := errors.WithStack(err)
+			errors.Details(errE)["url"] = apiURL
+			if previousURL != "" {
+				errors.Details(errE)["previous"] = previousURL
 			}
-			if apiResp.Error != nil {
-				errE := errors.New("response error")
-				errors.Details(errE)["url"] = apiURL
-				errors.Details(errE)["body"] = apiResp.Error
-				if previousURL != "" {
-					errors.Details(errE)["previous"] = previousURL
-				}
-				return errE
+			return errE
+		}
+		if apiResp.Error != nil {
+			errE := errors.New("response error")
+			errors.Details(errE)["url"] = apiURL
+			errors.Details(errE)["body"] = apiResp.Error
+			if previousURL != "" {
+				errors.Details(errE)["previous"] = previousURL
 			}
+			return errE
+		}
 
-			if len(batch) == 0 {
-				batch = apiResp.Query.Pages
-			} else if len(batch) != len(apiResp.Query.Pages) {
-				errE := errors.New("unexpected number of pages")
-				errors.Details(errE)["url"] = apiURL
-				errors.Details(errE)["got"] = len(apiResp.Query.Pages)
-				errors.Details(errE)["expected"] = len(batch)
-				if previousURL != "" {
-					errors.Details(errE)["previous"] = previousURL
+		if len(batch) == 0 {
+			batch = apiResp.Query.Pages
+		} else if len(batch) != len(apiResp.Query.Pages) {
+			errE := errors.New("unexpected number of pages")
+			errors.Details(errE)["url"] = apiURL
+			errors.Details(errE)["got"] = len(apiResp.Query.Pages)
+			errors.Details(errE)["expected"] = len(batch)
+			if previousURL != "" {
+				errors.Details(errE)["previous"] = previousURL
+			}
+			return errE
+		} else {
+			for i, page := range apiResp.Query.Pages {
+				if batch[i].Properties == nil {
+					batch[i].Properties = make(map[string]string)
 				}
-				return errE
-			} else {
-				for i, page := range apiResp.Query.Pages {
-					if batch[i].Properties == nil {
-						batch[i].Properties = make(map[string]string)
-					}
-					for key, value := range page.Properties {
-						batch[i].Properties[key] = value
-					}
-					batch[i].Categories = append(batch[i].Categories, page.Categories...)
-					batch[i].Templates = append(batch[i].Templates, page.Templates...)
-					batch[i].Redirects = append(batch[i].Redirects, page.Redirects...)
+				for key, value := range page.Properties {
+					batch[i].Properties[key] = value
 				}
+				batch[i].Categories = append(batch[i].Categories, page.Categories...)
+				batch[i].Templates = append(batch[i].Templates, page.Templates...)
+				batch[i].Redirects = append(batch[i].Redirects, page.Redirects...)
 			}
+		}
 
-			if apiResp.BatchComplete {
-				for _, page := range batch {
-					select {
-					case <-ctx.Done():
-						// Context has been canceled.
-						return errors.WithStack(ctx.Err())
-					case output <- page:
-					}
+		if apiResp.BatchComplete {
+			for _, page := range batch {
+				select {
+				case <-ctx.Done():
+					// Context has been canceled.
+					return errors.WithStack(ctx.Err())
+				case output <- page:
 				}
-				batch = nil
 			}
+			batch = nil
+		}
 
-			if len(apiResp.Continue) == 0 {
-				if !apiResp.BatchComplete {
-					errE := errors.New("batch incomplete without continue")
-					errors.Details(errE)["url"] = apiURL
-					if previousURL != "" {
-						errors.Details(errE)["previous"] = previousURL
-					}
-					return errE
+		if len(apiResp.Continue) == 0 {
+			if !apiResp.BatchComplete {
+				errE := errors.New("batch incomplete without continue")
+				errors.Details(errE)["url"] = apiURL
+				if previousURL != "" {
+					errors.Details(errE)["previous"] = previousURL
 				}
-				break
+				return errE
 			}
+			break
+		}
 
-			previousURL = apiURL
+		previousURL = apiURL
 
-			// Make a copy.
-			data = shallowCopy(baseData)
-			for key, value := range apiResp.Continue {
-				// Because we are calling Set and not Add, the shallow copy above is enough.
-				data.Set(key, value)
-			}
+		// Make a copy.
+		data = shallowCopy(baseData)
+		for key, value := range apiResp.Continue {
+			// Because we are calling Set and not Add, the shallow copy above is enough.
+			data.Set(key, value)
 		}
 	}
 
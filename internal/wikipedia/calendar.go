@@ -0,0 +1,122 @@
+package wikipedia
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/mediawiki"
+
+	"gitlab.com/peerdb/search"
+)
+
+const (
+	// Wikidata item for the proleptic Julian calendar.
+	julianCalendarID = "Q1985786"
+)
+
+var timeRegex = regexp.MustCompile(`^([+-]\d{1,})-(\d{2})-(\d{2})T(\d{2}):(\d{2}):(\d{2})Z$`)
+
+// TimeValue is a Wikidata time value with its date and time as they are in the dump.
+//
+// mediawiki.TimeValue stores them as time.Time which normalizes dates (e.g., Julian
+// leap day 1700-02-29 becomes 1700-03-01) and cannot represent all years.
+// Year uses astronomical numbering, in which 1 BCE is represented by 0.
+type TimeValue struct {
+	Year      int64
+	Month     time.Month
+	Day       int
+	Hour      int
+	Minute    int
+	Second    int
+	Precision mediawiki.TimePrecision
+	Calendar  mediawiki.CalendarModel
+}
+
+// parseTime parses date and time in the Wikidata format.
+func parseTime(t string) (TimeValue, errors.E) {
+	match := timeRegex.FindStringSubmatch(t)
+	if match == nil {
+		return TimeValue{}, errors.Errorf(`unable to parse time "%s"`, t)
+	}
+	year, err := strconv.ParseInt(match[1], 10, 64) //nolint:gomnd
+	if err != nil {
+		return TimeValue{}, errors.WithMessagef(err, `unable to parse year "%s"`, t)
+	}
+	if year < 0 {
+		// Wikidata uses historical numbering, in which year 0 is undefined,
+		// but we use astronomical numbering, so we add 1 here.
+		year++
+	} else if year == 0 {
+		return TimeValue{}, errors.New("year cannot be 0")
+	}
+	value := TimeValue{Year: year} //nolint:exhaustruct
+	for i, v := range []*int{(*int)(&value.Month), &value.Day, &value.Hour, &value.Minute, &value.Second} {
+		// All values have two digits, so they cannot fail to parse.
+		n, _ := strconv.Atoi(match[i+2])
+		*v = n
+	}
+	if value.Month == 0 {
+		// Wikidata uses 0 when month is unknown or insignificant.
+		value.Month = time.January
+	}
+	if value.Day == 0 {
+		// Wikidata uses 0 when day is unknown or insignificant.
+		value.Day = 1
+	}
+	return value, nil
+}
+
+// floorDiv is integer division which rounds towards negative infinity.
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// julianDayNumber returns the Julian Day Number for the date in the proleptic Julian calendar.
+func julianDayNumber(year int64, month time.Month, day int) int64 {
+	// We count months from March so that the leap day is at the end of the year.
+	a := floorDiv(14-int64(month), 12)                               //nolint:gomnd
+	y := year + 4800 - a                                             //nolint:gomnd
+	m := int64(month) + 12*a - 3                                     //nolint:gomnd
+	return int64(day) + (153*m+2)/5 + 365*y + floorDiv(y, 4) - 32083 //nolint:gomnd
+}
+
+// gregorianDate returns the date in the proleptic Gregorian calendar for the Julian Day Number.
+func gregorianDate(jdn int64) (int64, time.Month, int) {
+	a := jdn + 32044                           //nolint:gomnd
+	b := floorDiv(4*a+3, 146097)               //nolint:gomnd
+	c := a - floorDiv(146097*b, 4)             //nolint:gomnd
+	d := floorDiv(4*c+3, 1461)                 //nolint:gomnd
+	e := c - floorDiv(1461*d, 4)               //nolint:gomnd
+	m := floorDiv(5*e+2, 153)                  //nolint:gomnd
+	day := e - floorDiv(153*m+2, 5) + 1        //nolint:gomnd
+	month := m + 3 - 12*floorDiv(m, 10)        //nolint:gomnd
+	year := 100*b + d - 4800 + floorDiv(m, 10) //nolint:gomnd
+	return year, time.Month(month), int(day)
+}
+
+// julianToGregorian converts a date in the proleptic Julian calendar to
+// one in the proleptic Gregorian calendar.
+//
+// It converts through the Julian Day Number so that every valid Julian date
+// (including Julian leap days which do not exist in the Gregorian calendar,
+// e.g., 1700-02-29) maps exactly.
+func julianToGregorian(year int64, month time.Month, day int) (int64, time.Month, int) {
+	return gregorianDate(julianDayNumber(year, month, day))
+}
+
+// convertCalendar returns the timestamp converted to the proleptic Gregorian calendar.
+// Timestamps with precision coarser than a day are not shifted because the difference
+// between calendars is smaller than their precision.
+func convertCalendar(value TimeValue) (search.Timestamp, errors.E) {
+	year, month, day := value.Year, value.Month, value.Day
+	if value.Calendar == mediawiki.Julian && value.Precision >= mediawiki.Day {
+		year, month, day = julianToGregorian(year, month, day)
+	}
+	return search.NewTimestamp(year, month, day, value.Hour, value.Minute, value.Second)
+}
@@ -0,0 +1,145 @@
+package wikipedia
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/tozd/go/mediawiki"
+	"gitlab.com/tozd/go/x"
+
+	"gitlab.com/peerdb/search"
+)
+
+func TestJulianToGregorian(t *testing.T) {
+	type date struct {
+		year  int64
+		month time.Month
+		day   int
+	}
+	tests := []struct {
+		julian    date
+		gregorian date
+	}{
+		{date{1582, 10, 5}, date{1582, 10, 15}},
+		{date{1, 1, 3}, date{1, 1, 1}},
+		{date{1700, 2, 28}, date{1700, 3, 10}},
+		{date{1700, 2, 29}, date{1700, 3, 11}},
+		{date{1700, 3, 1}, date{1700, 3, 12}},
+		{date{1918, 1, 31}, date{1918, 2, 13}},
+		{date{-100, 3, 1}, date{-100, 2, 27}},
+		{date{-4712, 1, 1}, date{-4713, 11, 24}},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(fmt.Sprintf("%d-%02d-%02d", test.julian.year, test.julian.month, test.julian.day), func(t *testing.T) {
+			year, month, day := julianToGregorian(test.julian.year, test.julian.month, test.julian.day)
+			assert.Equal(t, test.gregorian, date{year, month, day})
+		})
+	}
+}
+
+func TestConvertCalendar(t *testing.T) {
+	julian := TimeValue{
+		Year:      1582,
+		Month:     10,
+		Day:       5,
+		Hour:      12,
+		Minute:    0,
+		Second:    0,
+		Precision: mediawiki.Day,
+		Calendar:  mediawiki.Julian,
+	}
+
+	timestamp, errE := convertCalendar(julian)
+	require.NoError(t, errE)
+	assert.Equal(t, search.TimestampFromTime(time.Date(1582, 10, 15, 12, 0, 0, 0, time.UTC)), timestamp)
+
+	julian.Precision = mediawiki.Year
+	timestamp, errE = convertCalendar(julian)
+	require.NoError(t, errE)
+	assert.Equal(t, search.TimestampFromTime(time.Date(1582, 10, 5, 12, 0, 0, 0, time.UTC)), timestamp)
+
+	julian.Precision = mediawiki.Day
+	julian.Calendar = mediawiki.Gregorian
+	timestamp, errE = convertCalendar(julian)
+	require.NoError(t, errE)
+	assert.Equal(t, search.TimestampFromTime(time.Date(1582, 10, 5, 12, 0, 0, 0, time.UTC)), timestamp)
+
+	// Julian leap day which does not exist in the Gregorian calendar.
+	timestamp, errE = convertCalendar(TimeValue{Year: 1700, Month: 2, Day: 29, Precision: mediawiki.Day, Calendar: mediawiki.Julian}) //nolint:exhaustruct
+	require.NoError(t, errE)
+	assert.Equal(t, search.TimestampFromTime(time.Date(1700, 3, 11, 0, 0, 0, 0, time.UTC)), timestamp)
+
+	// Years which time.Time cannot represent.
+	timestamp, errE = convertCalendar(TimeValue{Year: -13_798_000_000_000, Month: 1, Day: 1, Precision: mediawiki.BillionYears, Calendar: mediawiki.Gregorian}) //nolint:exhaustruct
+	require.NoError(t, errE)
+	assert.Equal(t, search.Timestamp{Year: -13_798_000_000_000, Seconds: 0}, timestamp)
+
+	_, errE = convertCalendar(TimeValue{Year: 1700, Month: 2, Day: 29, Precision: mediawiki.Day, Calendar: mediawiki.Gregorian}) //nolint:exhaustruct
+	assert.Error(t, errE)
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		time     string
+		expected TimeValue
+	}{
+		{"+1700-02-29T00:00:00Z", TimeValue{Year: 1700, Month: 2, Day: 29}},                                    //nolint:exhaustruct
+		{"+2001-12-31T23:59:58Z", TimeValue{Year: 2001, Month: 12, Day: 31, Hour: 23, Minute: 59, Second: 58}}, //nolint:exhaustruct
+		{"-0001-00-00T00:00:00Z", TimeValue{Year: 0, Month: 1, Day: 1}},                                        //nolint:exhaustruct
+		{"-13798000000000-00-00T00:00:00Z", TimeValue{Year: -13_797_999_999_999, Month: 1, Day: 1}},            //nolint:exhaustruct
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.time, func(t *testing.T) {
+			value, errE := parseTime(test.time)
+			require.NoError(t, errE)
+			assert.Equal(t, test.expected, value)
+		})
+	}
+
+	_, errE := parseTime("+0000-01-01T00:00:00Z")
+	assert.Error(t, errE)
+	_, errE = parseTime("1700-02-29")
+	assert.Error(t, errE)
+}
+
+func TestDecodeEntity(t *testing.T) {
+	entity, errE := decodeEntity([]byte(`{
+		"id": "Q1", "pageid": 1, "ns": 0, "title": "Q1", "modified": "2022-01-01T00:00:00Z", "type": "item", "lastrevid": 1,
+		"claims": {"P569": [{
+			"id": "Q1$1", "type": "statement", "rank": "normal",
+			"mainsnak": {"snaktype": "value", "property": "P569", "datatype": "time", "datavalue": {"type": "time", "value": {
+				"time": "+1700-02-29T00:00:00Z", "timezone": 0, "before": 0, "after": 0, "precision": 11,
+				"calendarmodel": "http://www.wikidata.org/entity/Q1985786"
+			}}}
+		}]}
+	}`))
+	require.NoError(t, errE)
+	assert.Equal(t, TimeValue{Year: 1700, Month: 2, Day: 29, Precision: mediawiki.Day, Calendar: mediawiki.Julian}, entity.Claims["P569"][0].MainSnak.DataValue.Value) //nolint:exhaustruct
+}
+
+func TestDecodeCommonsEntity(t *testing.T) {
+	var entity commonsEntity
+	errE := x.UnmarshalWithoutUnknownFields([]byte(`{
+		"id": "M1", "pageid": 1, "ns": 6, "title": "File:Example.jpg", "modified": "2022-01-01T00:00:00Z", "type": "mediainfo", "lastrevid": 1,
+		"statements": {"P571": [{
+			"id": "M1$1", "type": "statement", "rank": "normal",
+			"mainsnak": {"snaktype": "value", "property": "P571", "datavalue": {"type": "time", "value": {
+				"time": "+1700-02-29T00:00:00Z", "timezone": 0, "before": 0, "after": 0, "precision": 11,
+				"calendarmodel": "http://www.wikidata.org/entity/Q1985786"
+			}}},
+			"references": [{"snaks": {"P813": [{"snaktype": "value", "property": "P813", "datavalue": {"type": "time", "value": {
+				"time": "-0044-03-15T00:00:00Z", "timezone": 0, "before": 0, "after": 0, "precision": 11,
+				"calendarmodel": "http://www.wikidata.org/entity/Q1985786"
+			}}}]}}]
+		}]}
+	}`), &entity)
+	require.NoError(t, errE)
+	claims := entity.toEntity().Claims
+	assert.Equal(t, TimeValue{Year: 1700, Month: 2, Day: 29, Precision: mediawiki.Day, Calendar: mediawiki.Julian}, claims["P571"][0].MainSnak.DataValue.Value)                      //nolint:exhaustruct
+	assert.Equal(t, TimeValue{Year: -43, Month: 3, Day: 15, Precision: mediawiki.Day, Calendar: mediawiki.Julian}, claims["P571"][0].References[0].Snaks["P813"][0].DataValue.Value) //nolint:exhaustruct
+}
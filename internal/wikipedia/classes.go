@@ -0,0 +1,216 @@
+package wikipedia
+
+import (
+	"sync"
+
+	"gitlab.com/tozd/go/errors"
+
+	"gitlab.com/peerdb/search"
+)
+
+const (
+	// Wikidata property "instance of".
+	wikidataInstanceOf = "P31"
+	// Wikidata property "subclass of".
+	wikidataSubclassOf = "P279"
+)
+
+// ClassPropertyIDs returns IDs of properties used by the class hierarchy: Wikidata's
+// "instance of" and "subclass of" properties and standard properties for their closure.
+func ClassPropertyIDs() []search.Identifier {
+	return []search.Identifier{
+		GetWikidataDocumentID(wikidataInstanceOf),
+		GetWikidataDocumentID(wikidataSubclassOf),
+		search.GetStandardPropertyID("INSTANCE_OF_CLASS"),
+		search.GetStandardPropertyID("SUBCLASS_OF_CLASS"),
+	}
+}
+
+type classEdge struct {
+	To         search.DocumentReference
+	Confidence search.Confidence
+}
+
+// ClassReference is a class reached when traversing the class hierarchy.
+type ClassReference struct {
+	Class search.DocumentReference
+	// Confidence is the lowest confidence of claims on the path to the class.
+	Confidence search.Confidence
+	// Depth is the number of subclass relations followed to reach the class.
+	// Direct classes have depth 0.
+	Depth int
+}
+
+// ClassClosure is the transitive closure of classes of a document.
+type ClassClosure struct {
+	// InstanceOf are classes of which the document is an instance.
+	InstanceOf []ClassReference
+	// SubclassOf are classes of which the document is a subclass.
+	SubclassOf []ClassReference
+	// Cycle is true if the document is (transitively) a subclass of itself.
+	Cycle bool
+	// Truncated is true if the depth limit was reached before all classes were visited.
+	Truncated bool
+}
+
+// ClassHierarchy holds Wikidata "instance of" and "subclass of" relations between documents.
+//
+// It is safe for concurrent use.
+type ClassHierarchy struct {
+	mu         sync.RWMutex
+	instanceOf map[search.Identifier][]classEdge
+	subclassOf map[search.Identifier][]classEdge
+}
+
+// NewClassHierarchy returns a new empty class hierarchy.
+func NewClassHierarchy() *ClassHierarchy {
+	return &ClassHierarchy{
+		mu:         sync.RWMutex{},
+		instanceOf: map[search.Identifier][]classEdge{},
+		subclassOf: map[search.Identifier][]classEdge{},
+	}
+}
+
+// AddDocument records active "instance of" and "subclass of" claims of the document.
+func (h *ClassHierarchy) AddDocument(document *search.Document) {
+	if document.Active == nil {
+		return
+	}
+
+	instanceOf := GetWikidataDocumentID(wikidataInstanceOf)
+	subclassOf := GetWikidataDocumentID(wikidataSubclassOf)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, claim := range document.Active.Relation {
+		if claim.Confidence < search.ActiveClaimThreshold || claim.To.ID == "" {
+			continue
+		}
+		edge := classEdge{
+			To:         claim.To,
+			Confidence: claim.Confidence,
+		}
+		switch claim.Prop.ID {
+		case instanceOf:
+			h.instanceOf[document.ID] = append(h.instanceOf[document.ID], edge)
+		case subclassOf:
+			h.subclassOf[document.ID] = append(h.subclassOf[document.ID], edge)
+		}
+	}
+}
+
+// Closure returns all classes of which the document is an instance or a subclass,
+// following at most maxDepth subclass relations from its direct classes.
+func (h *ClassHierarchy) Closure(id search.Identifier, maxDepth int) ClassClosure {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	instanceOf, _, instanceTruncated := h.traverse(id, h.instanceOf[id], maxDepth)
+	subclassOf, cycle, subclassTruncated := h.traverse(id, h.subclassOf[id], maxDepth)
+
+	return ClassClosure{
+		InstanceOf: instanceOf,
+		SubclassOf: subclassOf,
+		Cycle:      cycle,
+		Truncated:  instanceTruncated || subclassTruncated,
+	}
+}
+
+// traverse does a breadth-first traversal of subclass relations starting with edges.
+// Every class is returned only once, at its smallest depth. It also returns if
+// the document with the ID has been reached (a cycle) and if the depth limit was reached.
+// The caller must hold the lock.
+func (h *ClassHierarchy) traverse(id search.Identifier, edges []classEdge, maxDepth int) ([]ClassReference, bool, bool) {
+	result := []ClassReference{}
+	cycle := false
+	truncated := false
+	visited := map[search.Identifier]int{}
+
+	queue := []ClassReference{}
+	for _, edge := range edges {
+		queue = append(queue, ClassReference{
+			Class:      edge.To,
+			Confidence: edge.Confidence,
+			Depth:      0,
+		})
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if current.Class.ID == id {
+			cycle = true
+			continue
+		}
+		if i, ok := visited[current.Class.ID]; ok {
+			// We keep the highest confidence among paths of the same (smallest) depth.
+			if result[i].Depth == current.Depth && result[i].Confidence < current.Confidence {
+				result[i].Confidence = current.Confidence
+			}
+			continue
+		}
+		visited[current.Class.ID] = len(result)
+		result = append(result, current)
+
+		superclasses := h.subclassOf[current.Class.ID]
+		if len(superclasses) > 0 && current.Depth >= maxDepth {
+			truncated = true
+			continue
+		}
+		for _, edge := range superclasses {
+			confidence := current.Confidence
+			if edge.Confidence < confidence {
+				confidence = edge.Confidence
+			}
+			queue = append(queue, ClassReference{
+				Class:      edge.To,
+				Confidence: confidence,
+				Depth:      current.Depth + 1,
+			})
+		}
+	}
+
+	return result, cycle, truncated
+}
+
+// UpdateClassClosure replaces claims for the class closure of the document with claims
+// for the provided closure, using INSTANCE_OF_CLASS and SUBCLASS_OF_CLASS properties.
+func UpdateClassClosure(document *search.Document, closure ClassClosure) errors.E {
+	document.Remove(search.GetStandardPropertyID("INSTANCE_OF_CLASS"))
+	document.Remove(search.GetStandardPropertyID("SUBCLASS_OF_CLASS"))
+
+	for _, c := range []struct {
+		Mnemonic string
+		Classes  []ClassReference
+	}{
+		{"INSTANCE_OF_CLASS", closure.InstanceOf},
+		{"SUBCLASS_OF_CLASS", closure.SubclassOf},
+	} {
+		prop, errE := search.StandardProperties.Reference(search.Mnemonic(c.Mnemonic))
+		if errE != nil {
+			return errE
+		}
+		for _, class := range c.Classes {
+			errE := document.Add(&search.RelationClaim{
+				CoreClaim: search.CoreClaim{
+					ID:         search.GetID(NameSpaceWikidata, document.ID, c.Mnemonic, class.Class.ID),
+					Confidence: class.Confidence,
+				},
+				Prop: prop,
+				To: search.DocumentReference{
+					ID:     class.Class.ID,
+					Name:   class.Class.Name,
+					Score:  class.Class.Score,
+					Scores: class.Class.Scores,
+				},
+			})
+			if errE != nil {
+				return errE
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package wikipedia
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/peerdb/search"
+)
+
+func classDocument(id string, instanceOf []string, subclassOf []string) *search.Document {
+	document := &search.Document{
+		CoreDocument: search.CoreDocument{
+			ID:    GetWikidataDocumentID(id),
+			Name:  search.Name{"en": id},
+			Score: 0.5,
+		},
+		Active: &search.ClaimTypes{},
+	}
+	for prop, classes := range map[string][]string{wikidataInstanceOf: instanceOf, wikidataSubclassOf: subclassOf} {
+		for _, class := range classes {
+			document.Active.Relation = append(document.Active.Relation, search.RelationClaim{
+				CoreClaim: search.CoreClaim{
+					ID:         search.GetID(NameSpaceWikidata, id, prop, class),
+					Confidence: HighConfidence,
+				},
+				Prop: search.DocumentReference{
+					ID:    GetWikidataDocumentID(prop),
+					Name:  search.Name{"en": prop},
+					Score: 0.5,
+				},
+				To: search.DocumentReference{
+					ID:    GetWikidataDocumentID(class),
+					Name:  search.Name{"en": class},
+					Score: 0.5,
+				},
+			})
+		}
+	}
+	return document
+}
+
+func classIDs(classes []ClassReference) []search.Identifier {
+	ids := []search.Identifier{}
+	for _, class := range classes {
+		ids = append(ids, class.Class.ID)
+	}
+	return ids
+}
+
+func TestClassHierarchy(t *testing.T) {
+	// Q1 (a tower) is an instance of Q2 (tower), which is a subclass of Q3 (building),
+	// which is a subclass of Q4 (structure). Q4 and Q5 form a cycle.
+	documents := []*search.Document{
+		classDocument("Q1", []string{"Q2"}, nil),
+		classDocument("Q2", nil, []string{"Q3"}),
+		classDocument("Q3", nil, []string{"Q4"}),
+		classDocument("Q4", nil, []string{"Q5"}),
+		classDocument("Q5", nil, []string{"Q4"}),
+	}
+	h := NewClassHierarchy()
+	for _, document := range documents {
+		h.AddDocument(document)
+	}
+
+	closure := h.Closure(GetWikidataDocumentID("Q1"), 10)
+	assert.Equal(t, []search.Identifier{
+		GetWikidataDocumentID("Q2"), GetWikidataDocumentID("Q3"), GetWikidataDocumentID("Q4"), GetWikidataDocumentID("Q5"),
+	}, classIDs(closure.InstanceOf))
+	assert.Empty(t, closure.SubclassOf)
+	assert.False(t, closure.Cycle)
+	assert.False(t, closure.Truncated)
+	assert.Equal(t, 3, closure.InstanceOf[3].Depth)
+
+	closure = h.Closure(GetWikidataDocumentID("Q4"), 10)
+	assert.Empty(t, closure.InstanceOf)
+	assert.Equal(t, []search.Identifier{GetWikidataDocumentID("Q5")}, classIDs(closure.SubclassOf))
+	assert.True(t, closure.Cycle)
+
+	closure = h.Closure(GetWikidataDocumentID("Q1"), 1)
+	assert.Equal(t, []search.Identifier{GetWikidataDocumentID("Q2"), GetWikidataDocumentID("Q3")}, classIDs(closure.InstanceOf))
+	assert.True(t, closure.Truncated)
+
+	document := documents[0]
+	errE := UpdateClassClosure(document, h.Closure(document.ID, 10))
+	require.NoError(t, errE)
+	claims := document.Get(search.GetStandardPropertyID("INSTANCE_OF_CLASS"))
+	assert.Len(t, claims, 4)
+
+	// Updating again replaces existing claims.
+	errE = UpdateClassClosure(document, h.Closure(document.ID, 0))
+	require.NoError(t, errE)
+	claims = document.Get(search.GetStandardPropertyID("INSTANCE_OF_CLASS"))
+	require.Len(t, claims, 1)
+	claim, ok := claims[0].(*search.RelationClaim)
+	require.True(t, ok)
+	assert.Equal(t, GetWikidataDocumentID("Q2"), claim.To.ID)
+	// Original claims are kept.
+	assert.Len(t, document.Get(GetWikidataDocumentID(wikidataInstanceOf)), 1)
+}
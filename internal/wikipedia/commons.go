@@ -14,13 +14,13 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/hashicorp/go-retryablehttp"
-	"github.com/olivere/elastic/v7"
 	"github.com/rs/zerolog"
 	"gitlab.com/tozd/go/errors"
 	"gitlab.com/tozd/go/mediawiki"
 	"gitlab.com/tozd/go/x"
 
 	"gitlab.com/peerdb/search"
+	"gitlab.com/peerdb/search/identifier"
 )
 
 const (
@@ -30,7 +30,7 @@ const (
 )
 
 var (
-	NameSpaceWikimediaCommonsFile = uuid.MustParse("31974ea8-ab0c-466d-9aaa-e1bf3c959edc")
+	NameSpaceWikimediaCommonsFile = identifier.RegisterNamespace("WikimediaCommonsFile", uuid.MustParse("31974ea8-ab0c-466d-9aaa-e1bf3c959edc"))
 
 	// We have a list of media types we support primarily to make sure we use consistent media
 	// types and to know if we have to map an unknown one to a known one, or to add a new one.
@@ -414,6 +414,14 @@ func convertImage(
 
 	prefix := GetMediawikiFilePrefix(image.Name)
 
+	props, err := getProperties(
+		mnemonicPrefix+"_FILE_NAME", mnemonicPrefix+"_FILE", "FILE_URL", "IS", "FILE", "MEDIA_TYPE", "MEDIAWIKI_MEDIA_TYPE",
+		"SIZE", "PAGE_COUNT", "LENGTH", "LIST", "ORDER", "PREVIEW_URL", "WIDTH", "HEIGHT",
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	document := &search.Document{
 		CoreDocument: search.CoreDocument{
 			ID: id,
@@ -429,7 +437,7 @@ func convertImage(
 						ID:         search.GetID(namespace, image.Name, mnemonicPrefix+"_FILE_NAME", 0),
 						Confidence: HighConfidence,
 					},
-					Prop:       search.GetStandardPropertyReference(mnemonicPrefix + "_FILE_NAME"),
+					Prop:       props[mnemonicPrefix+"_FILE_NAME"],
 					Identifier: image.Name,
 				},
 			},
@@ -439,7 +447,7 @@ func convertImage(
 						ID:         search.GetID(namespace, image.Name, mnemonicPrefix+"_FILE", 0),
 						Confidence: HighConfidence,
 					},
-					Prop: search.GetStandardPropertyReference(mnemonicPrefix + "_FILE"),
+					Prop: props[mnemonicPrefix+"_FILE"],
 					IRI:  fmt.Sprintf("https://en.wikipedia.org/wiki/File:%s", image.Name),
 				},
 				{
@@ -447,7 +455,7 @@ func convertImage(
 						ID:         search.GetID(namespace, image.Name, "FILE_URL", 0),
 						Confidence: HighConfidence,
 					},
-					Prop: search.GetStandardPropertyReference("FILE_URL"),
+					Prop: props["FILE_URL"],
 					IRI:  fmt.Sprintf("https://upload.wikimedia.org/wikipedia/%s/%s/%s", fileSite, prefix, image.Name),
 				},
 			},
@@ -457,8 +465,8 @@ func convertImage(
 						ID:         search.GetID(namespace, image.Name, "IS", 0, "FILE", 0),
 						Confidence: HighConfidence,
 					},
-					Prop: search.GetStandardPropertyReference("IS"),
-					To:   search.GetStandardPropertyReference("FILE"),
+					Prop: props["IS"],
+					To:   props["FILE"],
 				},
 			},
 		},
@@ -496,12 +504,12 @@ func convertImage(
 		return nil, errors.WithStack(errors.BaseWrapf(SkippedError, `unsupported Mediawiki media type "%s"`, image.MediaType))
 	}
 
-	err := document.Add(&search.StringClaim{
+	err = document.Add(&search.StringClaim{
 		CoreClaim: search.CoreClaim{
 			ID:         search.GetID(namespace, image.Name, "MEDIA_TYPE", 0),
 			Confidence: HighConfidence,
 		},
-		Prop:   search.GetStandardPropertyReference("MEDIA_TYPE"),
+		Prop:   props["MEDIA_TYPE"],
 		String: mediaType,
 	})
 	if err != nil {
@@ -512,7 +520,7 @@ func convertImage(
 			ID:         search.GetID(namespace, image.Name, "MEDIAWIKI_MEDIA_TYPE", 0),
 			Confidence: HighConfidence,
 		},
-		Prop: search.GetStandardPropertyReference("MEDIAWIKI_MEDIA_TYPE"),
+		Prop: props["MEDIAWIKI_MEDIA_TYPE"],
 		Enum: []string{strings.ToLower(image.MediaType)},
 	})
 	if err != nil {
@@ -528,7 +536,7 @@ func convertImage(
 			ID:         search.GetID(namespace, image.Name, "SIZE", 0),
 			Confidence: HighConfidence,
 		},
-		Prop:   search.GetStandardPropertyReference("SIZE"),
+		Prop:   props["SIZE"],
 		Amount: float64(image.Size),
 		Unit:   search.AmountUnitByte,
 	})
@@ -552,7 +560,7 @@ func convertImage(
 					ID:         search.GetID(namespace, image.Name, "PAGE_COUNT", 0),
 					Confidence: MediumConfidence,
 				},
-				Prop:   search.GetStandardPropertyReference("PAGE_COUNT"),
+				Prop:   props["PAGE_COUNT"],
 				Amount: float64(pageCount),
 				Unit:   search.AmountUnitNone,
 			})
@@ -577,7 +585,7 @@ func convertImage(
 					ID:         search.GetID(namespace, image.Name, "LENGTH", 0),
 					Confidence: MediumConfidence,
 				},
-				Prop:   search.GetStandardPropertyReference("LENGTH"),
+				Prop:   props["LENGTH"],
 				Amount: duration,
 				Unit:   search.AmountUnitSecond,
 			})
@@ -676,7 +684,7 @@ func convertImage(
 									ID:         search.GetID(namespace, image.Name, "PREVIEW_URL", i, "LIST", 0),
 									Confidence: HighConfidence,
 								},
-								Prop:       search.GetStandardPropertyReference("LIST"),
+								Prop:       props["LIST"],
 								Identifier: previewsList,
 							},
 						},
@@ -686,14 +694,14 @@ func convertImage(
 									ID:         search.GetID(namespace, image.Name, "PREVIEW_URL", i, "ORDER", 0),
 									Confidence: HighConfidence,
 								},
-								Prop:   search.GetStandardPropertyReference("ORDER"),
+								Prop:   props["ORDER"],
 								Amount: float64(i),
 								Unit:   search.AmountUnitNone,
 							},
 						},
 					},
 				},
-				Prop: search.GetStandardPropertyReference("PREVIEW_URL"),
+				Prop: props["PREVIEW_URL"],
 				IRI:  preview,
 			})
 			if err != nil {
@@ -709,7 +717,7 @@ func convertImage(
 				ID:         search.GetID(namespace, image.Name, "WIDTH", 0),
 				Confidence: MediumConfidence,
 			},
-			Prop:   search.GetStandardPropertyReference("WIDTH"),
+			Prop:   props["WIDTH"],
 			Amount: float64(image.Width),
 			Unit:   search.AmountUnitPixel,
 		})
@@ -721,7 +729,7 @@ func convertImage(
 				ID:         search.GetID(namespace, image.Name, "HEIGHT", 0),
 				Confidence: MediumConfidence,
 			},
-			Prop:   search.GetStandardPropertyReference("HEIGHT"),
+			Prop:   props["HEIGHT"],
 			Amount: float64(image.Height),
 			Unit:   search.AmountUnitPixel,
 		})
@@ -733,12 +741,14 @@ func convertImage(
 	return document, err
 }
 
-func GetWikimediaCommonsFile(ctx context.Context, index string, esClient *elastic.Client, name string) (*search.Document, *elastic.SearchHit, errors.E) {
-	document, hit, err := getDocumentFromESByProp(ctx, index, esClient, "WIKIMEDIA_COMMONS_FILE_NAME", name)
+// GetWikimediaCommonsFile returns the document of the Wikimedia Commons file with the name together with
+// its version, which can be used to update the document only if it has not changed in the meantime.
+func GetWikimediaCommonsFile(ctx context.Context, backend search.Backend, name string) (*search.Document, search.Version, errors.E) {
+	document, version, err := getDocumentByProp(ctx, backend, "WIKIMEDIA_COMMONS_FILE_NAME", name)
 	if err != nil {
 		errors.Details(err)["file"] = name
-		return nil, nil, err
+		return nil, "", err
 	}
 
-	return document, hit, nil
+	return document, version, nil
 }
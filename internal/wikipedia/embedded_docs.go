@@ -7,7 +7,6 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/olivere/elastic/v7"
 	"github.com/rs/zerolog"
 	"gitlab.com/tozd/go/errors"
 	"gitlab.com/tozd/go/x"
@@ -20,11 +19,10 @@ var referenceNotFoundError = errors.Base("document reference to a nonexistent do
 type updateEmbeddedDocumentsVisitor struct {
 	Context                      context.Context
 	Log                          zerolog.Logger
-	Index                        string
+	Backend                      search.Backend
 	Cache                        *Cache
 	SkippedWikidataEntities      *sync.Map
 	SkippedWikimediaCommonsFiles *sync.Map
-	ESClient                     *elastic.Client
 	Changed                      int
 	DocumentID                   search.Identifier
 	EntityIDs                    []string
@@ -149,7 +147,7 @@ func (v *updateEmbeddedDocumentsVisitor) getDocumentByProp(property, title strin
 		return maybeDocument.(*search.Document), nil
 	}
 
-	document, _, err := getDocumentFromESByProp(v.Context, v.Index, v.ESClient, property, title)
+	document, _, err := getDocumentByProp(v.Context, v.Backend, property, title)
 	if errors.Is(err, NotFoundError) {
 		v.Cache.Add(title, nil)
 		return nil, err
@@ -174,7 +172,7 @@ func (v *updateEmbeddedDocumentsVisitor) getDocumentByID(id search.Identifier) (
 		return maybeDocument.(*search.Document), nil
 	}
 
-	document, _, err := getDocumentFromESByID(v.Context, v.Index, v.ESClient, id)
+	document, _, err := getDocument(v.Context, v.Backend, id)
 	if errors.Is(err, NotFoundError) {
 		v.Cache.Add(id, nil)
 		return nil, err
@@ -530,11 +528,12 @@ func (v *updateEmbeddedDocumentsVisitor) VisitFile(claim *search.FileClaim) (sea
 			v.Changed++
 		}
 
-		// TODO: First extract individual lists, then sort each least by order, and then concatenate lists.
 		var previews []string
-		for _, cc := range fileDocument.Get(search.GetStandardPropertyID("PREVIEW_URL")) {
-			if c, ok := cc.(*search.ReferenceClaim); ok {
-				previews = append(previews, c.IRI)
+		for _, list := range fileDocument.Lists(search.GetStandardPropertyID("PREVIEW_URL")) {
+			for _, cc := range list.Claims() {
+				if c, ok := cc.(*search.ReferenceClaim); ok {
+					previews = append(previews, c.IRI)
+				}
 			}
 		}
 
@@ -548,7 +547,7 @@ func (v *updateEmbeddedDocumentsVisitor) VisitFile(claim *search.FileClaim) (sea
 }
 
 func UpdateEmbeddedDocuments(
-	ctx context.Context, index string, log zerolog.Logger, esClient *elastic.Client, cache *Cache,
+	ctx context.Context, log zerolog.Logger, backend search.Backend, cache *Cache,
 	skippedWikidataEntities *sync.Map, skippedWikimediaCommonsFiles *sync.Map, document *search.Document,
 ) (bool, errors.E) {
 	// We try to obtain unhashed document IDs to use in logging.
@@ -577,11 +576,10 @@ func UpdateEmbeddedDocuments(
 	v := updateEmbeddedDocumentsVisitor{
 		Context:                      ctx,
 		Log:                          log,
-		Index:                        index,
+		Backend:                      backend,
 		Cache:                        cache,
 		SkippedWikidataEntities:      skippedWikidataEntities,
 		SkippedWikimediaCommonsFiles: skippedWikimediaCommonsFiles,
-		ESClient:                     esClient,
 		Changed:                      0,
 		DocumentID:                   document.ID,
 		EntityIDs:                    entityIDs,
@@ -0,0 +1,220 @@
+package wikipedia
+
+import (
+	"context"
+	"encoding/json"
+
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/mediawiki"
+	"gitlab.com/tozd/go/x"
+)
+
+// dataValue is mediawiki.DataValue, but time values are TimeValue
+// instead of mediawiki.TimeValue.
+type dataValue mediawiki.DataValue
+
+func (v *dataValue) UnmarshalJSON(b []byte) error {
+	var t struct {
+		Type  string `json:"type"`
+		Error string `json:"error"`
+	}
+	err := json.Unmarshal(b, &t)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if t.Type != "time" || t.Error != "" {
+		return (*mediawiki.DataValue)(v).UnmarshalJSON(b)
+	}
+	var value struct {
+		Type  string `json:"type"`
+		Value struct {
+			Time      string                  `json:"time"`
+			Precision mediawiki.TimePrecision `json:"precision"`
+			Calendar  mediawiki.CalendarModel `json:"calendarmodel"`
+			// Defined and declared not used, but sometimes still set. We ignore it.
+			Timezone int64 `json:"timezone"`
+			// Defined and declared not used, but sometimes still set. We ignore it.
+			Before int64 `json:"before"`
+			// Defined and declared not used, but sometimes still set. We ignore it.
+			After int64 `json:"after"`
+		} `json:"value"`
+	}
+	errE := x.UnmarshalWithoutUnknownFields(b, &value)
+	if errE != nil {
+		return errE
+	}
+	timeValue, errE := parseTime(value.Value.Time)
+	if errE != nil {
+		v.Value = mediawiki.ErrorValue(errE.Error())
+		return nil
+	}
+	timeValue.Precision = value.Value.Precision
+	timeValue.Calendar = value.Value.Calendar
+	v.Value = timeValue
+	return nil
+}
+
+// snak is mediawiki.Snak with dataValue.
+type snak struct {
+	Hash      string              `json:"hash,omitempty"`
+	SnakType  mediawiki.SnakType  `json:"snaktype"`
+	Property  string              `json:"property"`
+	DataType  *mediawiki.DataType `json:"datatype,omitempty"`
+	DataValue *dataValue          `json:"datavalue,omitempty"`
+}
+
+func (s snak) toSnak() mediawiki.Snak {
+	return mediawiki.Snak{
+		Hash:      s.Hash,
+		SnakType:  s.SnakType,
+		Property:  s.Property,
+		DataType:  s.DataType,
+		DataValue: (*mediawiki.DataValue)(s.DataValue),
+	}
+}
+
+func toSnaks(snaks map[string][]snak) map[string][]mediawiki.Snak {
+	if snaks == nil {
+		return nil
+	}
+	result := make(map[string][]mediawiki.Snak, len(snaks))
+	for prop, ss := range snaks {
+		result[prop] = make([]mediawiki.Snak, len(ss))
+		for i, s := range ss {
+			result[prop][i] = s.toSnak()
+		}
+	}
+	return result
+}
+
+// reference is mediawiki.Reference with snaks.
+type reference struct {
+	Hash       string            `json:"hash,omitempty"`
+	Snaks      map[string][]snak `json:"snaks,omitempty"`
+	SnaksOrder []string          `json:"snaks-order,omitempty"`
+}
+
+// statement is mediawiki.Statement with snaks.
+type statement struct {
+	ID              string                  `json:"id"`
+	Type            mediawiki.StatementType `json:"type"`
+	MainSnak        snak                    `json:"mainsnak"`
+	Rank            mediawiki.StatementRank `json:"rank"`
+	Qualifiers      map[string][]snak       `json:"qualifiers,omitempty"`
+	QualifiersOrder []string                `json:"qualifiers-order,omitempty"`
+	References      []reference             `json:"references,omitempty"`
+}
+
+func toClaims(claims map[string][]statement) map[string][]mediawiki.Statement {
+	if claims == nil {
+		return nil
+	}
+	result := make(map[string][]mediawiki.Statement, len(claims))
+	for prop, statements := range claims {
+		result[prop] = make([]mediawiki.Statement, len(statements))
+		for i, s := range statements {
+			var references []mediawiki.Reference
+			if s.References != nil {
+				references = make([]mediawiki.Reference, len(s.References))
+				for j, r := range s.References {
+					references[j] = mediawiki.Reference{
+						Hash:       r.Hash,
+						Snaks:      toSnaks(r.Snaks),
+						SnaksOrder: r.SnaksOrder,
+					}
+				}
+			}
+			result[prop][i] = mediawiki.Statement{
+				ID:              s.ID,
+				Type:            s.Type,
+				MainSnak:        s.MainSnak.toSnak(),
+				Rank:            s.Rank,
+				Qualifiers:      toSnaks(s.Qualifiers),
+				QualifiersOrder: s.QualifiersOrder,
+				References:      references,
+			}
+		}
+	}
+	return result
+}
+
+// entity is a Wikidata entities JSON dump entity. Its claims
+// shadow claims of the embedded mediawiki.Entity when decoding.
+type entity struct {
+	mediawiki.Entity
+	Claims map[string][]statement `json:"claims,omitempty"`
+}
+
+func (e entity) toEntity() mediawiki.Entity {
+	en := e.Entity
+	en.Claims = toClaims(e.Claims)
+	return en
+}
+
+// commonsEntity is a Wikimedia Commons entities JSON dump entity.
+// The only difference is that its claims are named "statements" in the JSON.
+type commonsEntity struct {
+	mediawiki.Entity
+	Claims map[string][]statement `json:"statements,omitempty"`
+}
+
+func (e commonsEntity) toEntity() mediawiki.Entity {
+	en := e.Entity
+	en.Claims = toClaims(e.Claims)
+	return en
+}
+
+// decodeEntity decodes JSON of a Wikidata entity. Time values in the entity
+// are TimeValue instead of mediawiki.TimeValue.
+func decodeEntity(data []byte) (mediawiki.Entity, errors.E) {
+	var e entity
+	errE := x.UnmarshalWithoutUnknownFields(data, &e)
+	if errE != nil {
+		return mediawiki.Entity{}, errE //nolint:exhaustruct
+	}
+	return e.toEntity(), nil
+}
+
+// ProcessWikidataDump is like mediawiki.ProcessWikidataDump, but time values
+// in entities are TimeValue instead of mediawiki.TimeValue.
+func ProcessWikidataDump(
+	ctx context.Context, config *mediawiki.ProcessDumpConfig,
+	processEntity func(context.Context, mediawiki.Entity) errors.E,
+) errors.E {
+	return mediawiki.Process(ctx, &mediawiki.ProcessConfig[entity]{
+		URL:                    config.URL,
+		Path:                   config.Path,
+		Client:                 config.Client,
+		DecompressionThreads:   config.DecompressionThreads,
+		DecodingThreads:        config.DecodingThreads,
+		ItemsProcessingThreads: config.ItemsProcessingThreads,
+		Process: func(ctx context.Context, e entity) errors.E {
+			return processEntity(ctx, e.toEntity())
+		},
+		Progress:    config.Progress,
+		FileType:    mediawiki.JSONArray,
+		Compression: mediawiki.BZIP2,
+	})
+}
+
+// ProcessCommonsEntitiesDump is like mediawiki.ProcessCommonsEntitiesDump, but time values
+// in entities are TimeValue instead of mediawiki.TimeValue.
+func ProcessCommonsEntitiesDump(
+	ctx context.Context, config *mediawiki.ProcessDumpConfig,
+	processEntity func(context.Context, mediawiki.Entity) errors.E,
+) errors.E {
+	return mediawiki.Process(ctx, &mediawiki.ProcessConfig[commonsEntity]{
+		URL:                    config.URL,
+		Path:                   config.Path,
+		Client:                 config.Client,
+		DecompressionThreads:   config.DecompressionThreads,
+		DecodingThreads:        config.DecodingThreads,
+		ItemsProcessingThreads: config.ItemsProcessingThreads,
+		Process: func(ctx context.Context, e commonsEntity) errors.E {
+			return processEntity(ctx, e.toEntity())
+		},
+		Progress:    config.Progress,
+		FileType:    mediawiki.JSONArray,
+		Compression: mediawiki.BZIP2,
+	})
+}
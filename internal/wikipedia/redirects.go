@@ -0,0 +1,73 @@
+package wikipedia
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gitlab.com/tozd/go/errors"
+
+	"gitlab.com/peerdb/search"
+)
+
+// ConvertWikidataRedirects adds ALIAS claims to the document of the Wikidata entity for all
+// entities redirecting to it. Wikidata entities become redirects when they are merged into
+// another entity, so requests for documents of merged entities are redirected to the
+// document of the entity they were merged into.
+//
+// Wikidata entities dumps do not contain redirects, so they are obtained through the API,
+// see ListAllRedirectTargets.
+func ConvertWikidataRedirects(log zerolog.Logger, id string, page AllPagesPage, document *search.Document) errors.E {
+	for _, redirect := range page.Redirects {
+		// Only items are merged.
+		if !strings.HasPrefix(redirect.Title, "Q") {
+			continue
+		}
+		if redirect.Title == id {
+			continue
+		}
+
+		claimID := search.GetID(NameSpaceWikidata, id, "ALIAS", redirect.Title)
+		if document.GetByID(claimID) != nil {
+			continue
+		}
+		claim, err := search.NewAliasClaim(claimID, GetWikidataDocumentID(redirect.Title), HighConfidence)
+		if err == nil {
+			err = document.Add(claim)
+		}
+		if err != nil {
+			log.Error().Str("doc", string(document.ID)).Str("entity", id).Str("claim", string(claimID)).Str("redirect", redirect.Title).
+				Err(err).Fields(errors.AllDetails(err)).Msg("claim cannot be added")
+		}
+	}
+	return nil
+}
+
+// convertFileAlias adds an ALIAS claim to the file document for the redirect from another
+// file, so that requests for documents of redirected (e.g., renamed) files are redirected
+// to the file document.
+func convertFileAlias(log zerolog.Logger, namespace uuid.UUID, filename, redirect string, document *search.Document) {
+	if !strings.HasPrefix(redirect, "File:") {
+		return
+	}
+	// We normalize the name in the same way file names are normalized when documents are made.
+	alias := strings.TrimPrefix(redirect, "File:")
+	alias = strings.ReplaceAll(alias, " ", "_")
+	alias = FirstUpperCase(alias)
+	if alias == filename {
+		return
+	}
+
+	claimID := search.GetID(namespace, filename, "ALIAS", alias)
+	if document.GetByID(claimID) != nil {
+		return
+	}
+	claim, err := search.NewAliasClaim(claimID, search.GetID(namespace, alias), HighConfidence)
+	if err == nil {
+		err = document.Add(claim)
+	}
+	if err != nil {
+		log.Error().Str("doc", string(document.ID)).Str("file", filename).Str("claim", string(claimID)).Str("redirect", redirect).
+			Err(err).Fields(errors.AllDetails(err)).Msg("claim cannot be added")
+	}
+}
@@ -0,0 +1,68 @@
+package wikipedia
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/peerdb/search"
+)
+
+func TestConvertPageRedirectsFileAliases(t *testing.T) {
+	filename := "New_name.jpg"
+	document := &search.Document{
+		CoreDocument: search.CoreDocument{
+			ID: search.GetID(NameSpaceWikimediaCommonsFile, filename),
+		},
+	}
+	page := AllPagesPage{
+		Title: "File:New name.jpg",
+		Redirects: []PageReference{
+			{Title: "File:old name.jpg"},
+			{Title: "File:New name.jpg"},
+			{Title: "Category:Not a file"},
+		},
+	}
+
+	errE := ConvertPageRedirects(zerolog.Nop(), NameSpaceWikimediaCommonsFile, filename, page, document)
+	require.NoError(t, errE)
+	// Converting again does not add duplicate claims.
+	errE = ConvertPageRedirects(zerolog.Nop(), NameSpaceWikimediaCommonsFile, filename, page, document)
+	require.NoError(t, errE)
+
+	claims := document.Get(search.GetStandardPropertyID("ALIAS"))
+	require.Len(t, claims, 1)
+	claim, ok := claims[0].(*search.IdentifierClaim)
+	require.True(t, ok)
+	assert.Equal(t, string(search.GetID(NameSpaceWikimediaCommonsFile, "Old_name.jpg")), claim.Identifier)
+}
+
+func TestConvertWikidataRedirects(t *testing.T) {
+	document := &search.Document{
+		CoreDocument: search.CoreDocument{
+			ID: GetWikidataDocumentID("Q1"),
+		},
+	}
+	page := AllPagesPage{
+		Title: "Q1",
+		Redirects: []PageReference{
+			{Title: "Q2"},
+			{Title: "Q1"},
+			{Title: "Property:P1"},
+		},
+	}
+
+	errE := ConvertWikidataRedirects(zerolog.Nop(), "Q1", page, document)
+	require.NoError(t, errE)
+	// Converting again does not add duplicate claims.
+	errE = ConvertWikidataRedirects(zerolog.Nop(), "Q1", page, document)
+	require.NoError(t, errE)
+
+	claims := document.Get(search.GetStandardPropertyID("ALIAS"))
+	require.Len(t, claims, 1)
+	claim, ok := claims[0].(*search.IdentifierClaim)
+	require.True(t, ok)
+	assert.Equal(t, string(GetWikidataDocumentID("Q2")), claim.Identifier)
+}
@@ -0,0 +1,79 @@
+package wikipedia_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/tozd/go/errors"
+
+	"gitlab.com/peerdb/search"
+	"gitlab.com/peerdb/search/internal/wikipedia"
+	"gitlab.com/peerdb/search/schema"
+)
+
+func TestConvertedDocumentsValidate(t *testing.T) {
+	for _, conf := range []struct {
+		Dir     string
+		Convert func(id, html string, document *search.Document) errors.E
+	}{
+		{
+			"article",
+			wikipedia.ConvertWikipediaArticle,
+		},
+		{
+			"file",
+			func(id, html string, document *search.Document) errors.E {
+				return wikipedia.ConvertFileDescription(wikipedia.NameSpaceWikimediaCommonsFile, id, "FILE", html, document)
+			},
+		},
+		{
+			"category",
+			func(id, html string, document *search.Document) errors.E {
+				return wikipedia.ConvertCategoryDescription(id, "CATEGORY", html, document)
+			},
+		},
+		{
+			"template",
+			func(id, html string, document *search.Document) errors.E {
+				return wikipedia.ConvertTemplateDescription(id, "TEMPLATE", html, document)
+			},
+		},
+	} {
+		conf := conf
+		t.Run(conf.Dir, func(t *testing.T) {
+			entries, err := content.ReadDir("testdata/" + conf.Dir)
+			require.NoError(t, err)
+			for _, entry := range entries {
+				if entry.IsDir() {
+					continue
+				}
+				if !strings.HasSuffix(entry.Name(), "_in.html") {
+					continue
+				}
+				base := strings.TrimSuffix(entry.Name(), "_in.html")
+				entry := entry
+				t.Run(base, func(t *testing.T) {
+					input, err := content.ReadFile(filepath.Join("testdata", conf.Dir, entry.Name()))
+					require.NoError(t, err)
+					document := &search.Document{
+						CoreDocument: search.CoreDocument{
+							ID:    wikipedia.GetWikidataDocumentID("Q1"),
+							Name:  search.Name{"en": base},
+							Score: 0.5,
+						},
+					}
+					errE := conf.Convert("Q1", string(input), document)
+					require.NoError(t, errE)
+					data, err := json.Marshal(document)
+					require.NoError(t, err)
+					errE = schema.Validate(data)
+					assert.NoError(t, errE, "% -+#.1v", errE)
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package wikipedia
+
+import (
+	"gitlab.com/peerdb/search"
+)
+
+// unitConversion describes how to convert an amount in a Wikidata unit
+// to an amount in the corresponding standard (SI) unit: value * Factor + Offset.
+type unitConversion struct {
+	Unit   search.AmountUnit
+	Factor float64
+	Offset float64
+}
+
+// wikidataUnits maps Wikidata unit items to standard units.
+// Only common units are listed here. Amounts in other units are stored as-is
+// with a UNIT meta claim and a custom unit.
+// TODO: Populate automatically from "conversion to SI unit" (P2370) Wikidata statements.
+var wikidataUnits = map[string]unitConversion{ //nolint:gomnd
+	// Length.
+	"Q11573":  {search.AmountUnitMetre, 1, 0},                // metre
+	"Q828224": {search.AmountUnitMetre, 1e3, 0},              // kilometre
+	"Q174728": {search.AmountUnitMetre, 1e-2, 0},             // centimetre
+	"Q174789": {search.AmountUnitMetre, 1e-3, 0},             // millimetre
+	"Q253276": {search.AmountUnitMetre, 1609.344, 0},         // mile
+	"Q3710":   {search.AmountUnitMetre, 0.3048, 0},           // foot
+	"Q218593": {search.AmountUnitMetre, 0.0254, 0},           // inch
+	"Q482798": {search.AmountUnitMetre, 0.9144, 0},           // yard
+	"Q93318":  {search.AmountUnitMetre, 1852, 0},             // nautical mile
+	"Q1811":   {search.AmountUnitMetre, 149597870700, 0},     // astronomical unit
+	"Q531":    {search.AmountUnitMetre, 9460730472580800, 0}, // light-year
+
+	// Mass.
+	"Q11570":  {search.AmountUnitKilogram, 1, 0},              // kilogram
+	"Q41803":  {search.AmountUnitKilogram, 1e-3, 0},           // gram
+	"Q191118": {search.AmountUnitKilogram, 1e3, 0},            // tonne
+	"Q100995": {search.AmountUnitKilogram, 0.45359237, 0},     // pound
+	"Q48013":  {search.AmountUnitKilogram, 0.028349523125, 0}, // ounce
+
+	// Time.
+	"Q11574":   {search.AmountUnitSecond, 1, 0},        // second
+	"Q723733":  {search.AmountUnitSecond, 1e-3, 0},     // millisecond
+	"Q7727":    {search.AmountUnitSecond, 60, 0},       // minute
+	"Q25235":   {search.AmountUnitSecond, 3600, 0},     // hour
+	"Q573":     {search.AmountUnitSecond, 86400, 0},    // day
+	"Q23387":   {search.AmountUnitSecond, 604800, 0},   // week
+	"Q577":     {search.AmountUnitSecond, 31557600, 0}, // year (Julian year)
+	"Q1092296": {search.AmountUnitSecond, 31557600, 0}, // annum (Julian year)
+
+	// Temperature.
+	"Q25267": {search.AmountUnitCelsius, 1, 0},                    // degree Celsius
+	"Q11579": {search.AmountUnitCelsius, 1, -273.15},              // kelvin
+	"Q42289": {search.AmountUnitCelsius, 5.0 / 9.0, -160.0 / 9.0}, // degree Fahrenheit
+
+	// Area.
+	"Q25343":  {search.AmountUnitSquareMetre, 1, 0},              // square metre
+	"Q712226": {search.AmountUnitSquareMetre, 1e6, 0},            // square kilometre
+	"Q35852":  {search.AmountUnitSquareMetre, 1e4, 0},            // hectare
+	"Q81292":  {search.AmountUnitSquareMetre, 4046.8564224, 0},   // acre
+	"Q232291": {search.AmountUnitSquareMetre, 2589988.110336, 0}, // square mile
+
+	// Speed.
+	"Q182429": {search.AmountUnitMetrePerSecond, 1, 0},             // metre per second
+	"Q180154": {search.AmountUnitMetrePerSecond, 1 / 3.6, 0},       // kilometre per hour
+	"Q211256": {search.AmountUnitMetrePerSecond, 0.44704, 0},       // mile per hour
+	"Q128822": {search.AmountUnitMetrePerSecond, 1852.0 / 3600, 0}, // knot
+
+	// Density.
+	"Q844211":   {search.AmountUnitKilogramPerCubicMetre, 1, 0},   // kilogram per cubic metre
+	"Q13147228": {search.AmountUnitKilogramPerCubicMetre, 1e3, 0}, // gram per cubic centimetre
+
+	// Energy.
+	"Q25269":  {search.AmountUnitJoule, 1, 0},     // joule
+	"Q182098": {search.AmountUnitJoule, 3.6e6, 0}, // kilowatt hour
+
+	// Power.
+	"Q25236":   {search.AmountUnitWatt, 1, 0},   // watt
+	"Q184484":  {search.AmountUnitWatt, 1e3, 0}, // kilowatt
+	"Q6982035": {search.AmountUnitWatt, 1e6, 0}, // megawatt
+
+	// Pressure.
+	"Q44395":    {search.AmountUnitPascal, 1, 0},      // pascal
+	"Q21064807": {search.AmountUnitPascal, 1e3, 0},    // kilopascal
+	"Q103510":   {search.AmountUnitPascal, 1e5, 0},    // bar
+	"Q177974":   {search.AmountUnitPascal, 101325, 0}, // standard atmosphere
+
+	// Electricity.
+	"Q25250": {search.AmountUnitVolt, 1, 0},    // volt
+	"Q25406": {search.AmountUnitCoulomb, 1, 0}, // coulomb
+
+	// Frequency.
+	"Q39369":   {search.AmountUnitHertz, 1, 0},   // hertz
+	"Q2143992": {search.AmountUnitHertz, 1e3, 0}, // kilohertz
+	"Q732707":  {search.AmountUnitHertz, 1e6, 0}, // megahertz
+	"Q3276763": {search.AmountUnitHertz, 1e9, 0}, // gigahertz
+
+	// Angle.
+	"Q33680": {search.AmountUnitRadian, 1, 0},                    // radian
+	"Q28390": {search.AmountUnitRadian, 0.017453292519943295, 0}, // degree
+
+	// Information.
+	"Q8799":  {search.AmountUnitByte, 1, 0},   // byte
+	"Q79735": {search.AmountUnitByte, 1e3, 0}, // kilobyte
+	"Q79738": {search.AmountUnitByte, 1e6, 0}, // megabyte
+	"Q79741": {search.AmountUnitByte, 1e9, 0}, // gigabyte
+
+	// Currency.
+	"Q4917": {search.AmountUnitDollar, 1, 0}, // United States dollar
+}
+
+// Convert returns value converted to the standard unit.
+func (c unitConversion) Convert(value float64) float64 {
+	return value*c.Factor + c.Offset
+}
+
+// convertAmount converts the amount and its uncertainty bounds (if provided) in a Wikidata unit
+// to the standard unit. It returns false if the Wikidata unit is not known.
+func convertAmount(unitID string, amount float64, uncertaintyLower, uncertaintyUpper *float64) (search.AmountUnit, float64, *float64, *float64, bool) {
+	conversion, ok := wikidataUnits[unitID]
+	if !ok {
+		return search.AmountUnitCustom, amount, uncertaintyLower, uncertaintyUpper, false
+	}
+	var lower, upper *float64
+	if uncertaintyLower != nil {
+		l := conversion.Convert(*uncertaintyLower)
+		lower = &l
+	}
+	if uncertaintyUpper != nil {
+		u := conversion.Convert(*uncertaintyUpper)
+		upper = &u
+	}
+	return conversion.Unit, conversion.Convert(amount), lower, upper, true
+}
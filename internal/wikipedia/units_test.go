@@ -0,0 +1,32 @@
+package wikipedia
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/peerdb/search"
+)
+
+func TestConvertAmount(t *testing.T) {
+	lower := 31.0
+	upper := 33.0
+	unit, amount, uncertaintyLower, uncertaintyUpper, ok := convertAmount("Q42289", 32.0, &lower, &upper)
+	assert.True(t, ok)
+	assert.Equal(t, search.AmountUnitCelsius, unit)
+	assert.InDelta(t, 0.0, amount, 1e-9)
+	assert.InDelta(t, -5.0/9.0, *uncertaintyLower, 1e-9)
+	assert.InDelta(t, 5.0/9.0, *uncertaintyUpper, 1e-9)
+
+	unit, amount, uncertaintyLower, uncertaintyUpper, ok = convertAmount("Q828224", 1.5, nil, nil)
+	assert.True(t, ok)
+	assert.Equal(t, search.AmountUnitMetre, unit)
+	assert.InDelta(t, 1500.0, amount, 1e-9)
+	assert.Nil(t, uncertaintyLower)
+	assert.Nil(t, uncertaintyUpper)
+
+	unit, amount, _, _, ok = convertAmount("Q0", 1.5, nil, nil)
+	assert.False(t, ok)
+	assert.Equal(t, search.AmountUnitCustom, unit)
+	assert.Equal(t, 1.5, amount)
+}
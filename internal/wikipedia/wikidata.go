@@ -10,13 +10,12 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
-	"github.com/olivere/elastic/v7"
 	"github.com/rs/zerolog"
 	"gitlab.com/tozd/go/errors"
 	"gitlab.com/tozd/go/mediawiki"
-	"gitlab.com/tozd/go/x"
 
 	"gitlab.com/peerdb/search"
+	"gitlab.com/peerdb/search/identifier"
 )
 
 const (
@@ -26,6 +25,11 @@ const (
 	NoConfidence     = 0.0
 )
 
+const (
+	// Wikidata property "subproperty of".
+	wikidataSubpropertyOf = "P1647"
+)
+
 const (
 	WikidataReference                 = "xx-Wikidata"
 	WikimediaCommonsEntityReference   = "xx-CommonsEntity"
@@ -37,7 +41,7 @@ const (
 )
 
 var (
-	NameSpaceWikidata = uuid.MustParse("8f8ba777-bcce-4e45-8dd4-a328e6722c82")
+	NameSpaceWikidata = identifier.RegisterNamespace("Wikidata", uuid.MustParse("8f8ba777-bcce-4e45-8dd4-a328e6722c82"))
 
 	notSupportedDataValueTypeError = errors.BaseWrap(SilentSkippedError, "not supported data value type")
 	notSupportedDataTypeError      = errors.BaseWrap(SilentSkippedError, "not supported data type")
@@ -228,30 +232,46 @@ func getDocumentReference(id, source string) search.DocumentReference {
 	panic(errors.Errorf("unsupported ID for source \"%s\": %s", source, id))
 }
 
-func getDocumentFromESByProp(ctx context.Context, index string, esClient *elastic.Client, property, id string) (*search.Document, *elastic.SearchHit, errors.E) {
-	searchResult, err := esClient.Search(index).Query(elastic.NewNestedQuery("active.id",
-		elastic.NewBoolQuery().Must(
-			elastic.NewTermQuery("active.id.prop._id", search.GetStandardPropertyID(property)),
-			elastic.NewTermQuery("active.id.id", id),
-		),
-	)).SeqNoPrimaryTerm(true).Do(ctx)
-	if err != nil {
+// getProperties returns references to standard properties with the mnemonics, keyed by the mnemonic.
+// It returns an error if any of the properties is not registered.
+func getProperties(mnemonics ...string) (map[string]search.DocumentReference, errors.E) {
+	properties := make(map[string]search.DocumentReference, len(mnemonics))
+	for _, mnemonic := range mnemonics {
+		reference, errE := search.StandardProperties.Reference(search.Mnemonic(mnemonic))
+		if errE != nil {
+			return nil, errE
+		}
+		properties[mnemonic] = reference
+	}
+	return properties, nil
+}
+
+// getDocument returns the document with the ID together with its version, which can be used
+// to update the document only if it has not changed in the meantime.
+func getDocument(ctx context.Context, backend search.Backend, id search.Identifier) (*search.Document, search.Version, errors.E) {
+	document, version, errE := backend.Get(ctx, id, search.FullProjection())
+	if errors.Is(errE, search.ErrNotFound) {
+		// Caller should add details to the error.
+		return nil, "", errors.WithStack(NotFoundError)
+	} else if errE != nil {
 		// Caller should add details to the error.
-		return nil, nil, errors.WithStack(err)
+		return nil, "", errE
 	}
 
-	// There might be multiple hits because IDs are not unique (we remove zeroes and do a case insensitive matching).
-	for _, hit := range searchResult.Hits.Hits {
-		var document search.Document
-		err = x.UnmarshalWithoutUnknownFields(hit.Source, &document)
-		if err != nil {
-			// Caller should add details to the error.
-			return nil, nil, errors.WithStack(err)
-		}
+	return document, version, nil
+}
 
-		// ID is not stored in the document, so we set it here ourselves.
-		document.ID = search.Identifier(hit.Id)
+// getDocumentByProp returns the document with an identifier claim for the property with the ID,
+// together with its version (see getDocument).
+func getDocumentByProp(ctx context.Context, backend search.Backend, property, id string) (*search.Document, search.Version, errors.E) {
+	documents, errE := backend.FindByIdentifier(ctx, []search.Identifier{search.GetStandardPropertyID(property)}, id)
+	if errE != nil {
+		// Caller should add details to the error.
+		return nil, "", errE
+	}
 
+	// There might be multiple documents because IDs are not unique (we remove zeroes and do a case insensitive matching).
+	for _, document := range documents {
 		found := false
 		for _, claim := range document.Get(search.GetStandardPropertyID(property)) {
 			if c, ok := claim.(*search.IdentifierClaim); ok && c.Identifier == id {
@@ -260,52 +280,29 @@ func getDocumentFromESByProp(ctx context.Context, index string, esClient *elasti
 			}
 		}
 
-		// If this hit is not precisely for this name, we continue with the next one.
+		// If this document is not precisely for this name, we continue with the next one.
 		if !found {
 			continue
 		}
 
-		return &document, hit, nil
+		// Returned documents might include only identifier claims, so we fetch the whole document.
+		return getDocument(ctx, backend, document.ID)
 	}
 
 	// Caller should add details to the error.
-	return nil, nil, errors.WithStack(NotFoundError)
-}
-
-func getDocumentFromESByID(ctx context.Context, index string, esClient *elastic.Client, id search.Identifier) (*search.Document, *elastic.GetResult, errors.E) {
-	esDoc, err := esClient.Get().Index(index).Id(string(id)).Do(ctx)
-	if elastic.IsNotFound(err) {
-		// Caller should add details to the error.
-		return nil, nil, errors.WithStack(NotFoundError)
-	} else if err != nil {
-		// Caller should add details to the error.
-		return nil, nil, errors.WithStack(err)
-	} else if !esDoc.Found {
-		// Caller should add details to the error.
-		return nil, nil, errors.WithStack(NotFoundError)
-	}
-
-	var document search.Document
-	err = x.UnmarshalWithoutUnknownFields(esDoc.Source, &document)
-	if err != nil {
-		// Caller should add details to the error.
-		return nil, nil, errors.WithStack(err)
-	}
-
-	// ID is not stored in the document, so we set it here ourselves.
-	document.ID = id
-
-	return &document, esDoc, nil
+	return nil, "", errors.WithStack(NotFoundError)
 }
 
-func GetWikidataItem(ctx context.Context, index string, esClient *elastic.Client, id string) (*search.Document, *elastic.SearchHit, errors.E) {
-	document, hit, err := getDocumentFromESByProp(ctx, index, esClient, "WIKIDATA_ITEM_ID", id)
+// GetWikidataItem returns the document of the Wikidata item with the ID together with
+// its version, which can be used to update the document only if it has not changed in the meantime.
+func GetWikidataItem(ctx context.Context, backend search.Backend, id string) (*search.Document, search.Version, errors.E) {
+	document, version, err := getDocumentByProp(ctx, backend, "WIKIDATA_ITEM_ID", id)
 	if err != nil {
 		errors.Details(err)["entity"] = id
-		return nil, nil, err
+		return nil, "", err
 	}
 
-	return document, hit, nil
+	return document, version, nil
 }
 
 func resolveDataTypeFromPropertyDocument(document *search.Document, prop string, valueType *mediawiki.WikiBaseEntityType) (mediawiki.DataType, errors.E) {
@@ -347,7 +344,7 @@ func resolveDataTypeFromPropertyDocument(document *search.Document, prop string,
 }
 
 func getDataTypeForProperty(
-	ctx context.Context, index string, esClient *elastic.Client, cache *Cache,
+	ctx context.Context, backend search.Backend, cache *Cache,
 	prop string, valueType *mediawiki.WikiBaseEntityType,
 ) (mediawiki.DataType, errors.E) {
 	id := GetWikidataDocumentID(prop)
@@ -362,7 +359,7 @@ func getDataTypeForProperty(
 		return resolveDataTypeFromPropertyDocument(maybeDocument.(*search.Document), prop, valueType)
 	}
 
-	document, _, err := getDocumentFromESByID(ctx, index, esClient, id)
+	document, _, err := getDocument(ctx, backend, id)
 	if errors.Is(err, NotFoundError) {
 		cache.Add(id, nil)
 		errors.Details(err)["prop"] = prop
@@ -386,7 +383,7 @@ func getWikiBaseEntityType(value interface{}) *mediawiki.WikiBaseEntityType {
 }
 
 func processSnak( //nolint:ireturn,nolintlint
-	ctx context.Context, index string, log zerolog.Logger, esClient *elastic.Client, cache *Cache,
+	ctx context.Context, log zerolog.Logger, backend search.Backend, cache *Cache,
 	namespace uuid.UUID, prop string, idArgs []interface{}, confidence search.Confidence, snak mediawiki.Snak,
 ) (search.Claim, errors.E) {
 	id := search.GetID(namespace, idArgs...)
@@ -421,7 +418,7 @@ func processSnak( //nolint:ireturn,nolintlint
 		// Wikimedia Commons might not have the datatype field set, so we have to fetch it ourselves.
 		// See: https://phabricator.wikimedia.org/T311977
 		var err errors.E
-		dataType, err = getDataTypeForProperty(ctx, index, esClient, cache, prop, getWikiBaseEntityType(snak.DataValue.Value))
+		dataType, err = getDataTypeForProperty(ctx, backend, cache, prop, getWikiBaseEntityType(snak.DataValue.Value))
 		if err != nil {
 			return nil, errors.WithMessagef(err, "unable to resolve data type for property with value %T", snak.DataValue.Value)
 		}
@@ -463,6 +460,11 @@ func processSnak( //nolint:ireturn,nolintlint
 			args = append(args, "IS", 0, title, 0)
 			claimID := search.GetID(namespace, args...)
 
+			isProp, errE := search.StandardProperties.Reference("IS")
+			if errE != nil {
+				return nil, errE
+			}
+
 			// An invalid claim we post-process later.
 			return &search.FileClaim{
 				CoreClaim: search.CoreClaim{
@@ -475,7 +477,7 @@ func processSnak( //nolint:ireturn,nolintlint
 									ID:         claimID,
 									Confidence: HighConfidence,
 								},
-								Prop: search.GetStandardPropertyReference("IS"),
+								Prop: isProp,
 								To:   getDocumentReference(title, ""),
 							},
 						},
@@ -601,13 +603,6 @@ func processSnak( //nolint:ireturn,nolintlint
 			if value.Unit == "1" {
 				claim.Unit = search.AmountUnitNone
 			} else {
-				// For now we store the amount as-is and convert to the same unit later on
-				// using the unit we store into meta claims.
-				// TODO: Implement unit post-processing.
-				claim.Unit = search.AmountUnitCustom
-				args := append([]interface{}{}, idArgs...)
-				args = append(args, "UNIT", 0)
-				claimID := search.GetID(NameSpaceWikidata, args...)
 				var unitID string
 				if strings.HasPrefix(value.Unit, "http://www.wikidata.org/entity/") {
 					unitID = strings.TrimPrefix(value.Unit, "http://www.wikidata.org/entity/")
@@ -616,17 +611,60 @@ func processSnak( //nolint:ireturn,nolintlint
 				} else {
 					return nil, errors.Errorf("unsupported unit URL: %s", value.Unit)
 				}
-				claim.CoreClaim.Meta = &search.ClaimTypes{
-					Relation: search.RelationClaims{
-						{
-							CoreClaim: search.CoreClaim{
-								ID:         claimID,
-								Confidence: HighConfidence,
+				unitProp, errE := search.StandardProperties.Reference("UNIT")
+				if errE != nil {
+					return nil, errE
+				}
+				args := append([]interface{}{}, idArgs...)
+				args = append(args, "UNIT", 0)
+				unitClaim := search.RelationClaim{
+					CoreClaim: search.CoreClaim{
+						ID:         search.GetID(NameSpaceWikidata, args...),
+						Confidence: HighConfidence,
+					},
+					Prop: unitProp,
+					To:   getDocumentReference(unitID, ""),
+				}
+
+				unit, amount, uncertaintyLower, uncertaintyUpper, ok := convertAmount(unitID, claim.Amount, claim.UncertaintyLower, claim.UncertaintyUpper)
+				if ok {
+					originalAmountProp, errE := search.StandardProperties.Reference("ORIGINAL_AMOUNT")
+					if errE != nil {
+						return nil, errE
+					}
+					// We store the amount converted to the standard unit and keep the original
+					// amount (together with its unit) as a meta claim.
+					args := append([]interface{}{}, idArgs...)
+					args = append(args, "ORIGINAL_AMOUNT", 0)
+					claim.CoreClaim.Meta = &search.ClaimTypes{
+						Amount: search.AmountClaims{
+							{
+								CoreClaim: search.CoreClaim{
+									ID:         search.GetID(NameSpaceWikidata, args...),
+									Confidence: HighConfidence,
+									Meta: &search.ClaimTypes{
+										Relation: search.RelationClaims{unitClaim},
+									},
+								},
+								Prop:             originalAmountProp,
+								Amount:           claim.Amount,
+								UncertaintyLower: claim.UncertaintyLower,
+								UncertaintyUpper: claim.UncertaintyUpper,
+								Unit:             search.AmountUnitCustom,
 							},
-							Prop: search.GetStandardPropertyReference("UNIT"),
-							To:   getDocumentReference(unitID, ""),
 						},
-					},
+					}
+					claim.Amount = amount
+					claim.UncertaintyLower = uncertaintyLower
+					claim.UncertaintyUpper = uncertaintyUpper
+					claim.Unit = unit
+				} else {
+					// For units we do not know how to convert, we store the amount as-is
+					// together with the unit in a meta claim.
+					claim.Unit = search.AmountUnitCustom
+					claim.CoreClaim.Meta = &search.ClaimTypes{
+						Relation: search.RelationClaims{unitClaim},
+					}
 				}
 			}
 
@@ -634,19 +672,47 @@ func processSnak( //nolint:ireturn,nolintlint
 		default:
 			return nil, errors.Errorf("unexpected data type for QuantityValue: %d", dataType)
 		}
-	case mediawiki.TimeValue:
+	case TimeValue:
 		switch dataType { //nolint:exhaustive
 		case mediawiki.Time:
-			// TODO: Convert timestamps in Julian calendar to ones in Gregorian calendar.
-			return &search.TimeClaim{
+			timestamp, errE := convertCalendar(value)
+			if errE != nil {
+				return nil, errE
+			}
+			claim := search.TimeClaim{
 				CoreClaim: search.CoreClaim{
 					ID:         id,
 					Confidence: confidence,
 				},
 				Prop:      getDocumentReference(prop, ""),
-				Timestamp: search.Timestamp(value.Time),
+				Timestamp: timestamp,
 				Precision: search.TimePrecision(value.Precision),
-			}, nil
+			}
+
+			if value.Calendar == mediawiki.Julian {
+				calendarProp, errE := search.StandardProperties.Reference("CALENDAR")
+				if errE != nil {
+					return nil, errE
+				}
+				// We always store timestamps in the proleptic Gregorian calendar,
+				// but we keep the information about the original calendar.
+				args := append([]interface{}{}, idArgs...)
+				args = append(args, "CALENDAR", 0)
+				claim.CoreClaim.Meta = &search.ClaimTypes{
+					Relation: search.RelationClaims{
+						{
+							CoreClaim: search.CoreClaim{
+								ID:         search.GetID(NameSpaceWikidata, args...),
+								Confidence: HighConfidence,
+							},
+							Prop: calendarProp,
+							To:   getDocumentReference(julianCalendarID, ""),
+						},
+					},
+				}
+			}
+
+			return &claim, nil
 		default:
 			return nil, errors.Errorf("unexpected data type for TimeValue: %d", dataType)
 		}
@@ -656,13 +722,13 @@ func processSnak( //nolint:ireturn,nolintlint
 }
 
 func addQualifiers(
-	ctx context.Context, index string, log zerolog.Logger, esClient *elastic.Client, cache *Cache, namespace uuid.UUID,
+	ctx context.Context, log zerolog.Logger, backend search.Backend, cache *Cache, namespace uuid.UUID,
 	claim search.Claim, entityID, prop, statementID string, qualifiers map[string][]mediawiki.Snak, qualifiersOrder []string,
 ) errors.E {
 	for _, p := range qualifiersOrder {
 		for i, qualifier := range qualifiers[p] {
 			qualifierClaim, err := processSnak(
-				ctx, index, log, esClient, cache, namespace, p, []interface{}{entityID, prop, statementID, "qualifier", p, i}, MediumConfidence, qualifier,
+				ctx, log, backend, cache, namespace, p, []interface{}{entityID, prop, statementID, "qualifier", p, i}, MediumConfidence, qualifier,
 			)
 			if errors.Is(err, SilentSkippedError) {
 				log.Debug().Str("entity", entityID).Array("path", zerolog.Arr().Str(prop).Str(statementID).Str("qualifier").Str(p).Int(i)).
@@ -687,7 +753,7 @@ func addQualifiers(
 // In the second mode, when there are multiple snak types, it wraps them into a temporary WIKIDATA_REFERENCE claim which will be processed later.
 // TODO: Implement post-processing of temporary WIKIDATA_REFERENCE claims.
 func addReference(
-	ctx context.Context, index string, log zerolog.Logger, esClient *elastic.Client, cache *Cache, namespace uuid.UUID,
+	ctx context.Context, log zerolog.Logger, backend search.Backend, cache *Cache, namespace uuid.UUID,
 	claim search.Claim, entityID, prop, statementID string, i int, reference mediawiki.Reference,
 ) errors.E {
 	// Edge case.
@@ -700,12 +766,16 @@ func addReference(
 	if len(reference.SnaksOrder) == 1 {
 		referenceClaim = claim
 	} else {
+		referenceProp, errE := search.StandardProperties.Reference("WIKIDATA_REFERENCE")
+		if errE != nil {
+			return errE
+		}
 		referenceClaim = &search.TextClaim{
 			CoreClaim: search.CoreClaim{
 				ID:         search.GetID(namespace, entityID, prop, statementID, "reference", i, "WIKIDATA_REFERENCE", 0),
 				Confidence: NoConfidence,
 			},
-			Prop: search.GetStandardPropertyReference("WIKIDATA_REFERENCE"),
+			Prop: referenceProp,
 			HTML: search.TranslatableHTMLString{
 				"XX": html.EscapeString("A temporary group of multiple Wikidata reference statements for later processing."),
 			},
@@ -715,7 +785,7 @@ func addReference(
 	for _, property := range reference.SnaksOrder {
 		for j, snak := range reference.Snaks[property] {
 			c, err := processSnak(
-				ctx, index, log, esClient, cache, namespace, property, []interface{}{entityID, prop, statementID, "reference", i, property, j}, MediumConfidence, snak,
+				ctx, log, backend, cache, namespace, property, []interface{}{entityID, prop, statementID, "reference", i, property, j}, MediumConfidence, snak,
 			)
 			if errors.Is(err, SilentSkippedError) {
 				log.Debug().Str("entity", entityID).Array("path", zerolog.Arr().Str(prop).Str(statementID).Str("reference").Int(i).Str(property).Int(j)).
@@ -748,7 +818,7 @@ func addReference(
 // ConvertEntity converts both Wikidata entities and Wikimedia Commons entities.
 // Entities can reference only Wikimedia Commons files and not Wikipedia files.
 func ConvertEntity(
-	ctx context.Context, index string, log zerolog.Logger, esClient *elastic.Client, cache *Cache,
+	ctx context.Context, log zerolog.Logger, backend search.Backend, cache *Cache,
 	namespace uuid.UUID, entity mediawiki.Entity,
 ) (*search.Document, errors.E) {
 	englishLabels := getEnglishValues(entity.Labels)
@@ -762,6 +832,14 @@ func ConvertEntity(
 		return nil, errors.WithStack(errors.BaseWrap(SilentSkippedError, "limited only to English"))
 	}
 
+	standardProps, errE := getProperties(
+		"WIKIDATA_PROPERTY_ID", "WIKIDATA_PROPERTY_PAGE", "IS", "PROPERTY", "WIKIDATA_ITEM_ID", "WIKIDATA_ITEM_PAGE", "ITEM",
+		"WIKIMEDIA_COMMONS_ENTITY_ID", "ALSO_KNOWN_AS", "DESCRIPTION", "SUBPROPERTY_OF",
+	)
+	if errE != nil {
+		return nil, errE
+	}
+
 	var id search.Identifier
 	var name string
 	var filename string
@@ -808,7 +886,7 @@ func ConvertEntity(
 						ID:         search.GetID(namespace, entity.ID, "WIKIDATA_PROPERTY_ID", 0),
 						Confidence: HighConfidence,
 					},
-					Prop:       search.GetStandardPropertyReference("WIKIDATA_PROPERTY_ID"),
+					Prop:       standardProps["WIKIDATA_PROPERTY_ID"],
 					Identifier: entity.ID,
 				},
 			},
@@ -818,7 +896,7 @@ func ConvertEntity(
 						ID:         search.GetID(namespace, entity.ID, "WIKIDATA_PROPERTY_PAGE", 0),
 						Confidence: HighConfidence,
 					},
-					Prop: search.GetStandardPropertyReference("WIKIDATA_PROPERTY_PAGE"),
+					Prop: standardProps["WIKIDATA_PROPERTY_PAGE"],
 					IRI:  fmt.Sprintf("https://www.wikidata.org/wiki/Property:%s", entity.ID),
 				},
 			},
@@ -828,8 +906,8 @@ func ConvertEntity(
 						ID:         search.GetID(namespace, entity.ID, "IS", 0, "PROPERTY", 0),
 						Confidence: HighConfidence,
 					},
-					Prop: search.GetStandardPropertyReference("IS"),
-					To:   search.GetStandardPropertyReference("PROPERTY"),
+					Prop: standardProps["IS"],
+					To:   standardProps["PROPERTY"],
 				},
 			},
 		}
@@ -841,7 +919,7 @@ func ConvertEntity(
 						ID:         search.GetID(namespace, entity.ID, "WIKIDATA_ITEM_ID", 0),
 						Confidence: HighConfidence,
 					},
-					Prop:       search.GetStandardPropertyReference("WIKIDATA_ITEM_ID"),
+					Prop:       standardProps["WIKIDATA_ITEM_ID"],
 					Identifier: entity.ID,
 				},
 			},
@@ -851,7 +929,7 @@ func ConvertEntity(
 						ID:         search.GetID(namespace, entity.ID, "WIKIDATA_ITEM_PAGE", 0),
 						Confidence: HighConfidence,
 					},
-					Prop: search.GetStandardPropertyReference("WIKIDATA_ITEM_PAGE"),
+					Prop: standardProps["WIKIDATA_ITEM_PAGE"],
 					IRI:  fmt.Sprintf("https://www.wikidata.org/wiki/%s", entity.ID),
 				},
 			},
@@ -861,8 +939,8 @@ func ConvertEntity(
 						ID:         search.GetID(namespace, entity.ID, "IS", 0, "ITEM", 0),
 						Confidence: HighConfidence,
 					},
-					Prop: search.GetStandardPropertyReference("IS"),
-					To:   search.GetStandardPropertyReference("ITEM"),
+					Prop: standardProps["IS"],
+					To:   standardProps["ITEM"],
 				},
 			},
 		}
@@ -877,7 +955,7 @@ func ConvertEntity(
 						ID:         search.GetID(namespace, entity.ID, "WIKIMEDIA_COMMONS_ENTITY_ID", 0),
 						Confidence: HighConfidence,
 					},
-					Prop:       search.GetStandardPropertyReference("WIKIMEDIA_COMMONS_ENTITY_ID"),
+					Prop:       standardProps["WIKIMEDIA_COMMONS_ENTITY_ID"],
 					Identifier: entity.ID,
 				},
 			},
@@ -923,12 +1001,16 @@ func ConvertEntity(
 					return nil, errE
 				}
 			}
+			siteProps, errE := getProperties(site.MnemonicPrefix+"_PAGE_TITLE", site.MnemonicPrefix+"_PAGE")
+			if errE != nil {
+				return nil, errE
+			}
 			document.Active.Identifier = append(document.Active.Identifier, search.IdentifierClaim{
 				CoreClaim: search.CoreClaim{
 					ID:         search.GetID(namespace, entity.ID, site.MnemonicPrefix+"_PAGE_TITLE", 0),
 					Confidence: HighConfidence,
 				},
-				Prop:       search.GetStandardPropertyReference(site.MnemonicPrefix + "_PAGE_TITLE"),
+				Prop:       siteProps[site.MnemonicPrefix+"_PAGE_TITLE"],
 				Identifier: siteLink.Title,
 			})
 			document.Active.Reference = append(document.Active.Reference, search.ReferenceClaim{
@@ -936,7 +1018,7 @@ func ConvertEntity(
 					ID:         search.GetID(namespace, entity.ID, site.MnemonicPrefix+"_PAGE", 0),
 					Confidence: HighConfidence,
 				},
-				Prop: search.GetStandardPropertyReference(site.MnemonicPrefix + "_PAGE"),
+				Prop: siteProps[site.MnemonicPrefix+"_PAGE"],
 				IRI:  url,
 			})
 		}
@@ -947,6 +1029,10 @@ func ConvertEntity(
 		// We use this in resolveDataTypeFromPropertyDocument, too.
 		claimTypeMnemonic := getPropertyClaimType(*entity.DataType)
 		if claimTypeMnemonic != "" {
+			claimType, errE := search.StandardProperties.Reference(search.Mnemonic(claimTypeMnemonic))
+			if errE != nil {
+				return nil, errE
+			}
 			document.Active.Relation = append(document.Active.Relation, search.RelationClaim{
 				CoreClaim: search.CoreClaim{
 					ID: search.GetID(namespace, entity.ID, "IS", 0, claimTypeMnemonic, 0),
@@ -955,8 +1041,8 @@ func ConvertEntity(
 					// TODO: Decide what should really be confidence here or implement "later on" part described above.
 					Confidence: LowConfidence,
 				},
-				Prop: search.GetStandardPropertyReference("IS"),
-				To:   search.GetStandardPropertyReference(claimTypeMnemonic),
+				Prop: standardProps["IS"],
+				To:   claimType,
 			})
 		}
 	}
@@ -970,7 +1056,7 @@ func ConvertEntity(
 				ID:         search.GetID(namespace, entity.ID, "ALSO_KNOWN_AS", i),
 				Confidence: HighConfidence,
 			},
-			Prop: search.GetStandardPropertyReference("ALSO_KNOWN_AS"),
+			Prop: standardProps["ALSO_KNOWN_AS"],
 			HTML: search.TranslatableHTMLString{
 				"en": html.EscapeString(label),
 			},
@@ -984,7 +1070,7 @@ func ConvertEntity(
 				ID:         search.GetID(namespace, entity.ID, "DESCRIPTION", i),
 				Confidence: MediumConfidence,
 			},
-			Prop: search.GetStandardPropertyReference("DESCRIPTION"),
+			Prop: standardProps["DESCRIPTION"],
 			HTML: search.TranslatableHTMLString{
 				"en": html.EscapeString(description),
 			},
@@ -1008,7 +1094,7 @@ func ConvertEntity(
 
 			confidence := getConfidence(entity.ID, prop, statement.ID, statement.Rank)
 			claim, err := processSnak(
-				ctx, index, log, esClient, cache, namespace, prop, []interface{}{entity.ID, prop, statement.ID, "mainsnak"}, confidence, statement.MainSnak,
+				ctx, log, backend, cache, namespace, prop, []interface{}{entity.ID, prop, statement.ID, "mainsnak"}, confidence, statement.MainSnak,
 			)
 			if errors.Is(err, SilentSkippedError) {
 				log.Debug().Str("entity", entity.ID).Array("path", zerolog.Arr().Str(prop).Str(statement.ID).Str("mainsnak")).
@@ -1020,7 +1106,7 @@ func ConvertEntity(
 				continue
 			}
 			err = addQualifiers(
-				ctx, index, log, esClient, cache, namespace, claim, entity.ID, prop, statement.ID, statement.Qualifiers, statement.QualifiersOrder,
+				ctx, log, backend, cache, namespace, claim, entity.ID, prop, statement.ID, statement.Qualifiers, statement.QualifiersOrder,
 			)
 			if err != nil {
 				log.Warn().Str("entity", entity.ID).Array("path", zerolog.Arr().Str(prop).Str(statement.ID).Str("qualifiers")).
@@ -1028,7 +1114,7 @@ func ConvertEntity(
 				continue
 			}
 			for i, reference := range statement.References {
-				err = addReference(ctx, index, log, esClient, cache, namespace, claim, entity.ID, prop, statement.ID, i, reference)
+				err = addReference(ctx, log, backend, cache, namespace, claim, entity.ID, prop, statement.ID, i, reference)
 				if err != nil {
 					log.Warn().Str("entity", entity.ID).Array("path", zerolog.Arr().Str(prop).Str(statement.ID).Str("reference").Int(i)).
 						Err(err).Fields(errors.AllDetails(err)).Send()
@@ -1039,6 +1125,23 @@ func ConvertEntity(
 			if err != nil {
 				log.Error().Str("entity", entity.ID).Array("path", zerolog.Arr().Str(prop).Str(statement.ID)).
 					Err(err).Fields(errors.AllDetails(err)).Msg("claim cannot be added")
+				continue
+			}
+			if relationClaim, ok := claim.(*search.RelationClaim); ok && prop == wikidataSubpropertyOf {
+				// We map Wikidata's subproperty relations to our standard property as well,
+				// so that we can build a property hierarchy independent of Wikidata properties.
+				err = document.Add(&search.RelationClaim{
+					CoreClaim: search.CoreClaim{
+						ID:         search.GetID(namespace, entity.ID, prop, statement.ID, "SUBPROPERTY_OF", 0),
+						Confidence: relationClaim.Confidence,
+					},
+					Prop: standardProps["SUBPROPERTY_OF"],
+					To:   relationClaim.To,
+				})
+				if err != nil {
+					log.Error().Str("entity", entity.ID).Array("path", zerolog.Arr().Str(prop).Str(statement.ID)).
+						Err(err).Fields(errors.AllDetails(err)).Msg("claim cannot be added")
+				}
 			}
 		}
 	}
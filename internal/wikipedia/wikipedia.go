@@ -9,16 +9,16 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/hashicorp/go-retryablehttp"
-	"github.com/olivere/elastic/v7"
 	"github.com/rs/zerolog"
 	"gitlab.com/tozd/go/errors"
 	"gitlab.com/tozd/go/mediawiki"
 
 	"gitlab.com/peerdb/search"
+	"gitlab.com/peerdb/search/identifier"
 )
 
 var (
-	NameSpaceWikipediaFile = uuid.MustParse("94b1c372-bc28-454c-a45a-2e4d29d15146")
+	NameSpaceWikipediaFile = identifier.RegisterNamespace("WikipediaFile", uuid.MustParse("94b1c372-bc28-454c-a45a-2e4d29d15146"))
 
 	WikimediaCommonsFileError = errors.Base("file is from Wikimedia Commons error")
 )
@@ -55,13 +55,21 @@ func ConvertWikipediaArticle(id, html string, document *search.Document) errors.
 	claimID = search.GetID(NameSpaceWikidata, id, "LABEL", 0, "HAS_ARTICLE", 0)
 	existingClaim := document.GetByID(claimID)
 	if existingClaim == nil {
+		labelProp, errE := search.StandardProperties.Reference("LABEL")
+		if errE != nil {
+			return errE
+		}
+		hasArticle, errE := search.StandardProperties.Reference("HAS_ARTICLE")
+		if errE != nil {
+			return errE
+		}
 		claim := &search.RelationClaim{
 			CoreClaim: search.CoreClaim{
 				ID:         claimID,
 				Confidence: HighConfidence,
 			},
-			Prop: search.GetStandardPropertyReference("LABEL"),
-			To:   search.GetStandardPropertyReference("HAS_ARTICLE"),
+			Prop: labelProp,
+			To:   hasArticle,
 		}
 		err = document.Add(claim)
 		if err != nil {
@@ -127,12 +135,16 @@ func updateTextClaim(claimID search.Identifier, document *search.Document, prop,
 		}
 		claim.HTML["en"] = value
 	} else {
+		propRef, errE := search.StandardProperties.Reference(search.Mnemonic(prop))
+		if errE != nil {
+			return errE
+		}
 		claim := &search.TextClaim{
 			CoreClaim: search.CoreClaim{
 				ID:         claimID,
 				Confidence: HighConfidence,
 			},
-			Prop: search.GetStandardPropertyReference(prop),
+			Prop: propRef,
 			HTML: search.TranslatableHTMLString{
 				"en": value,
 			},
@@ -190,12 +202,16 @@ func SetPageID(namespace uuid.UUID, mnemonicPrefix string, id string, pageID int
 		}
 		claim.Identifier = strconv.FormatInt(pageID, 10) //nolint:gomnd
 	} else {
+		prop, errE := search.StandardProperties.Reference(search.Mnemonic(mnemonicPrefix + "_PAGE_ID"))
+		if errE != nil {
+			return errE
+		}
 		claim := &search.IdentifierClaim{
 			CoreClaim: search.CoreClaim{
 				ID:         claimID,
 				Confidence: HighConfidence,
 			},
-			Prop:       search.GetStandardPropertyReference(mnemonicPrefix + "_PAGE_ID"),
+			Prop:       prop,
 			Identifier: strconv.FormatInt(pageID, 10), //nolint:gomnd
 		}
 		err := document.Add(claim)
@@ -214,15 +230,17 @@ func ConvertTemplateDescription(id, from string, html string, document *search.D
 	return convertDescription(NameSpaceWikidata, id, from, html, document, ExtractTemplateDescription)
 }
 
-func GetWikipediaFile(ctx context.Context, index string, esClient *elastic.Client, name string) (*search.Document, *elastic.SearchHit, errors.E) {
-	document, hit, errE := getDocumentFromESByProp(ctx, index, esClient, "ENGLISH_WIKIPEDIA_FILE_NAME", name)
+// GetWikipediaFile returns the document of the Wikipedia file with the name together with its version,
+// which can be used to update the document only if it has not changed in the meantime.
+func GetWikipediaFile(ctx context.Context, backend search.Backend, name string) (*search.Document, search.Version, errors.E) {
+	document, version, errE := getDocumentByProp(ctx, backend, "ENGLISH_WIKIPEDIA_FILE_NAME", name)
 	if errors.Is(errE, NotFoundError) {
 		// Passthrough.
 	} else if errE != nil {
 		errors.Details(errE)["file"] = name
-		return nil, nil, errE
+		return nil, "", errE
 	} else {
-		return document, hit, nil
+		return document, version, nil
 	}
 
 	// Is there a Wikimedia Commons file under that name? Most files with article on Wikipedia
@@ -230,21 +248,21 @@ func GetWikipediaFile(ctx context.Context, index string, esClient *elastic.Clien
 	// from Wikimedia Commons which have different names so this can have false negatives.
 	// False positives might also be possible but are probably harmless: we already did not
 	// find a Wikipedia file, so we are primarily trying to understand why not.
-	_, _, errE2 := getDocumentFromESByProp(ctx, index, esClient, "WIKIMEDIA_COMMONS_FILE_NAME", name)
+	_, _, errE2 := getDocumentByProp(ctx, backend, "WIKIMEDIA_COMMONS_FILE_NAME", name)
 	if errors.Is(errE2, NotFoundError) {
 		// We have not found a Wikimedia Commons file. Return the original error.
 		errors.Details(errE)["file"] = name
-		return nil, nil, errE
+		return nil, "", errE
 	} else if errE2 != nil {
 		errors.Details(errE2)["file"] = name
-		return nil, nil, errors.WithMessage(errE2, "checking for Wikimedia Commons")
+		return nil, "", errors.WithMessage(errE2, "checking for Wikimedia Commons")
 	}
 
 	// We found a Wikimedia Commons file.
 	errE = errors.WithStack(WikimediaCommonsFileError)
 	errors.Details(errE)["file"] = name
 	errors.Details(errE)["url"] = fmt.Sprintf("https://commons.wikimedia.org/wiki/File:%s", name)
-	return nil, nil, errE
+	return nil, "", errE
 }
 
 // TODO: How to remove categories which has previously been added but are later on removed?
@@ -287,15 +305,21 @@ func convertInCategory(log zerolog.Logger, namespace uuid.UUID, mnemonicPrefix,
 	claimID := search.GetID(namespace, id, "IN_"+mnemonicPrefix+"_CATEGORY", 0, category, 0)
 	existingClaim := document.GetByID(claimID)
 	if existingClaim == nil {
+		prop, err := search.StandardProperties.Reference(search.Mnemonic("IN_" + mnemonicPrefix + "_CATEGORY"))
+		if err != nil {
+			log.Error().Str("doc", string(document.ID)).Str("entity", id).Str("title", title).
+				Err(err).Fields(errors.AllDetails(err)).Msg("property not found")
+			return
+		}
 		claim := &search.RelationClaim{
 			CoreClaim: search.CoreClaim{
 				ID:         claimID,
 				Confidence: HighConfidence,
 			},
-			Prop: search.GetStandardPropertyReference("IN_" + mnemonicPrefix + "_CATEGORY"),
+			Prop: prop,
 			To:   getDocumentReference(category, mnemonicPrefix),
 		}
-		err := document.Add(claim)
+		err = document.Add(claim)
 		if err != nil {
 			log.Error().Str("doc", string(document.ID)).Str("entity", id).Str("claim", string(claimID)).Str("title", title).
 				Err(err).Fields(errors.AllDetails(err)).Msg("claim cannot be added")
@@ -311,15 +335,21 @@ func convertUsedTemplate(log zerolog.Logger, namespace uuid.UUID, mnemonicPrefix
 	claimID := search.GetID(namespace, id, "USES_"+mnemonicPrefix+"_TEMPLATE", template, 0)
 	existingClaim := document.GetByID(claimID)
 	if existingClaim == nil {
+		prop, err := search.StandardProperties.Reference(search.Mnemonic("USES_" + mnemonicPrefix + "_TEMPLATE"))
+		if err != nil {
+			log.Error().Str("doc", string(document.ID)).Str("entity", id).Str("title", title).
+				Err(err).Fields(errors.AllDetails(err)).Msg("property not found")
+			return
+		}
 		claim := &search.RelationClaim{
 			CoreClaim: search.CoreClaim{
 				ID:         claimID,
 				Confidence: HighConfidence,
 			},
-			Prop: search.GetStandardPropertyReference("USES_" + mnemonicPrefix + "_TEMPLATE"),
+			Prop: prop,
 			To:   getDocumentReference(template, mnemonicPrefix),
 		}
-		err := document.Add(claim)
+		err = document.Add(claim)
 		if err != nil {
 			log.Error().Str("doc", string(document.ID)).Str("entity", id).Str("claim", string(claimID)).Str("title", title).
 				Err(err).Fields(errors.AllDetails(err)).Msg("claim cannot be added")
@@ -327,18 +357,30 @@ func convertUsedTemplate(log zerolog.Logger, namespace uuid.UUID, mnemonicPrefix
 	}
 }
 
+// ConvertArticleRedirects adds ALSO_KNOWN_AS claims to the document for all redirects pointing
+// to the article. For files, it also adds ALIAS claims for redirects from other files, so that
+// requests for documents of redirected (e.g., renamed) files are redirected to the file document.
+//
 // TODO: How to remove redirects which has previously been added but are later on removed?
 func ConvertArticleRedirects(log zerolog.Logger, namespace uuid.UUID, id string, article mediawiki.Article, document *search.Document) errors.E {
 	for _, redirect := range article.Redirects {
 		convertRedirect(log, namespace, id, article.Name, redirect.Name, document)
+		if strings.HasPrefix(article.Name, "File:") {
+			convertFileAlias(log, namespace, id, redirect.Name, document)
+		}
 	}
 	return nil
 }
 
+// ConvertPageRedirects is like ConvertArticleRedirects, but for pages obtained through the API.
+//
 // TODO: How to remove redirects which has previously been added but are later on removed?
 func ConvertPageRedirects(log zerolog.Logger, namespace uuid.UUID, id string, page AllPagesPage, document *search.Document) errors.E {
 	for _, redirect := range page.Redirects {
 		convertRedirect(log, namespace, id, page.Title, redirect.Title, document)
+		if strings.HasPrefix(page.Title, "File:") {
+			convertFileAlias(log, namespace, id, redirect.Title, document)
+		}
 	}
 	return nil
 }
@@ -361,17 +403,23 @@ func convertRedirect(log zerolog.Logger, namespace uuid.UUID, id, title, redirec
 	if found {
 		return
 	}
+	prop, err := search.StandardProperties.Reference("ALSO_KNOWN_AS")
+	if err != nil {
+		log.Error().Str("doc", string(document.ID)).Str("entity", id).Str("title", title).
+			Err(err).Fields(errors.AllDetails(err)).Msg("property not found")
+		return
+	}
 	claim := &search.TextClaim{
 		CoreClaim: search.CoreClaim{
 			ID:         claimID,
 			Confidence: MediumConfidence,
 		},
-		Prop: search.GetStandardPropertyReference("ALSO_KNOWN_AS"),
+		Prop: prop,
 		HTML: search.TranslatableHTMLString{
 			"en": escapedName,
 		},
 	}
-	err := document.Add(claim)
+	err = document.Add(claim)
 	if err != nil {
 		log.Error().Str("doc", string(document.ID)).Str("entity", id).Str("claim", string(claimID)).Str("title", title).
 			Err(err).Fields(errors.AllDetails(err)).Msg("claim cannot be added")
@@ -0,0 +1,284 @@
+package search
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+	"gitlab.com/tozd/go/errors"
+
+	"gitlab.com/peerdb/search/identifier"
+)
+
+var nameSpaceLists = identifier.RegisterNamespace("Lists", uuid.MustParse("a3687bde-6165-4a28-9d3f-73debd0f2bd1"))
+
+// List is an ordered list of claims. All claims in the list have a LIST
+// identifier meta claim with the same list ID and are ordered by their
+// ORDER amount meta claims.
+type List struct {
+	// ID is the list ID. It is empty for a claim which is not part of any list.
+	ID       string
+	Elements []ListElement
+}
+
+// ListElement is an element of a list.
+type ListElement struct {
+	Claim Claim
+	// Order is nil if the claim does not have an ORDER meta claim.
+	Order *float64
+	// Children are lists which are children of this element
+	// (using CHILD identifier meta claims).
+	Children []List
+}
+
+// Claims returns all claims of the list in order, depth-first
+// (each element is followed by claims of its children lists).
+func (l List) Claims() []Claim {
+	claims := []Claim{}
+	for _, element := range l.Elements {
+		claims = append(claims, element.Claim)
+		for _, child := range element.Children {
+			claims = append(claims, child.Claims()...)
+		}
+	}
+	return claims
+}
+
+// getListID returns the list ID of the claim, if it has it.
+func getListID(claim Claim) (string, bool) {
+	for _, cc := range claim.GetMeta(GetStandardPropertyID("LIST")) {
+		if c, ok := cc.(*IdentifierClaim); ok {
+			return c.Identifier, true
+		}
+	}
+	return "", false
+}
+
+// getListOrder returns the order of the claim inside its list, if it has it.
+func getListOrder(claim Claim) *float64 {
+	for _, cc := range claim.GetMeta(GetStandardPropertyID("ORDER")) {
+		if c, ok := cc.(*AmountClaim); ok {
+			order := c.Amount
+			return &order
+		}
+	}
+	return nil
+}
+
+// setListOrder sets the order of the claim inside its list.
+// The claim must already have an ORDER meta claim.
+func setListOrder(claim Claim, order float64) {
+	for _, cc := range claim.GetMeta(GetStandardPropertyID("ORDER")) {
+		if c, ok := cc.(*AmountClaim); ok {
+			c.Amount = order
+			return
+		}
+	}
+}
+
+// getListChildren returns IDs of children lists of the claim.
+func getListChildren(claim Claim) []string {
+	children := []string{}
+	for _, cc := range claim.GetMeta(GetStandardPropertyID("CHILD")) {
+		if c, ok := cc.(*IdentifierClaim); ok {
+			children = append(children, c.Identifier)
+		}
+	}
+	return children
+}
+
+// sortListElements sorts elements by their order. Elements without
+// order are at the end, in the order in which they are in the document.
+func sortListElements(elements []ListElement) {
+	sort.SliceStable(elements, func(i, j int) bool {
+		if elements[i].Order == nil {
+			return false
+		} else if elements[j].Order == nil {
+			return true
+		}
+		return *elements[i].Order < *elements[j].Order
+	})
+}
+
+// listElements returns all claims in the document which are part of the list, in order.
+func (d *Document) listElements(listID string) []ListElement {
+	elements := []ListElement{}
+	for _, claim := range d.AllClaims() {
+		if id, ok := getListID(claim); ok && id == listID {
+			elements = append(elements, ListElement{
+				Claim:    claim,
+				Order:    getListOrder(claim),
+				Children: nil,
+			})
+		}
+	}
+	sortListElements(elements)
+	return elements
+}
+
+// resolveChildren populates children lists of all elements (recursively).
+// Lists already in visited are not resolved again to prevent infinite recursion.
+func (d *Document) resolveChildren(elements []ListElement, visited map[string]bool) {
+	for i := range elements {
+		for _, childID := range getListChildren(elements[i].Claim) {
+			if visited[childID] {
+				continue
+			}
+			visited[childID] = true
+			childElements := d.listElements(childID)
+			d.resolveChildren(childElements, visited)
+			elements[i].Children = append(elements[i].Children, List{
+				ID:       childID,
+				Elements: childElements,
+			})
+		}
+	}
+}
+
+// Lists returns claims for the property grouped into lists by their LIST
+// meta claims, each list sorted by ORDER meta claims. Children lists
+// (using CHILD meta claims) are resolved into a tree and are not returned
+// at the top level. Lists which are part of a longer cycle of children are
+// not returned at all, but they can be obtained with GetList. Each claim
+// which is not part of any list is returned as its own list with empty ID.
+// Lists are returned in the order of their first claim in the document.
+func (d *Document) Lists(propID Identifier) []List {
+	children := map[string]bool{}
+	for _, claim := range d.AllClaims() {
+		listID, _ := getListID(claim)
+		for _, childID := range getListChildren(claim) {
+			// We ignore lists which are their own children.
+			if childID != listID {
+				children[childID] = true
+			}
+		}
+	}
+
+	lists := []List{}
+	seen := map[string]bool{}
+	for _, claim := range d.Get(propID) {
+		listID, ok := getListID(claim)
+		if !ok {
+			lists = append(lists, List{
+				ID: "",
+				Elements: []ListElement{{
+					Claim:    claim,
+					Order:    getListOrder(claim),
+					Children: nil,
+				}},
+			})
+			continue
+		}
+		if seen[listID] || children[listID] {
+			continue
+		}
+		seen[listID] = true
+		lists = append(lists, List{
+			ID:       listID,
+			Elements: d.listElements(listID),
+		})
+	}
+
+	visited := map[string]bool{}
+	for _, list := range lists {
+		if list.ID != "" {
+			visited[list.ID] = true
+		}
+	}
+	for _, list := range lists {
+		d.resolveChildren(list.Elements, visited)
+	}
+
+	return lists
+}
+
+// GetList returns the list with the given ID, with children lists resolved.
+func (d *Document) GetList(listID string) List {
+	elements := d.listElements(listID)
+	d.resolveChildren(elements, map[string]bool{listID: true})
+	return List{
+		ID:       listID,
+		Elements: elements,
+	}
+}
+
+// addListMeta adds LIST and ORDER meta claims to the claim.
+func addListMeta(claim Claim, listID string, order float64) errors.E {
+	if _, ok := getListID(claim); ok {
+		return errors.Errorf(`claim "%s" is already part of a list`, claim.GetID())
+	}
+	listProp, err := StandardProperties.Reference("LIST")
+	if err != nil {
+		return err
+	}
+	orderProp, err := StandardProperties.Reference("ORDER")
+	if err != nil {
+		return err
+	}
+	err = claim.AddMeta(&IdentifierClaim{
+		CoreClaim: CoreClaim{
+			ID:         GetID(nameSpaceLists, claim.GetID(), "LIST", 0),
+			Confidence: claim.GetConfidence(),
+		},
+		Prop:       listProp,
+		Identifier: listID,
+	})
+	if err != nil {
+		return err
+	}
+	return claim.AddMeta(&AmountClaim{
+		CoreClaim: CoreClaim{
+			ID:         GetID(nameSpaceLists, claim.GetID(), "ORDER", 0),
+			Confidence: claim.GetConfidence(),
+		},
+		Prop:   orderProp,
+		Amount: order,
+		Unit:   AmountUnitNone,
+	})
+}
+
+// AppendToList adds the claim to the document as the last element of the list.
+// LIST and ORDER meta claims are added to the claim.
+func (d *Document) AppendToList(listID string, claim Claim) errors.E {
+	return d.InsertIntoList(listID, -1, claim)
+}
+
+// InsertIntoList adds the claim to the document as an element of the list at the index.
+// If index is negative or past the end of the list, the claim is appended to the list.
+// LIST and ORDER meta claims are added to the claim. The claim's order is chosen between
+// orders of its neighbors. Only when there is no such order (because of limited precision
+// of float64) existing elements of the list are renumbered.
+func (d *Document) InsertIntoList(listID string, index int, claim Claim) errors.E {
+	// We only consider elements with order.
+	elements := []ListElement{}
+	for _, element := range d.listElements(listID) {
+		if element.Order != nil {
+			elements = append(elements, element)
+		}
+	}
+
+	var order float64
+	switch {
+	case len(elements) == 0:
+		order = 0
+	case index < 0 || index >= len(elements):
+		order = *elements[len(elements)-1].Order + 1
+	case index == 0:
+		order = *elements[0].Order - 1
+	default:
+		prev, next := *elements[index-1].Order, *elements[index].Order
+		order = (prev + next) / 2 //nolint:gomnd
+		if order == prev || order == next {
+			// There is no float64 between neighbors, so we renumber all elements.
+			for i, element := range elements {
+				setListOrder(element.Claim, float64(i))
+			}
+			order = float64(index) - 0.5 //nolint:gomnd
+		}
+	}
+
+	err := addListMeta(claim, listID, order)
+	if err != nil {
+		return err
+	}
+	return d.Add(claim)
+}
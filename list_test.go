@@ -0,0 +1,143 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/peerdb/search"
+	"gitlab.com/peerdb/search/identifier"
+)
+
+func previewClaim(iri string) *search.ReferenceClaim {
+	return &search.ReferenceClaim{
+		CoreClaim: search.CoreClaim{
+			ID:         search.Identifier(identifier.NewRandom()),
+			Confidence: 1.0,
+		},
+		Prop: search.GetStandardPropertyReference("PREVIEW_URL"),
+		IRI:  iri,
+	}
+}
+
+func listIRIs(t *testing.T, list search.List) []string {
+	t.Helper()
+
+	iris := []string{}
+	for _, claim := range list.Claims() {
+		c, ok := claim.(*search.ReferenceClaim)
+		require.True(t, ok)
+		iris = append(iris, c.IRI)
+	}
+	return iris
+}
+
+func TestLists(t *testing.T) {
+	doc := search.Document{}
+
+	for _, iri := range []string{"a", "b", "c"} {
+		errE := doc.AppendToList("first", previewClaim(iri))
+		require.NoError(t, errE)
+	}
+	errE := doc.InsertIntoList("first", 0, previewClaim("0"))
+	require.NoError(t, errE)
+	errE = doc.InsertIntoList("first", 2, previewClaim("a2"))
+	require.NoError(t, errE)
+
+	errE = doc.Add(previewClaim("single"))
+	require.NoError(t, errE)
+
+	errE = doc.AppendToList("second", previewClaim("x"))
+	require.NoError(t, errE)
+
+	parent := previewClaim("y")
+	errE = parent.AddMeta(&search.IdentifierClaim{
+		CoreClaim: search.CoreClaim{
+			ID:         search.Identifier(identifier.NewRandom()),
+			Confidence: 1.0,
+		},
+		Prop:       search.GetStandardPropertyReference("CHILD"),
+		Identifier: "child",
+	})
+	require.NoError(t, errE)
+	errE = doc.AppendToList("second", parent)
+	require.NoError(t, errE)
+	errE = doc.AppendToList("child", previewClaim("y2"))
+	require.NoError(t, errE)
+	errE = doc.InsertIntoList("child", 0, previewClaim("y1"))
+	require.NoError(t, errE)
+
+	lists := doc.Lists(search.GetStandardPropertyID("PREVIEW_URL"))
+	require.Len(t, lists, 3)
+	assert.Equal(t, "first", lists[0].ID)
+	assert.Equal(t, []string{"0", "a", "a2", "b", "c"}, listIRIs(t, lists[0]))
+	assert.Equal(t, "", lists[1].ID)
+	assert.Equal(t, []string{"single"}, listIRIs(t, lists[1]))
+	assert.Equal(t, "second", lists[2].ID)
+	assert.Equal(t, []string{"x", "y", "y1", "y2"}, listIRIs(t, lists[2]))
+	require.Len(t, lists[2].Elements[1].Children, 1)
+	assert.Equal(t, "child", lists[2].Elements[1].Children[0].ID)
+
+	assert.Equal(t, []string{"y1", "y2"}, listIRIs(t, doc.GetList("child")))
+
+	// A claim can be only in one list.
+	claim := previewClaim("z")
+	errE = doc.AppendToList("first", claim)
+	require.NoError(t, errE)
+	errE = doc.AppendToList("second", claim)
+	assert.Error(t, errE)
+}
+
+func TestListsCycle(t *testing.T) {
+	doc := search.Document{}
+
+	claim := previewClaim("a")
+	errE := claim.AddMeta(&search.IdentifierClaim{
+		CoreClaim: search.CoreClaim{
+			ID:         search.Identifier(identifier.NewRandom()),
+			Confidence: 1.0,
+		},
+		Prop:       search.GetStandardPropertyReference("CHILD"),
+		Identifier: "list",
+	})
+	require.NoError(t, errE)
+	errE = doc.AppendToList("list", claim)
+	require.NoError(t, errE)
+
+	// A list which is its own child is returned, but its child is not resolved again.
+	lists := doc.Lists(search.GetStandardPropertyID("PREVIEW_URL"))
+	require.Len(t, lists, 1)
+	assert.Equal(t, []string{"a"}, listIRIs(t, lists[0]))
+	assert.Empty(t, lists[0].Elements[0].Children)
+	assert.Equal(t, []string{"a"}, listIRIs(t, doc.GetList("list")))
+}
+
+func TestInsertIntoListRenumber(t *testing.T) {
+	doc := search.Document{}
+
+	errE := doc.AppendToList("list", previewClaim("first"))
+	require.NoError(t, errE)
+	errE = doc.AppendToList("list", previewClaim("last"))
+	require.NoError(t, errE)
+
+	// Repeatedly inserting just before the last element halves the gap between
+	// neighbors until there is no float64 between them anymore.
+	expected := []string{"first"}
+	for i := 0; i < 100; i++ {
+		iri := string(rune('a'+i%26)) + string(rune('a'+i/26))
+		errE = doc.InsertIntoList("list", i+1, previewClaim(iri))
+		require.NoError(t, errE)
+		expected = append(expected, iri)
+	}
+	expected = append(expected, "last")
+
+	assert.Equal(t, expected, listIRIs(t, doc.GetList("list")))
+
+	orders := map[float64]bool{}
+	for _, element := range doc.GetList("list").Elements {
+		require.NotNil(t, element.Order)
+		assert.False(t, orders[*element.Order], "duplicate order %f", *element.Order)
+		orders[*element.Order] = true
+	}
+}
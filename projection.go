@@ -0,0 +1,265 @@
+package search
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+
+	"gitlab.com/tozd/go/errors"
+
+	"gitlab.com/peerdb/search/identifier"
+)
+
+// claimTypeNames returns JSON names of all claim types, in order of ClaimTypes fields.
+func claimTypeNames() []string {
+	names := []string{}
+	t := reflect.TypeOf(ClaimTypes{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		names = append(names, name)
+	}
+	return names
+}
+
+// Projection describes which parts of a document should be returned.
+// Zero value returns nothing but the core document, use FullProjection
+// for the projection which returns the whole document.
+type Projection struct {
+	// ClaimTypes are JSON names of claim types to include (e.g., "id", "rel").
+	// If empty, all claim types are included.
+	ClaimTypes []string
+	// Props are IDs of properties for which to include claims.
+	// If empty, claims for all properties are included.
+	Props []Identifier
+	// Active includes active claims.
+	Active bool
+	// Inactive includes inactive claims.
+	Inactive bool
+	// Meta includes meta claims.
+	Meta bool
+}
+
+// FullProjection returns the projection which includes the whole document.
+func FullProjection() Projection {
+	return Projection{
+		ClaimTypes: nil,
+		Props:      nil,
+		Active:     true,
+		Inactive:   true,
+		Meta:       true,
+	}
+}
+
+// splitValues returns all comma-separated non-empty values for the key.
+func splitValues(form url.Values, key string) []string {
+	values := []string{}
+	for _, value := range form[key] {
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			if v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// getProjection returns the projection requested with "types" (comma-separated claim types),
+// "props" (comma-separated property IDs), "claims" ("active" or "inactive"), and "meta"
+// ("true" or "false") parameters. Missing parameters do not limit the projection.
+func getProjection(form url.Values) (Projection, errors.E) {
+	projection := FullProjection()
+
+	validTypes := map[string]bool{}
+	for _, name := range claimTypeNames() {
+		validTypes[name] = true
+	}
+	for _, claimType := range splitValues(form, "types") {
+		if !validTypes[claimType] {
+			errE := errors.New("invalid claim type")
+			errors.Details(errE)["type"] = claimType
+			return projection, errE
+		}
+		projection.ClaimTypes = append(projection.ClaimTypes, claimType)
+	}
+
+	for _, prop := range splitValues(form, "props") {
+		if !identifier.Valid(prop) {
+			errE := errors.New("invalid property ID")
+			errors.Details(errE)["prop"] = prop
+			return projection, errE
+		}
+		projection.Props = append(projection.Props, Identifier(prop))
+	}
+
+	switch claims := form.Get("claims"); claims {
+	case "":
+	case "active":
+		projection.Inactive = false
+	case "inactive":
+		projection.Active = false
+	default:
+		errE := errors.New("invalid claims")
+		errors.Details(errE)["claims"] = claims
+		return projection, errE
+	}
+
+	switch meta := form.Get("meta"); meta {
+	case "", "true":
+	case "false":
+		projection.Meta = false
+	default:
+		errE := errors.New("invalid meta")
+		errors.Details(errE)["meta"] = meta
+		return projection, errE
+	}
+
+	return projection, nil
+}
+
+// IsFull returns true if the projection includes the whole document.
+func (p Projection) IsFull() bool {
+	return len(p.ClaimTypes) == 0 && len(p.Props) == 0 && p.Active && p.Inactive && p.Meta
+}
+
+// sourceFilter returns ElasticSearch source includes and excludes which
+// implement the projection as much as possible. Filtering by properties
+// cannot be done with source filtering.
+func (p Projection) sourceFilter() ([]string, []string) {
+	if p.IsFull() {
+		return nil, nil
+	}
+
+	fields := []string{}
+	if p.Active {
+		fields = append(fields, "active")
+	}
+	if p.Inactive {
+		fields = append(fields, "inactive")
+	}
+
+	includes := []string{"name", "score", "scores", "mnemonic"}
+	excludes := []string{}
+	for _, field := range fields {
+		if len(p.ClaimTypes) == 0 {
+			includes = append(includes, field+".*")
+		} else {
+			for _, claimType := range p.ClaimTypes {
+				includes = append(includes, field+"."+claimType)
+			}
+		}
+		if !p.Meta {
+			excludes = append(excludes, field+".*.meta")
+		}
+	}
+	return includes, excludes
+}
+
+type projectionVisitor struct {
+	ClaimTypes map[string]bool
+	Props      map[Identifier]bool
+	Meta       bool
+}
+
+func (v *projectionVisitor) visit(claimType string, prop Identifier, claim *CoreClaim) VisitResult {
+	if len(v.ClaimTypes) > 0 && !v.ClaimTypes[claimType] {
+		return Drop
+	}
+	if len(v.Props) > 0 && !v.Props[prop] {
+		return Drop
+	}
+	if !v.Meta {
+		claim.Meta = nil
+	}
+	return Keep
+}
+
+func (v *projectionVisitor) VisitIdentifier(claim *IdentifierClaim) (VisitResult, errors.E) {
+	return v.visit("id", claim.Prop.ID, &claim.CoreClaim), nil
+}
+
+func (v *projectionVisitor) VisitReference(claim *ReferenceClaim) (VisitResult, errors.E) {
+	return v.visit("ref", claim.Prop.ID, &claim.CoreClaim), nil
+}
+
+func (v *projectionVisitor) VisitText(claim *TextClaim) (VisitResult, errors.E) {
+	return v.visit("text", claim.Prop.ID, &claim.CoreClaim), nil
+}
+
+func (v *projectionVisitor) VisitString(claim *StringClaim) (VisitResult, errors.E) {
+	return v.visit("string", claim.Prop.ID, &claim.CoreClaim), nil
+}
+
+func (v *projectionVisitor) VisitAmount(claim *AmountClaim) (VisitResult, errors.E) {
+	return v.visit("amount", claim.Prop.ID, &claim.CoreClaim), nil
+}
+
+func (v *projectionVisitor) VisitAmountRange(claim *AmountRangeClaim) (VisitResult, errors.E) {
+	return v.visit("amountRange", claim.Prop.ID, &claim.CoreClaim), nil
+}
+
+func (v *projectionVisitor) VisitEnumeration(claim *EnumerationClaim) (VisitResult, errors.E) {
+	return v.visit("enum", claim.Prop.ID, &claim.CoreClaim), nil
+}
+
+func (v *projectionVisitor) VisitRelation(claim *RelationClaim) (VisitResult, errors.E) {
+	return v.visit("rel", claim.Prop.ID, &claim.CoreClaim), nil
+}
+
+func (v *projectionVisitor) VisitFile(claim *FileClaim) (VisitResult, errors.E) {
+	return v.visit("file", claim.Prop.ID, &claim.CoreClaim), nil
+}
+
+func (v *projectionVisitor) VisitNoValue(claim *NoValueClaim) (VisitResult, errors.E) {
+	return v.visit("none", claim.Prop.ID, &claim.CoreClaim), nil
+}
+
+func (v *projectionVisitor) VisitUnknownValue(claim *UnknownValueClaim) (VisitResult, errors.E) {
+	return v.visit("unknown", claim.Prop.ID, &claim.CoreClaim), nil
+}
+
+func (v *projectionVisitor) VisitTime(claim *TimeClaim) (VisitResult, errors.E) {
+	return v.visit("time", claim.Prop.ID, &claim.CoreClaim), nil
+}
+
+func (v *projectionVisitor) VisitTimeRange(claim *TimeRangeClaim) (VisitResult, errors.E) {
+	return v.visit("timeRange", claim.Prop.ID, &claim.CoreClaim), nil
+}
+
+// Project modifies the document so that it contains only claims included by the projection.
+func (d *Document) Project(projection Projection) errors.E {
+	if projection.IsFull() {
+		return nil
+	}
+
+	if !projection.Active {
+		d.Active = nil
+	}
+	if !projection.Inactive {
+		d.Inactive = nil
+	}
+
+	v := projectionVisitor{
+		ClaimTypes: map[string]bool{},
+		Props:      map[Identifier]bool{},
+		Meta:       projection.Meta,
+	}
+	for _, claimType := range projection.ClaimTypes {
+		v.ClaimTypes[claimType] = true
+	}
+	for _, prop := range projection.Props {
+		v.Props[prop] = true
+	}
+	errE := d.Visit(&v)
+	if errE != nil {
+		return errE
+	}
+
+	if d.Active != nil && d.Active.Size() == 0 {
+		d.Active = nil
+	}
+	if d.Inactive != nil && d.Inactive.Size() == 0 {
+		d.Inactive = nil
+	}
+	return nil
+}
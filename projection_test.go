@@ -0,0 +1,92 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/peerdb/search"
+)
+
+func projectionTestDocument() search.Document {
+	return search.Document{
+		CoreDocument: search.CoreDocument{
+			ID:    "XkbTJqwFCFkfoxMBXow4HU",
+			Name:  search.Name{"en": "Name"},
+			Score: 0.5,
+		},
+		Active: &search.ClaimTypes{
+			Identifier: search.IdentifierClaims{
+				{
+					CoreClaim: search.CoreClaim{
+						ID:         "A8uA1GpBmqp9m8HMY1EUYz",
+						Confidence: 1.0,
+						Meta: &search.ClaimTypes{
+							NoValue: search.NoValueClaims{
+								{
+									CoreClaim: search.CoreClaim{ID: "B8uA1GpBmqp9m8HMY1EUYz", Confidence: 1.0},
+									Prop:      search.GetStandardPropertyReference("ARTICLE"),
+								},
+							},
+						},
+					},
+					Prop:       search.GetStandardPropertyReference("LIST"),
+					Identifier: "list",
+				},
+			},
+			Reference: search.ReferenceClaims{
+				{
+					CoreClaim: search.CoreClaim{ID: "C8uA1GpBmqp9m8HMY1EUYz", Confidence: 1.0},
+					Prop:      search.GetStandardPropertyReference("FILE_URL"),
+					IRI:       "https://example.com",
+				},
+			},
+		},
+		Inactive: &search.ClaimTypes{
+			NoValue: search.NoValueClaims{
+				{
+					CoreClaim: search.CoreClaim{ID: "D8uA1GpBmqp9m8HMY1EUYz", Confidence: 0.0},
+					Prop:      search.GetStandardPropertyReference("ARTICLE"),
+				},
+			},
+		},
+	}
+}
+
+func TestProject(t *testing.T) {
+	doc := projectionTestDocument()
+	errE := doc.Project(search.FullProjection())
+	require.NoError(t, errE)
+	assert.Equal(t, projectionTestDocument(), doc)
+
+	doc = projectionTestDocument()
+	projection := search.FullProjection()
+	projection.ClaimTypes = []string{"ref", "none"}
+	errE = doc.Project(projection)
+	require.NoError(t, errE)
+	assert.Empty(t, doc.Active.Identifier)
+	assert.Len(t, doc.Active.Reference, 1)
+	assert.Len(t, doc.Inactive.NoValue, 1)
+
+	doc = projectionTestDocument()
+	projection = search.FullProjection()
+	projection.Props = []search.Identifier{search.GetStandardPropertyID("LIST")}
+	projection.Meta = false
+	projection.Inactive = false
+	errE = doc.Project(projection)
+	require.NoError(t, errE)
+	assert.Nil(t, doc.Inactive)
+	require.Len(t, doc.Active.Identifier, 1)
+	assert.Empty(t, doc.Active.Reference)
+	assert.Nil(t, doc.Active.Identifier[0].Meta)
+
+	doc = projectionTestDocument()
+	projection = search.FullProjection()
+	projection.Props = []search.Identifier{search.GetStandardPropertyID("ARTICLE")}
+	errE = doc.Project(projection)
+	require.NoError(t, errE)
+	// Filtering by properties does not apply to meta claims.
+	assert.Nil(t, doc.Active)
+	assert.Len(t, doc.Inactive.NoValue, 1)
+}
@@ -43,6 +43,36 @@ var (
 			"The entity is a label.",
 			[]string{`"relation" claim type`},
 		},
+		{
+			"subproperty of",
+			"The property is a subproperty of another property. Every claim with the property implies also a claim with the other property.",
+			[]string{`"relation" claim type`},
+		},
+		{
+			"instance of class",
+			"The entity is an instance of the class, directly or through a chain of subclass relations.",
+			[]string{`"relation" claim type`},
+		},
+		{
+			"subclass of class",
+			"The entity is a subclass of the class, directly or through a chain of subclass relations.",
+			[]string{`"relation" claim type`},
+		},
+		{
+			"alias",
+			"A previous identifier of the entity, e.g., of an entity which has been merged into it or renamed. Requests for the previous identifier are redirected to the entity.",
+			[]string{`"identifier" claim type`},
+		},
+		{
+			"slug",
+			"Human-readable identifier of the entity used in its URL, made from its name.",
+			[]string{`"identifier" claim type`},
+		},
+		{
+			"previous slug",
+			"A slug which the entity had before. Requests for the slug are redirected to the entity.",
+			[]string{`"identifier" claim type`},
+		},
 		{
 			"property",
 			"The entity is a property.",
@@ -73,6 +103,16 @@ var (
 			"Unit associated with the amount.",
 			nil,
 		},
+		{
+			"original amount",
+			"Amount as it was before it was converted to the standard unit.",
+			[]string{`"amount" claim type`},
+		},
+		{
+			"calendar",
+			"Calendar in which the time was originally specified. Times are stored in the proleptic Gregorian calendar.",
+			[]string{`"relation" claim type`},
+		},
 		{
 			"claim type",
 			"The property maps to a supported claim type.",
@@ -252,25 +292,23 @@ var (
 		},
 	}
 
-	nameSpaceStandardProperties = uuid.MustParse("34cd10b4-5731-46b8-a6dd-45444680ca62")
-
-	// TODO: Use sync.Map?
+	nameSpaceStandardProperties = identifier.RegisterNamespace("StandardProperties", uuid.MustParse("34cd10b4-5731-46b8-a6dd-45444680ca62"))
 
-	// StandardProperties is a map from a standard property ID to a document describing it.
-	StandardProperties = map[string]Document{}
+	// StandardProperties is a registry of standard properties.
+	StandardProperties = NewPropertyRegistry()
 )
 
+// GetStandardPropertyReference returns a reference to the standard property with the mnemonic.
+//
+// It panics if the property cannot be found, so it should be used only with literal mnemonics
+// of builtin properties. Importers and any code which constructs mnemonics at runtime should
+// use StandardProperties.Reference and handle the error instead.
 func GetStandardPropertyReference(mnemonic string) DocumentReference {
-	property, ok := StandardProperties[string(GetStandardPropertyID(mnemonic))]
-	if !ok {
-		panic(errors.Errorf(`standard property for mnemonic "%s" cannot be found`, mnemonic))
-	}
-	return DocumentReference{
-		ID:     property.ID,
-		Name:   property.Name,
-		Score:  property.Score,
-		Scores: property.Scores,
+	reference, errE := StandardProperties.Reference(Mnemonic(mnemonic))
+	if errE != nil {
+		panic(errE)
 	}
+	return reference
 }
 
 func getMnemonic(data string) string {
@@ -278,13 +316,12 @@ func getMnemonic(data string) string {
 }
 
 func GetID(namespace uuid.UUID, args ...interface{}) Identifier {
-	res := namespace
-	for _, arg := range args {
-		res = uuid.NewSHA1(res, []byte(fmt.Sprint(arg)))
-	}
-	return Identifier(identifier.FromUUID(res))
+	return Identifier(identifier.FromNamespace(namespace, args...))
 }
 
+// GetStandardPropertyID returns the ID of the standard property with the mnemonic.
+//
+// It does not check that the property exists.
 func GetStandardPropertyID(mnemonic string) Identifier {
 	return GetID(nameSpaceStandardProperties, mnemonic)
 }
@@ -296,169 +333,122 @@ func getPropertyClaimID(propertyMnemonic, claimMnemonic string, i int, args ...i
 	return GetID(nameSpaceStandardProperties, a...)
 }
 
-func populateStandardProperties() {
-	for _, builtinProperty := range builtinProperties {
-		mnemonic := getMnemonic(builtinProperty.Name)
-		id := string(GetStandardPropertyID(mnemonic))
-		StandardProperties[id] = Document{
-			CoreDocument: CoreDocument{
-				ID: Identifier(id),
-				Name: Name{
-					"en": builtinProperty.Name,
-				},
-				Score: 0.0,
+// newStandardProperty creates a document describing a standard property.
+func newStandardProperty(mnemonic, name, descriptionHTML string, is []string) Document {
+	id := GetStandardPropertyID(mnemonic)
+	property := Document{
+		CoreDocument: CoreDocument{
+			ID: id,
+			Name: Name{
+				"en": name,
 			},
-			Mnemonic: Mnemonic(mnemonic),
-			Active: &ClaimTypes{
-				Text: TextClaims{
-					{
-						CoreClaim: CoreClaim{
-							ID:         getPropertyClaimID(mnemonic, "DESCRIPTION", 0),
-							Confidence: 1.0,
-						},
-						Prop: DocumentReference{
-							ID: GetStandardPropertyID("DESCRIPTION"),
-							Name: Name{
-								"en": "description",
-							},
-							Score: 0.0,
-						},
-						HTML: TranslatableHTMLString{
-							"en": builtinProperty.DescriptionHTML,
+			Score: 0.0,
+		},
+		Mnemonic: Mnemonic(mnemonic),
+		Active: &ClaimTypes{
+			Text: TextClaims{
+				{
+					CoreClaim: CoreClaim{
+						ID:         getPropertyClaimID(mnemonic, "DESCRIPTION", 0),
+						Confidence: 1.0,
+					},
+					Prop: DocumentReference{
+						ID: GetStandardPropertyID("DESCRIPTION"),
+						Name: Name{
+							"en": "description",
 						},
+						Score: 0.0,
+					},
+					HTML: TranslatableHTMLString{
+						"en": descriptionHTML,
 					},
 				},
-				Relation: RelationClaims{
-					{
-						CoreClaim: CoreClaim{
-							ID:         getPropertyClaimID(mnemonic, "IS", 0, "PROPERTY", 0),
-							Confidence: 1.0,
-						},
-						Prop: DocumentReference{
-							ID: GetStandardPropertyID("IS"),
-							Name: Name{
-								"en": "is",
-							},
-							Score: 0.0,
+			},
+			Relation: RelationClaims{
+				{
+					CoreClaim: CoreClaim{
+						ID:         getPropertyClaimID(mnemonic, "IS", 0, "PROPERTY", 0),
+						Confidence: 1.0,
+					},
+					Prop: DocumentReference{
+						ID: GetStandardPropertyID("IS"),
+						Name: Name{
+							"en": "is",
 						},
-						To: DocumentReference{
-							ID: GetStandardPropertyID("PROPERTY"),
-							Name: Name{
-								"en": "property",
-							},
-							Score: 0.0,
+						Score: 0.0,
+					},
+					To: DocumentReference{
+						ID: GetStandardPropertyID("PROPERTY"),
+						Name: Name{
+							"en": "property",
 						},
+						Score: 0.0,
 					},
 				},
 			},
-		}
+		},
+	}
 
-		activeClaimTypes := StandardProperties[id].Active
-		for _, isClaim := range builtinProperty.Is {
-			isClaimMnemonic := getMnemonic(isClaim)
-			activeClaimTypes.Relation = append(activeClaimTypes.Relation, RelationClaim{
-				CoreClaim: CoreClaim{
-					ID:         getPropertyClaimID(mnemonic, "IS", 0, isClaimMnemonic, 0),
-					Confidence: 1.0,
-				},
-				Prop: DocumentReference{
-					ID: GetStandardPropertyID("IS"),
-					Name: Name{
-						"en": "is",
-					},
-					Score: 0.0,
+	for _, isClaim := range is {
+		isClaimMnemonic := getMnemonic(isClaim)
+		property.Active.Relation = append(property.Active.Relation, RelationClaim{
+			CoreClaim: CoreClaim{
+				ID:         getPropertyClaimID(mnemonic, "IS", 0, isClaimMnemonic, 0),
+				Confidence: 1.0,
+			},
+			Prop: DocumentReference{
+				ID: GetStandardPropertyID("IS"),
+				Name: Name{
+					"en": "is",
 				},
-				To: DocumentReference{
-					ID: GetStandardPropertyID(isClaimMnemonic),
-					Name: Name{
-						"en": isClaim,
-					},
-					Score: 0.0,
+				Score: 0.0,
+			},
+			To: DocumentReference{
+				ID: GetStandardPropertyID(isClaimMnemonic),
+				Name: Name{
+					"en": isClaim,
 				},
-			})
+				Score: 0.0,
+			},
+		})
+	}
+
+	return property
+}
+
+func getClaimTypeName(claimType string) string {
+	return fmt.Sprintf(`"%s" claim type`, claimType)
+}
+
+func populateStandardProperties(registry *PropertyRegistry) errors.E {
+	for _, builtinProperty := range builtinProperties {
+		mnemonic := getMnemonic(builtinProperty.Name)
+		errE := registry.Register(newStandardProperty(
+			mnemonic, builtinProperty.Name, builtinProperty.DescriptionHTML, builtinProperty.Is,
+		))
+		if errE != nil {
+			return errE
 		}
+	}
 
-		for _, claimType := range claimTypes {
-			name := fmt.Sprintf(`"%s" claim type`, claimType)
-			mnemonic := getMnemonic(name)
-			id := string(GetStandardPropertyID(mnemonic))
-			description := fmt.Sprintf(`The property is useful with the "%s" claim type.`, claimType)
-			StandardProperties[id] = Document{
-				CoreDocument: CoreDocument{
-					ID: Identifier(id),
-					Name: Name{
-						"en": name,
-					},
-					Score: 0.0,
-				},
-				Mnemonic: Mnemonic(mnemonic),
-				Active: &ClaimTypes{
-					Text: TextClaims{
-						{
-							CoreClaim: CoreClaim{
-								ID:         getPropertyClaimID(mnemonic, "DESCRIPTION", 0),
-								Confidence: 1.0,
-							},
-							Prop: DocumentReference{
-								ID: GetStandardPropertyID("DESCRIPTION"),
-								Name: Name{
-									"en": "description",
-								},
-								Score: 0.0,
-							},
-							HTML: TranslatableHTMLString{
-								"en": html.EscapeString(description),
-							},
-						},
-					},
-					Relation: RelationClaims{
-						{
-							CoreClaim: CoreClaim{
-								ID:         getPropertyClaimID(mnemonic, "IS", 0, "PROPERTY", 0),
-								Confidence: 1.0,
-							},
-							Prop: DocumentReference{
-								ID: GetStandardPropertyID("IS"),
-								Name: Name{
-									"en": "is",
-								},
-								Score: 0.0,
-							},
-							To: DocumentReference{
-								ID: GetStandardPropertyID("PROPERTY"),
-								Name: Name{
-									"en": "property",
-								},
-								Score: 0.0,
-							},
-						},
-						{
-							CoreClaim: CoreClaim{
-								ID:         getPropertyClaimID(mnemonic, "IS", 0, "CLAIM_TYPE", 0),
-								Confidence: 1.0,
-							},
-							Prop: DocumentReference{
-								ID: GetStandardPropertyID("IS"),
-								Name: Name{
-									"en": "is",
-								},
-								Score: 0.0,
-							},
-							To: DocumentReference{
-								ID: GetStandardPropertyID("CLAIM_TYPE"),
-								Name: Name{
-									"en": "claim type",
-								},
-								Score: 0.0,
-							},
-						},
-					},
-				},
-			}
+	for _, claimType := range claimTypes {
+		name := getClaimTypeName(claimType)
+		mnemonic := getMnemonic(name)
+		description := fmt.Sprintf(`The property is useful with the "%s" claim type.`, claimType)
+		errE := registry.Register(newStandardProperty(
+			mnemonic, name, html.EscapeString(description), []string{"claim type"},
+		))
+		if errE != nil {
+			return errE
 		}
 	}
+
+	return nil
 }
 
 func init() {
-	populateStandardProperties()
+	errE := populateStandardProperties(StandardProperties)
+	if errE != nil {
+		panic(errE)
+	}
 }
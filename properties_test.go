@@ -0,0 +1,75 @@
+package search_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/peerdb/search"
+)
+
+// loadStandardPropertiesRun makes names of properties loaded into the global
+// registry unique, so that the test can run multiple times.
+var loadStandardPropertiesRun = 0
+
+func TestLoadStandardProperties(t *testing.T) {
+	loadStandardPropertiesRun++
+	run := loadStandardPropertiesRun
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "properties.yaml")
+	err := os.WriteFile(yamlPath, []byte(fmt.Sprintf(`
+- name: test yaml property %[1]d
+  descriptionHtml: A <b>test</b> property.
+  is:
+    - '"string" claim type'
+- name: test yaml other property %[1]d
+  descriptionHtml: Another test property.
+  mnemonic: TEST_YAML_OTHER_%[1]d
+  is:
+    - test yaml property %[1]d
+`, run)), 0o600)
+	require.NoError(t, err)
+
+	size := search.StandardProperties.Len()
+
+	errE := search.StandardProperties.Load(yamlPath)
+	require.NoError(t, errE)
+	assert.Equal(t, size+2, search.StandardProperties.Len())
+
+	id := search.GetStandardPropertyID(fmt.Sprintf("TEST_YAML_PROPERTY_%d", run))
+	property, errE := search.StandardProperties.Get(id)
+	require.NoError(t, errE)
+	assert.Equal(t, search.Mnemonic(fmt.Sprintf("TEST_YAML_PROPERTY_%d", run)), property.Mnemonic)
+	assert.Equal(t, search.Name{"en": fmt.Sprintf("test yaml property %d", run)}, property.Name)
+	assert.Equal(t, search.TranslatableHTMLString{"en": "A <b>test</b> property."}, property.Active.Text[0].HTML)
+	require.Len(t, property.Active.Relation, 2)
+	assert.Equal(t, search.GetStandardPropertyID("PROPERTY"), property.Active.Relation[0].To.ID)
+	assert.Equal(t, search.GetStandardPropertyID("STRING_CLAIM_TYPE"), property.Active.Relation[1].To.ID)
+
+	other, errE := search.StandardProperties.Get(search.GetStandardPropertyID(fmt.Sprintf("TEST_YAML_OTHER_%d", run)))
+	require.NoError(t, errE)
+	assert.Equal(t, id, other.Active.Relation[1].To.ID)
+
+	// Loaded properties are listed together with built-in ones.
+	stringProperties, errE := search.StandardProperties.ListByClaimType("string")
+	require.NoError(t, errE)
+	assert.Contains(t, stringProperties, property)
+
+	jsonPath := filepath.Join(dir, "properties.json")
+	err = os.WriteFile(jsonPath, []byte(fmt.Sprintf(`[{"name": "test json property %d", "descriptionHtml": "A test property."}]`, run)), 0o600)
+	require.NoError(t, err)
+
+	errE = search.StandardProperties.Load(jsonPath)
+	require.NoError(t, errE)
+	_, errE = search.StandardProperties.Get(search.GetStandardPropertyID(fmt.Sprintf("TEST_JSON_PROPERTY_%d", run)))
+	assert.NoError(t, errE)
+
+	// Loading the same properties again fails.
+	errE = search.StandardProperties.Load(jsonPath)
+	assert.Error(t, errE)
+}
@@ -0,0 +1,274 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// PropertyHierarchy is an in-memory hierarchy of properties, built from
+// SUBPROPERTY_OF claims of documents describing properties.
+//
+// It is safe for concurrent use.
+type PropertyHierarchy struct {
+	mu       sync.RWMutex
+	parents  map[Identifier][]DocumentReference
+	children map[Identifier][]Identifier
+}
+
+// NewPropertyHierarchy returns a new empty property hierarchy.
+func NewPropertyHierarchy() *PropertyHierarchy {
+	return &PropertyHierarchy{
+		mu:       sync.RWMutex{},
+		parents:  map[Identifier][]DocumentReference{},
+		children: map[Identifier][]Identifier{},
+	}
+}
+
+// Add records that the child property is a subproperty of the parent property.
+// Adding the same relation multiple times and relations of a property to itself are ignored.
+func (h *PropertyHierarchy) Add(child Identifier, parent DocumentReference) {
+	if child == parent.ID {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, p := range h.parents[child] {
+		if p.ID == parent.ID {
+			return
+		}
+	}
+	h.parents[child] = append(h.parents[child], DocumentReference{
+		ID:     parent.ID,
+		Name:   parent.Name,
+		Score:  parent.Score,
+		Scores: parent.Scores,
+		Claims: nil,
+	})
+	h.children[parent.ID] = append(h.children[parent.ID], child)
+}
+
+// AddDocument records all active SUBPROPERTY_OF claims of the document describing a property.
+func (h *PropertyHierarchy) AddDocument(property *Document) {
+	if property.Active == nil {
+		return
+	}
+	subpropertyOf := GetStandardPropertyID("SUBPROPERTY_OF")
+	for _, claim := range property.Active.Relation {
+		if claim.Prop.ID == subpropertyOf && claim.Confidence >= ActiveClaimThreshold {
+			h.Add(property.ID, claim.To)
+		}
+	}
+}
+
+// Parents returns references to direct parent properties of the property.
+func (h *PropertyHierarchy) Parents(id Identifier) []DocumentReference {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return append([]DocumentReference{}, h.parents[id]...)
+}
+
+// Children returns IDs of direct subproperties of the property.
+func (h *PropertyHierarchy) Children(id Identifier) []Identifier {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return append([]Identifier{}, h.children[id]...)
+}
+
+// Ancestors returns IDs of all (transitive) parent properties of the property,
+// closest first. The property itself is not included.
+func (h *PropertyHierarchy) Ancestors(id Identifier) []Identifier {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.traverse(id, func(id Identifier) []Identifier {
+		ids := make([]Identifier, 0, len(h.parents[id]))
+		for _, parent := range h.parents[id] {
+			ids = append(ids, parent.ID)
+		}
+		return ids
+	})
+}
+
+// Descendants returns IDs of all (transitive) subproperties of the property,
+// closest first. The property itself is not included.
+func (h *PropertyHierarchy) Descendants(id Identifier) []Identifier {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.traverse(id, func(id Identifier) []Identifier {
+		return h.children[id]
+	})
+}
+
+// traverse does a breadth-first traversal from the property using next
+// and returns visited properties. Cycles are visited only once.
+// The caller must hold the lock.
+func (h *PropertyHierarchy) traverse(id Identifier, next func(Identifier) []Identifier) []Identifier {
+	result := []Identifier{}
+	visited := map[Identifier]bool{id: true}
+	queue := []Identifier{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, n := range next(current) {
+			if visited[n] {
+				continue
+			}
+			visited[n] = true
+			result = append(result, n)
+			queue = append(queue, n)
+		}
+	}
+	return result
+}
+
+// ClaimGroup is a group of claims for a property and its direct subproperties.
+type ClaimGroup struct {
+	Prop   DocumentReference `json:"prop"`
+	Claims *ClaimTypes       `json:"claims"`
+}
+
+type groupVisitor struct {
+	Hierarchy *PropertyHierarchy
+	Groups    map[Identifier]*ClaimGroup
+}
+
+func (v *groupVisitor) add(prop DocumentReference, claim Claim) (VisitResult, errors.E) {
+	props := v.Hierarchy.Parents(prop.ID)
+	if len(props) == 0 {
+		props = []DocumentReference{prop}
+	}
+	for _, p := range props {
+		group, ok := v.Groups[p.ID]
+		if !ok {
+			group = &ClaimGroup{
+				Prop:   p,
+				Claims: nil,
+			}
+			v.Groups[p.ID] = group
+		} else if len(group.Prop.Name) == 0 {
+			// We prefer a reference with a name, if we have it.
+			group.Prop.Name = p.Name
+		}
+		document := Document{
+			CoreDocument: CoreDocument{},
+			Active:       group.Claims,
+		}
+		errE := document.Add(claim)
+		if errE != nil {
+			return Keep, errE
+		}
+		group.Claims = document.Active
+	}
+	return Keep, nil
+}
+
+func (v *groupVisitor) VisitIdentifier(claim *IdentifierClaim) (VisitResult, errors.E) {
+	return v.add(claim.Prop, claim)
+}
+
+func (v *groupVisitor) VisitReference(claim *ReferenceClaim) (VisitResult, errors.E) {
+	return v.add(claim.Prop, claim)
+}
+
+func (v *groupVisitor) VisitText(claim *TextClaim) (VisitResult, errors.E) {
+	return v.add(claim.Prop, claim)
+}
+
+func (v *groupVisitor) VisitString(claim *StringClaim) (VisitResult, errors.E) {
+	return v.add(claim.Prop, claim)
+}
+
+func (v *groupVisitor) VisitAmount(claim *AmountClaim) (VisitResult, errors.E) {
+	return v.add(claim.Prop, claim)
+}
+
+func (v *groupVisitor) VisitAmountRange(claim *AmountRangeClaim) (VisitResult, errors.E) {
+	return v.add(claim.Prop, claim)
+}
+
+func (v *groupVisitor) VisitEnumeration(claim *EnumerationClaim) (VisitResult, errors.E) {
+	return v.add(claim.Prop, claim)
+}
+
+func (v *groupVisitor) VisitRelation(claim *RelationClaim) (VisitResult, errors.E) {
+	return v.add(claim.Prop, claim)
+}
+
+func (v *groupVisitor) VisitFile(claim *FileClaim) (VisitResult, errors.E) {
+	return v.add(claim.Prop, claim)
+}
+
+func (v *groupVisitor) VisitNoValue(claim *NoValueClaim) (VisitResult, errors.E) {
+	return v.add(claim.Prop, claim)
+}
+
+func (v *groupVisitor) VisitUnknownValue(claim *UnknownValueClaim) (VisitResult, errors.E) {
+	return v.add(claim.Prop, claim)
+}
+
+func (v *groupVisitor) VisitTime(claim *TimeClaim) (VisitResult, errors.E) {
+	return v.add(claim.Prop, claim)
+}
+
+func (v *groupVisitor) VisitTimeRange(claim *TimeRangeClaim) (VisitResult, errors.E) {
+	return v.add(claim.Prop, claim)
+}
+
+// Group groups claims by their parent properties. Claims for properties without
+// a parent property are grouped by their own property. A claim for a property with
+// multiple parent properties is included in the group of every parent property.
+// Groups are sorted by property IDs.
+func (h *PropertyHierarchy) Group(claimTypes *ClaimTypes) ([]ClaimGroup, errors.E) {
+	if claimTypes == nil {
+		return []ClaimGroup{}, nil
+	}
+
+	v := groupVisitor{
+		Hierarchy: h,
+		Groups:    map[Identifier]*ClaimGroup{},
+	}
+	errE := claimTypes.Visit(&v)
+	if errE != nil {
+		return nil, errE
+	}
+
+	groups := make([]ClaimGroup, 0, len(v.Groups))
+	for _, group := range v.Groups {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Prop.ID < groups[j].Prop.ID
+	})
+	return groups, nil
+}
+
+// LoadPropertyHierarchy builds a property hierarchy from SUBPROPERTY_OF claims
+// of standard properties and of all documents in the backend.
+func LoadPropertyHierarchy(ctx context.Context, backend Backend) (*PropertyHierarchy, errors.E) {
+	h := NewPropertyHierarchy()
+
+	for _, property := range StandardProperties.List() {
+		property := property
+		h.AddDocument(&property)
+	}
+
+	query := Query{Text: "", Props: [][]Identifier{{GetStandardPropertyID("SUBPROPERTY_OF")}}, Classes: nil}
+	projection := Projection{ClaimTypes: []string{"rel"}, Props: nil, Active: true, Inactive: false, Meta: false}
+	errE := backend.Scroll(ctx, query, projection, func(document *Document, _ Version) errors.E {
+		h.AddDocument(document)
+		return nil
+	})
+	if errE != nil {
+		return nil, errE
+	}
+
+	return h, nil
+}
@@ -0,0 +1,129 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/peerdb/search"
+	"gitlab.com/peerdb/search/identifier"
+)
+
+func propertyReference(name string) search.DocumentReference {
+	return search.DocumentReference{
+		ID:     search.Identifier(identifier.NewRandom()),
+		Name:   search.Name{"en": name},
+		Score:  0.5,
+		Scores: nil,
+		Claims: nil,
+	}
+}
+
+func TestPropertyHierarchy(t *testing.T) {
+	location := propertyReference("location")
+	place := propertyReference("place")
+	birthPlace := propertyReference("place of birth")
+	deathPlace := propertyReference("place of death")
+
+	h := search.NewPropertyHierarchy()
+	h.Add(place.ID, location)
+	h.Add(birthPlace.ID, place)
+	h.Add(birthPlace.ID, place)
+	h.Add(deathPlace.ID, place)
+	h.Add(birthPlace.ID, birthPlace)
+	// A cycle.
+	h.Add(location.ID, birthPlace)
+
+	assert.Equal(t, []search.DocumentReference{place}, h.Parents(birthPlace.ID))
+	assert.Equal(t, []search.Identifier{birthPlace.ID, deathPlace.ID}, h.Children(place.ID))
+	assert.Equal(t, []search.Identifier{place.ID, location.ID}, h.Ancestors(birthPlace.ID))
+	assert.Equal(t, []search.Identifier{place.ID, birthPlace.ID, deathPlace.ID}, h.Descendants(location.ID))
+	assert.Empty(t, h.Descendants(search.Identifier(identifier.NewRandom())))
+}
+
+func TestPropertyHierarchyAddDocument(t *testing.T) {
+	parent := propertyReference("parent")
+	property := search.Document{
+		CoreDocument: search.CoreDocument{
+			ID:    search.Identifier(identifier.NewRandom()),
+			Name:  search.Name{"en": "child"},
+			Score: 0.5,
+		},
+		Active: &search.ClaimTypes{
+			Relation: search.RelationClaims{
+				{
+					CoreClaim: search.CoreClaim{
+						ID:         search.Identifier(identifier.NewRandom()),
+						Confidence: 1.0,
+					},
+					Prop: search.GetStandardPropertyReference("SUBPROPERTY_OF"),
+					To:   parent,
+				},
+				{
+					CoreClaim: search.CoreClaim{
+						ID:         search.Identifier(identifier.NewRandom()),
+						Confidence: 1.0,
+					},
+					Prop: search.GetStandardPropertyReference("IS"),
+					To:   search.GetStandardPropertyReference("PROPERTY"),
+				},
+			},
+		},
+	}
+
+	h := search.NewPropertyHierarchy()
+	h.AddDocument(&property)
+	assert.Equal(t, []search.DocumentReference{parent}, h.Parents(property.ID))
+	assert.Equal(t, []search.Identifier{property.ID}, h.Descendants(parent.ID))
+}
+
+func TestPropertyHierarchyGroup(t *testing.T) {
+	place := propertyReference("place")
+	birthPlace := propertyReference("place of birth")
+	deathPlace := propertyReference("place of death")
+	name := propertyReference("name")
+
+	h := search.NewPropertyHierarchy()
+	h.Add(birthPlace.ID, place)
+	h.Add(deathPlace.ID, place)
+
+	claimTypes := &search.ClaimTypes{
+		String: search.StringClaims{
+			{
+				CoreClaim: search.CoreClaim{ID: search.Identifier(identifier.NewRandom()), Confidence: 1.0},
+				Prop:      name,
+				String:    "Ada",
+			},
+		},
+		Relation: search.RelationClaims{
+			{
+				CoreClaim: search.CoreClaim{ID: search.Identifier(identifier.NewRandom()), Confidence: 1.0},
+				Prop:      birthPlace,
+				To:        propertyReference("London"),
+			},
+			{
+				CoreClaim: search.CoreClaim{ID: search.Identifier(identifier.NewRandom()), Confidence: 1.0},
+				Prop:      deathPlace,
+				To:        propertyReference("London"),
+			},
+		},
+	}
+
+	groups, errE := h.Group(claimTypes)
+	require.NoError(t, errE)
+	require.Len(t, groups, 2)
+
+	byProp := map[search.Identifier]search.ClaimGroup{}
+	for _, group := range groups {
+		byProp[group.Prop.ID] = group
+	}
+	assert.Equal(t, place, byProp[place.ID].Prop)
+	assert.Equal(t, claimTypes.Relation, byProp[place.ID].Claims.Relation)
+	assert.Empty(t, byProp[place.ID].Claims.String)
+	assert.Equal(t, claimTypes.String, byProp[name.ID].Claims.String)
+
+	groups, errE = h.Group(nil)
+	require.NoError(t, errE)
+	assert.Empty(t, groups)
+}
@@ -0,0 +1,281 @@
+package search
+
+import (
+	"os"
+	"sort"
+	"sync"
+
+	"gitlab.com/tozd/go/errors"
+	"gopkg.in/yaml.v3"
+
+	"gitlab.com/peerdb/search/identifier"
+)
+
+// PropertyDefinition describes an additional standard property.
+type PropertyDefinition struct {
+	// Name of the property in English.
+	Name string `json:"name" yaml:"name"`
+	// DescriptionHTML is a description of the property in English as HTML.
+	DescriptionHTML string `json:"descriptionHtml" yaml:"descriptionHtml"`
+	// Is contains names of standard properties to which the property is related
+	// with the IS relation (e.g., `"relation" claim type`).
+	Is []string `json:"is,omitempty" yaml:"is,omitempty"`
+	// Mnemonic of the property. If empty, it is derived from the name.
+	Mnemonic string `json:"mnemonic,omitempty" yaml:"mnemonic,omitempty"`
+}
+
+// PropertyRegistry is a registry of documents describing properties.
+//
+// It is safe for concurrent use. Documents returned from it are shared
+// and must not be modified.
+type PropertyRegistry struct {
+	mu         sync.RWMutex
+	properties map[Identifier]Document
+	mnemonics  map[Mnemonic]Identifier
+}
+
+// NewPropertyRegistry returns a new empty property registry.
+func NewPropertyRegistry() *PropertyRegistry {
+	return &PropertyRegistry{
+		mu:         sync.RWMutex{},
+		properties: map[Identifier]Document{},
+		mnemonics:  map[Mnemonic]Identifier{},
+	}
+}
+
+// check returns an error if the property cannot be registered.
+// The caller must hold the lock.
+func (r *PropertyRegistry) check(property *Document) errors.E {
+	if !identifier.Valid(string(property.ID)) {
+		errE := errors.New("invalid property ID")
+		errors.Details(errE)["id"] = string(property.ID)
+		return errE
+	}
+	if _, ok := r.properties[property.ID]; ok {
+		errE := errors.New("property already exists")
+		errors.Details(errE)["id"] = string(property.ID)
+		return errE
+	}
+	if property.Mnemonic != "" {
+		if _, ok := r.mnemonics[property.Mnemonic]; ok {
+			errE := errors.New("property mnemonic already exists")
+			errors.Details(errE)["mnemonic"] = string(property.Mnemonic)
+			return errE
+		}
+	}
+	return nil
+}
+
+// set stores the property. The caller must hold the lock.
+func (r *PropertyRegistry) set(property Document) {
+	r.properties[property.ID] = property
+	if property.Mnemonic != "" {
+		r.mnemonics[property.Mnemonic] = property.ID
+	}
+}
+
+// Register adds a document describing a property to the registry.
+//
+// It returns an error if a property with the same ID or mnemonic is already registered.
+func (r *PropertyRegistry) Register(property Document) errors.E {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	errE := r.check(&property)
+	if errE != nil {
+		return errE
+	}
+	r.set(property)
+	return nil
+}
+
+// Add adds documents for property definitions to the registry.
+//
+// IDs of properties are derived from their mnemonics in the same way as for builtin
+// standard properties. Either all or none of definitions are added.
+func (r *PropertyRegistry) Add(definitions []PropertyDefinition) errors.E {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// We use a temporary registry to check for duplicates among definitions as well.
+	added := NewPropertyRegistry()
+	for i, definition := range definitions {
+		if definition.Name == "" {
+			errE := errors.New("property name is missing")
+			errors.Details(errE)["index"] = i
+			return errE
+		}
+		mnemonic := definition.Mnemonic
+		if mnemonic == "" {
+			mnemonic = getMnemonic(definition.Name)
+		} else if mnemonic != getMnemonic(mnemonic) {
+			errE := errors.New("invalid property mnemonic")
+			errors.Details(errE)["mnemonic"] = mnemonic
+			return errE
+		}
+		property := newStandardProperty(mnemonic, definition.Name, definition.DescriptionHTML, definition.Is)
+		errE := r.check(&property)
+		if errE != nil {
+			return errE
+		}
+		errE = added.check(&property)
+		if errE != nil {
+			return errE
+		}
+		added.set(property)
+	}
+
+	for _, definition := range definitions {
+		for _, isClaim := range definition.Is {
+			mnemonic := Mnemonic(getMnemonic(isClaim))
+			_, ok := r.mnemonics[mnemonic]
+			if !ok {
+				_, ok = added.mnemonics[mnemonic]
+			}
+			if !ok {
+				errE := errors.New("property for IS relation cannot be found")
+				errors.Details(errE)["name"] = definition.Name
+				errors.Details(errE)["is"] = isClaim
+				return errE
+			}
+		}
+	}
+
+	for _, property := range added.properties {
+		r.set(property)
+	}
+
+	return nil
+}
+
+// Load reads property definitions from a YAML or JSON file at path and adds them
+// to the registry. The file should contain a list of property definitions.
+func (r *PropertyRegistry) Load(path string) errors.E {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer file.Close()
+
+	// JSON is a subset of YAML, so we can use a YAML decoder for both.
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	var definitions []PropertyDefinition
+	err = decoder.Decode(&definitions)
+	if err != nil {
+		errE := errors.WithMessage(err, "invalid property definitions")
+		errors.Details(errE)["path"] = path
+		return errE
+	}
+
+	errE := r.Add(definitions)
+	if errE != nil {
+		errors.Details(errE)["path"] = path
+		return errE
+	}
+
+	return nil
+}
+
+// Get returns the document describing the property with the ID.
+func (r *PropertyRegistry) Get(id Identifier) (Document, errors.E) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	property, ok := r.properties[id]
+	if !ok {
+		errE := errors.New("property cannot be found")
+		errors.Details(errE)["id"] = string(id)
+		return Document{}, errE
+	}
+	return property, nil
+}
+
+// GetByMnemonic returns the document describing the property with the mnemonic.
+func (r *PropertyRegistry) GetByMnemonic(mnemonic Mnemonic) (Document, errors.E) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.mnemonics[mnemonic]
+	if !ok {
+		errE := errors.New("property cannot be found")
+		errors.Details(errE)["mnemonic"] = string(mnemonic)
+		return Document{}, errE
+	}
+	return r.properties[id], nil
+}
+
+// Reference returns a reference to the property with the mnemonic.
+func (r *PropertyRegistry) Reference(mnemonic Mnemonic) (DocumentReference, errors.E) {
+	property, errE := r.GetByMnemonic(mnemonic)
+	if errE != nil {
+		return DocumentReference{}, errE
+	}
+	return DocumentReference{
+		ID:     property.ID,
+		Name:   property.Name,
+		Score:  property.Score,
+		Scores: property.Scores,
+		Claims: nil,
+	}, nil
+}
+
+// Len returns the number of registered properties.
+func (r *PropertyRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.properties)
+}
+
+// List returns documents describing all registered properties, sorted by their IDs.
+func (r *PropertyRegistry) List() []Document {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	properties := make([]Document, 0, len(r.properties))
+	for _, property := range r.properties {
+		properties = append(properties, property)
+	}
+	sort.Slice(properties, func(i, j int) bool {
+		return properties[i].ID < properties[j].ID
+	})
+	return properties
+}
+
+// ListByClaimType returns documents describing registered properties which are
+// declared (with the IS relation) to be useful with the claim type, sorted by their IDs.
+//
+// Claim type is one of "identifier", "reference", "text", "string", "amount", "amount range",
+// "enumeration", "relation", "file", "time", and "time range".
+func (r *PropertyRegistry) ListByClaimType(claimType string) ([]Document, errors.E) {
+	valid := false
+	for _, t := range claimTypes {
+		if t == claimType {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		errE := errors.New("unknown claim type")
+		errors.Details(errE)["claimType"] = claimType
+		return nil, errE
+	}
+
+	isID := GetStandardPropertyID("IS")
+	claimTypeID := GetStandardPropertyID(getMnemonic(getClaimTypeName(claimType)))
+
+	properties := []Document{}
+	for _, property := range r.List() {
+		if property.Active == nil {
+			continue
+		}
+		for _, claim := range property.Active.Relation {
+			if claim.Prop.ID == isID && claim.To.ID == claimTypeID {
+				properties = append(properties, property)
+				break
+			}
+		}
+	}
+	return properties, nil
+}
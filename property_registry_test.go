@@ -0,0 +1,158 @@
+package search_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/peerdb/search"
+)
+
+func TestStandardProperties(t *testing.T) {
+	property, errE := search.StandardProperties.GetByMnemonic("LABEL")
+	require.NoError(t, errE)
+	assert.Equal(t, search.GetStandardPropertyID("LABEL"), property.ID)
+
+	same, errE := search.StandardProperties.Get(property.ID)
+	require.NoError(t, errE)
+	assert.Equal(t, property, same)
+
+	reference, errE := search.StandardProperties.Reference("LABEL")
+	require.NoError(t, errE)
+	assert.Equal(t, search.GetStandardPropertyReference("LABEL"), reference)
+
+	_, errE = search.StandardProperties.GetByMnemonic("NO_SUCH_PROPERTY")
+	assert.Error(t, errE)
+	_, errE = search.StandardProperties.Reference("NO_SUCH_PROPERTY")
+	assert.Error(t, errE)
+	_, errE = search.StandardProperties.Get(search.GetStandardPropertyID("NO_SUCH_PROPERTY"))
+	assert.Error(t, errE)
+	assert.Panics(t, func() {
+		search.GetStandardPropertyReference("NO_SUCH_PROPERTY")
+	})
+
+	relations, errE := search.StandardProperties.ListByClaimType("relation")
+	require.NoError(t, errE)
+	assert.Contains(t, relations, property)
+
+	_, errE = search.StandardProperties.ListByClaimType("no such claim type")
+	assert.Error(t, errE)
+
+	list := search.StandardProperties.List()
+	assert.Len(t, list, search.StandardProperties.Len())
+	for i := 1; i < len(list); i++ {
+		assert.Less(t, list[i-1].ID, list[i].ID)
+	}
+}
+
+func TestPropertyRegistryLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "properties.yaml")
+	err := os.WriteFile(yamlPath, []byte(`
+- name: claim type
+  descriptionHtml: The entity is a claim type.
+- name: '"string" claim type'
+  descriptionHtml: The property is useful with the "string" claim type.
+  is:
+    - claim type
+- name: test yaml property
+  descriptionHtml: A <b>test</b> property.
+  is:
+    - '"string" claim type'
+- name: test yaml other property
+  descriptionHtml: Another test property.
+  mnemonic: TEST_YAML_OTHER
+  is:
+    - test yaml property
+`), 0o600)
+	require.NoError(t, err)
+
+	registry := search.NewPropertyRegistry()
+	errE := registry.Load(yamlPath)
+	require.NoError(t, errE)
+	assert.Equal(t, 4, registry.Len())
+
+	id := search.GetStandardPropertyID("TEST_YAML_PROPERTY")
+	property, errE := registry.Get(id)
+	require.NoError(t, errE)
+	assert.Equal(t, search.Mnemonic("TEST_YAML_PROPERTY"), property.Mnemonic)
+	assert.Equal(t, search.Name{"en": "test yaml property"}, property.Name)
+	assert.Equal(t, search.TranslatableHTMLString{"en": "A <b>test</b> property."}, property.Active.Text[0].HTML)
+	require.Len(t, property.Active.Relation, 2)
+	assert.Equal(t, search.GetStandardPropertyID("PROPERTY"), property.Active.Relation[0].To.ID)
+	assert.Equal(t, search.GetStandardPropertyID("STRING_CLAIM_TYPE"), property.Active.Relation[1].To.ID)
+
+	other, errE := registry.GetByMnemonic("TEST_YAML_OTHER")
+	require.NoError(t, errE)
+	assert.Equal(t, id, other.Active.Relation[1].To.ID)
+
+	stringProperties, errE := registry.ListByClaimType("string")
+	require.NoError(t, errE)
+	assert.Equal(t, []search.Document{property}, stringProperties)
+
+	jsonPath := filepath.Join(dir, "properties.json")
+	err = os.WriteFile(jsonPath, []byte(`[{"name": "test json property", "descriptionHtml": "A test property."}]`), 0o600)
+	require.NoError(t, err)
+
+	errE = registry.Load(jsonPath)
+	require.NoError(t, errE)
+	_, errE = registry.GetByMnemonic("TEST_JSON_PROPERTY")
+	assert.NoError(t, errE)
+
+	// Loading the same properties again fails.
+	errE = registry.Load(jsonPath)
+	assert.Error(t, errE)
+}
+
+func TestPropertyRegistryAddInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		definitions []search.PropertyDefinition
+	}{
+		{"missing name", []search.PropertyDefinition{{Name: "", DescriptionHTML: "", Is: nil, Mnemonic: ""}}},
+		{"existing", []search.PropertyDefinition{{Name: "label", DescriptionHTML: "", Is: nil, Mnemonic: ""}}},
+		{"invalid mnemonic", []search.PropertyDefinition{{Name: "test invalid", DescriptionHTML: "", Is: nil, Mnemonic: "test invalid"}}},
+		{"unknown is", []search.PropertyDefinition{{Name: "test unknown is", DescriptionHTML: "", Is: []string{"no such property"}, Mnemonic: ""}}},
+		{"duplicate", []search.PropertyDefinition{
+			{Name: "test duplicate", DescriptionHTML: "", Is: nil, Mnemonic: ""},
+			{Name: "test duplicate", DescriptionHTML: "", Is: nil, Mnemonic: ""},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			size := search.StandardProperties.Len()
+			errE := search.StandardProperties.Add(test.definitions)
+			assert.Error(t, errE)
+			// Nothing is added on error.
+			assert.Equal(t, size, search.StandardProperties.Len())
+		})
+	}
+}
+
+func TestPropertyRegistryConcurrent(t *testing.T) {
+	registry := search.NewPropertyRegistry()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mnemonic := fmt.Sprintf("TEST_%d", i)
+			errE := registry.Add([]search.PropertyDefinition{{Name: mnemonic, DescriptionHTML: "", Is: nil, Mnemonic: mnemonic}})
+			assert.NoError(t, errE)
+			_, errE = registry.GetByMnemonic(search.Mnemonic(mnemonic))
+			assert.NoError(t, errE)
+			registry.List()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, registry.Len())
+}
@@ -0,0 +1,76 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// NewAliasClaim returns a claim which records that requests for the alias
+// identifier should be redirected to the document the claim is added to.
+//
+// Importers should add such claims when a document replaces another document,
+// e.g., when documents are merged or when the source of a document is renamed
+// and the identifier of the document is derived from its name.
+func NewAliasClaim(id, alias Identifier, confidence Confidence) (*IdentifierClaim, errors.E) {
+	prop, errE := StandardProperties.Reference("ALIAS")
+	if errE != nil {
+		return nil, errE
+	}
+	return &IdentifierClaim{
+		CoreClaim: CoreClaim{
+			ID:         id,
+			Confidence: confidence,
+		},
+		Prop:       prop,
+		Identifier: string(alias),
+	}, nil
+}
+
+// ResolveAlias returns the ID of the document which has the alias identifier
+// recorded with an ALIAS claim. It returns an empty string if there is none.
+func ResolveAlias(ctx context.Context, backend Backend, alias string) (Identifier, errors.E) {
+	aliasProperty := GetStandardPropertyID("ALIAS")
+
+	documents, errE := backend.FindByIdentifier(ctx, []Identifier{aliasProperty}, alias)
+	if errE != nil {
+		errors.Details(errE)["alias"] = alias
+		return "", errE
+	}
+
+	// There might be multiple documents because identifier claims might be matched
+	// case insensitive, so we check that the alias matches exactly.
+	for _, document := range documents {
+		for _, claim := range document.Get(aliasProperty) {
+			if c, ok := claim.(*IdentifierClaim); ok && c.Identifier == alias {
+				return document.ID, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// redirectAlias responds with a permanent redirect if the document with the ID
+// has been replaced by another document. It returns true if it responded.
+func (s *Service) redirectAlias(w http.ResponseWriter, req *http.Request, id string) bool {
+	canonicalID, errE := ResolveAlias(req.Context(), s.Backend, id)
+	if errE != nil {
+		s.internalServerError(w, req, errE)
+		return true
+	}
+	if canonicalID == "" {
+		return false
+	}
+
+	path, errE := s.path("DocumentGet", url.Values{"id": {string(canonicalID)}}, req.URL.RawQuery)
+	if errE != nil {
+		s.internalServerError(w, req, errE)
+		return true
+	}
+	w.Header().Set("Location", path)
+	w.WriteHeader(http.StatusMovedPermanently)
+	return true
+}
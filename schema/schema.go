@@ -0,0 +1,76 @@
+// Package schema provides JSON Schema for documents and validation of documents against it.
+//
+// JSON Schema files are generated from Go structs with "go run ./cmd/schema".
+package schema
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gitlab.com/tozd/go/errors"
+)
+
+//go:embed doc.json
+var docSchema []byte
+
+//go:embed definitions.json
+var definitionsSchema []byte
+
+var (
+	compiledSchema     *jsonschema.Schema
+	compiledSchemaErr  errors.E
+	compiledSchemaOnce sync.Once
+)
+
+func compile() (*jsonschema.Schema, errors.E) {
+	compiledSchemaOnce.Do(func() {
+		compiler := jsonschema.NewCompiler()
+		compiler.Draft = jsonschema.Draft2019
+		compiler.AssertFormat = true
+		compiler.AssertContent = true
+		for url, data := range map[string][]byte{"doc.json": docSchema, "definitions.json": definitionsSchema} {
+			err := compiler.AddResource(url, bytes.NewReader(data))
+			if err != nil {
+				compiledSchemaErr = errors.WithStack(err)
+				return
+			}
+		}
+		s, err := compiler.Compile("doc.json")
+		if err != nil {
+			compiledSchemaErr = errors.WithStack(err)
+			return
+		}
+		compiledSchema = s
+	})
+	return compiledSchema, compiledSchemaErr
+}
+
+// Validate validates JSON of a document against the document JSON Schema.
+func Validate(data []byte) errors.E {
+	s, errE := compile()
+	if errE != nil {
+		return errE
+	}
+
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&v)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = s.Validate(v)
+	if err != nil {
+		errE := errors.WithMessage(err, "document does not validate")
+		var validationErr *jsonschema.ValidationError
+		if errors.As(err, &validationErr) {
+			errors.Details(errE)["errors"] = validationErr.BasicOutput().Errors
+		}
+		return errE
+	}
+	return nil
+}
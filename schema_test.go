@@ -0,0 +1,115 @@
+package search_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/peerdb/search"
+	"gitlab.com/peerdb/search/identifier"
+	"gitlab.com/peerdb/search/schema"
+)
+
+func schemaTestDocument() *search.Document {
+	prop := search.GetStandardPropertyReference("DESCRIPTION")
+	lower := 1.0
+	upper := 2.0
+	ts := timestamp(1815, time.June, 18, 0, 0, 0)
+	claim := func() search.CoreClaim {
+		return search.CoreClaim{ID: search.Identifier(identifier.NewRandom()), Confidence: 1.0}
+	}
+	return &search.Document{
+		CoreDocument: search.CoreDocument{
+			ID:     search.Identifier("XkbTJqwFCFkfoxMBXow4HU"),
+			Name:   search.Name{"en": "Name", "sl": "Ime"},
+			Score:  0.5,
+			Scores: search.Scores{"test": 0.5},
+		},
+		Mnemonic: "TEST",
+		Active: &search.ClaimTypes{
+			Identifier: search.IdentifierClaims{{CoreClaim: claim(), Prop: prop, Identifier: "Q1"}},
+			Reference:  search.ReferenceClaims{{CoreClaim: claim(), Prop: prop, IRI: "https://example.com/"}},
+			Text:       search.TextClaims{{CoreClaim: claim(), Prop: prop, HTML: search.TranslatableHTMLString{"en": "<b>text</b>"}}},
+			String:     search.StringClaims{{CoreClaim: claim(), Prop: prop, String: "string"}},
+			Amount: search.AmountClaims{{
+				CoreClaim: claim(), Prop: prop, Amount: 1.5, UncertaintyLower: &lower, UncertaintyUpper: &upper, Unit: search.AmountUnitMetre,
+			}},
+			AmountRange: search.AmountRangeClaims{{CoreClaim: claim(), Prop: prop, Lower: 1.0, Upper: 2.0, Unit: search.AmountUnitCustom}},
+			Enumeration: search.EnumerationClaims{{CoreClaim: claim(), Prop: prop, Enum: []string{"a", "b"}}},
+			Relation: search.RelationClaims{{
+				CoreClaim: search.CoreClaim{
+					ID:         search.Identifier(identifier.NewRandom()),
+					Confidence: -0.5,
+					Meta: &search.ClaimTypes{
+						String: search.StringClaims{{CoreClaim: claim(), Prop: prop, String: "meta"}},
+					},
+				},
+				Prop: prop,
+				To:   search.GetStandardPropertyReference("ARTICLE"),
+			}},
+			File: search.FileClaims{{
+				CoreClaim: claim(), Prop: prop, Type: "image/png", URL: "https://example.com/image.png", Preview: []string{"https://example.com/preview.png"},
+			}},
+			NoValue:      search.NoValueClaims{{CoreClaim: claim(), Prop: prop}},
+			UnknownValue: search.UnknownValueClaims{{CoreClaim: claim(), Prop: prop}},
+			Time: search.TimeClaims{{
+				CoreClaim: claim(), Prop: prop, Timestamp: ts, UncertaintyLower: &ts, Precision: search.TimePrecisionDay,
+			}},
+			TimeRange: search.TimeRangeClaims{{
+				CoreClaim: claim(), Prop: prop, Lower: timestamp(-2006, time.January, 1, 0, 0, 0), Upper: ts, Precision: search.TimePrecisionYear,
+			}},
+		},
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	document := schemaTestDocument()
+
+	data, err := json.Marshal(document)
+	require.NoError(t, err)
+	errE := schema.Validate(data)
+	assert.NoError(t, errE, "% -+#.1v", errE)
+
+	// Round trip through JSON.
+	var decoded search.Document
+	err = json.Unmarshal(data, &decoded)
+	require.NoError(t, err)
+	data2, err := json.Marshal(&decoded)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(data), string(data2))
+	errE = schema.Validate(data2)
+	assert.NoError(t, errE, "% -+#.1v", errE)
+}
+
+func TestSchemaValidateStandardProperties(t *testing.T) {
+	for _, property := range search.StandardProperties.List() {
+		property := property
+		t.Run(string(property.Mnemonic), func(t *testing.T) {
+			data, err := json.Marshal(&property)
+			require.NoError(t, err)
+			errE := schema.Validate(data)
+			assert.NoError(t, errE, "% -+#.1v", errE)
+		})
+	}
+}
+
+func TestSchemaValidateInvalid(t *testing.T) {
+	for _, data := range []string{
+		`{}`,
+		`{"name": {"en": "Name"}}`,
+		`{"name": {}, "score": 0.5}`,
+		`{"name": {"en": "Name"}, "score": 2}`,
+		`{"name": {"en": "Name"}, "score": 0.5, "unknown": true}`,
+		`{"name": {"en": "Name"}, "score": 0.5, "mnemonic": "invalid"}`,
+		`{"name": {"en": "Name"}, "score": 0.5, "active": {"invalid": []}}`,
+		`{"name": {"en": "Name"}, "score": 0.5, "active": {"none": [{"_id": "invalid", "confidence": 1, "prop": {"_id": "XkbTJqwFCFkfoxMBXow4HU", "name": {"en": "Name"}, "score": 0.5}}]}}`,
+		`{"name": {"en": "Name"}, "score": 0.5, "active": {"none": [{"_id": "XkbTJqwFCFkfoxMBXow4HU", "confidence": 1}]}}`,
+		`{"name": {"en": "Name"}, "score": 0.5, "active": {"amount": [{"_id": "XkbTJqwFCFkfoxMBXow4HU", "confidence": 1, "prop": {"_id": "XkbTJqwFCFkfoxMBXow4HU", "name": {"en": "Name"}, "score": 0.5}, "amount": 1, "unit": "invalid"}]}}`,
+		`{"name": {"en": "Name"}, "score": 0.5, "active": {"time": [{"_id": "XkbTJqwFCFkfoxMBXow4HU", "confidence": 1, "prop": {"_id": "XkbTJqwFCFkfoxMBXow4HU", "name": {"en": "Name"}, "score": 0.5}, "timestamp": "2006-12-04", "precision": "d"}]}}`,
+	} {
+		assert.Error(t, schema.Validate([]byte(data)), data)
+	}
+}
@@ -21,7 +21,6 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/alice"
 	servertiming "github.com/mitchellh/go-server-timing"
-	"github.com/olivere/elastic/v7"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/hlog"
 	"gitlab.com/tozd/go/errors"
@@ -45,13 +44,22 @@ type routes struct {
 	Routes []struct {
 		Name string `json:"name"`
 		Path string `json:"path"`
+		// API routes do not have a frontend view.
+		API bool `json:"api,omitempty"`
 	} `json:"routes"`
 }
 
 type Service struct {
-	ESClient     *elastic.Client
-	Log          zerolog.Logger
-	Development  string
+	// Backend stores and searches documents.
+	Backend Backend
+	// Prefix is the path prefix under which routes are registered, e.g., "/staging".
+	// It is empty for routes registered at the root.
+	Prefix      string
+	Log         zerolog.Logger
+	Development string
+	// Hierarchy is used to match subproperties and to group claims by parent properties.
+	// If nil, properties are treated as having no subproperties.
+	Hierarchy    *PropertyHierarchy
 	reverseProxy *httputil.ReverseProxy
 	routes       map[string][]pathSegment
 }
@@ -330,10 +338,10 @@ func (s *Service) configureRoutes(router *httprouter.Router) errors.E {
 				handlerName := fmt.Sprintf("%s%s%s", route.Name, strings.Title(strings.ToLower(method)), contentType) //nolint:staticcheck
 				m := v.MethodByName(handlerName)
 				if !m.IsValid() {
-					s.Log.Debug().Str("handler", handlerName).Str("name", route.Name).Str("path", route.Path).Msg("route registration: handler not found")
+					s.Log.Debug().Str("handler", handlerName).Str("name", route.Name).Str("path", s.Prefix+route.Path).Msg("route registration: handler not found")
 					continue
 				}
-				s.Log.Debug().Str("handler", handlerName).Str("name", route.Name).Str("path", route.Path).Msg("route registration: handler found")
+				s.Log.Debug().Str("handler", handlerName).Str("name", route.Name).Str("path", s.Prefix+route.Path).Msg("route registration: handler found")
 				h, ok := m.Interface().(func(http.ResponseWriter, *http.Request, httprouter.Params))
 				if !ok {
 					errE := errors.Errorf("invalid route handler type: %T", m.Interface())
@@ -349,10 +357,12 @@ func (s *Service) configureRoutes(router *httprouter.Router) errors.E {
 			if mux.IsEmpty() {
 				continue
 			}
-			router.Handle(method, route.Path, mux.Handle)
-			if method == http.MethodGet {
-				foundGet = true
-				router.Handle(http.MethodHead, route.Path, mux.Handle)
+			for _, path := range routerPaths(s.Prefix + route.Path) {
+				router.Handle(method, path, mux.Handle)
+				if method == http.MethodGet {
+					foundGet = true
+					router.Handle(http.MethodHead, path, mux.Handle)
+				}
 			}
 		}
 		if !foundGet {
@@ -362,17 +372,80 @@ func (s *Service) configureRoutes(router *httprouter.Router) errors.E {
 			return errE
 		}
 
-		s.routes[route.Name] = parsePath(route.Path)
+		s.routes[route.Name] = parsePath(s.Prefix + route.Path)
 	}
 
 	return nil
 }
 
+// ValidPrefix returns an error if the prefix cannot be used as a path prefix for
+// routes of a dataset. The prefix has to be a single path segment which is different
+// from the first path segment of any route, so that the frontend can determine the prefix
+// from the current location.
+func ValidPrefix(prefix string) errors.E {
+	var rs routes
+	errE := x.UnmarshalWithoutUnknownFields(routesConfiguration, &rs)
+	if errE != nil {
+		return errE
+	}
+
+	segment := strings.TrimPrefix(prefix, "/")
+	if !strings.HasPrefix(prefix, "/") || segment == "" || strings.ContainsAny(segment, "/:*?") {
+		errE := errors.New("invalid prefix")
+		errors.Details(errE)["prefix"] = prefix
+		return errE
+	}
+	for _, route := range rs.Routes {
+		segments := parsePath(route.Path)
+		if len(segments) > 0 && !segments[0].Parameter && segments[0].Value == segment {
+			errE := errors.New("prefix conflicts with a route")
+			errors.Details(errE)["prefix"] = prefix
+			errors.Details(errE)["route"] = route.Name
+			return errE
+		}
+	}
+
+	return nil
+}
+
+// RouteDatasetWith registers routes of the service under its prefix with the router
+// of another service. This allows serving multiple datasets (each from its own index)
+// from the same process. Static files and middleware are provided by the other service
+// so RouteWith has to be called on it as well.
+func (s *Service) RouteDatasetWith(router *httprouter.Router) errors.E {
+	if s.routes != nil {
+		panic(errors.New("RouteDatasetWith called more than once"))
+	}
+
+	errE := ValidPrefix(s.Prefix)
+	if errE != nil {
+		return errE
+	}
+
+	s.routes = make(map[string][]pathSegment)
+
+	if s.Development != "" {
+		errE := s.makeReverseProxy()
+		if errE != nil {
+			return errE
+		}
+	}
+
+	return s.configureRoutes(router)
+}
+
 func (s *Service) RouteWith(router *httprouter.Router, version string) (http.Handler, errors.E) {
 	if s.routes != nil {
 		panic(errors.New("RouteWith called more than once"))
 	}
 
+	if s.Prefix != "" {
+		errE := ValidPrefix(s.Prefix)
+		if errE != nil {
+			return nil, errE
+		}
+	}
+
 	s.routes = make(map[string][]pathSegment)
 
 	router.RedirectTrailingSlash = true
@@ -474,7 +547,11 @@ func parsePath(path string) []pathSegment {
 			continue
 		}
 		var segment pathSegment
-		if strings.HasPrefix(part, ":") {
+		if strings.HasPrefix(part, ":") && strings.HasSuffix(part, "?") {
+			segment.Value = strings.TrimSuffix(strings.TrimPrefix(part, ":"), "?")
+			segment.Parameter = true
+			segment.Optional = true
+		} else if strings.HasPrefix(part, ":") {
 			segment.Value = strings.TrimPrefix(part, ":")
 			segment.Parameter = true
 			segment.Optional = false
@@ -490,6 +567,23 @@ func parsePath(path string) []pathSegment {
 	return segments
 }
 
+// routerPaths returns paths to register with the router for the route path.
+// The router does not support optional parameters (":name?"), so the last segment
+// of the path can be an optional parameter and we then register the path both
+// without and with the parameter.
+func routerPaths(path string) []string {
+	i := strings.LastIndex(path, "/")
+	last := path[i+1:]
+	if !strings.HasPrefix(last, ":") || !strings.HasSuffix(last, "?") {
+		return []string{path}
+	}
+	without := path[:i]
+	if without == "" {
+		without = "/"
+	}
+	return []string{without, path[:i+1] + strings.TrimSuffix(last, "?")}
+}
+
 func (s *Service) path(name string, params url.Values, query string) (string, errors.E) {
 	segments, ok := s.routes[name]
 	if !ok {
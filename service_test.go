@@ -0,0 +1,18 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/peerdb/search"
+)
+
+func TestValidPrefix(t *testing.T) {
+	for _, prefix := range []string{"/staging", "/production", "/test-1"} {
+		assert.NoError(t, search.ValidPrefix(prefix), prefix)
+	}
+	for _, prefix := range []string{"", "/", "staging", "/staging/", "/a/b", "/:id", "/d", "/s", "/groups", "/batch"} {
+		assert.Error(t, search.ValidPrefix(prefix), prefix)
+	}
+}
@@ -0,0 +1,219 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/google/uuid"
+	"gitlab.com/tozd/go/errors"
+
+	"gitlab.com/peerdb/search/identifier"
+)
+
+const (
+	// Maximum number of characters in a slug made from a name, without a suffix.
+	maxSlugLength = 100
+)
+
+var nameSpaceSlugs = identifier.RegisterNamespace("Slugs", uuid.MustParse("6a1b8f64-6f0e-4b57-9d4a-3b1c0f5e2d7a"))
+
+// MakeSlug returns a human-readable slug for the name. Letters and digits are lower-cased
+// and all other characters are collapsed into a dash. It returns an empty string
+// if the name does not contain any letter or digit.
+func MakeSlug(name string) string {
+	var res strings.Builder
+	length := 0
+	dash := false
+	for _, r := range name {
+		if length >= maxSlugLength {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && res.Len() > 0 {
+				res.WriteRune('-')
+				length++
+			}
+			dash = false
+			res.WriteRune(unicode.ToLower(r))
+			length++
+		} else {
+			dash = true
+		}
+	}
+	return res.String()
+}
+
+// GetSlug returns the current slug of the document or an empty string if it does not have one.
+func GetSlug(document *Document) string {
+	for _, claim := range document.Get(GetStandardPropertyID("SLUG")) {
+		if c, ok := claim.(*IdentifierClaim); ok {
+			return c.Identifier
+		}
+	}
+	return ""
+}
+
+// getPreviousSlugs returns previous slugs of the document.
+func getPreviousSlugs(document *Document) []string {
+	slugs := []string{}
+	for _, claim := range document.Get(GetStandardPropertyID("PREVIOUS_SLUG")) {
+		if c, ok := claim.(*IdentifierClaim); ok {
+			slugs = append(slugs, c.Identifier)
+		}
+	}
+	return slugs
+}
+
+// SetSlug sets the current slug of the document. The existing current slug (if any)
+// is kept as a previous slug so that URLs using it keep working.
+func SetSlug(document *Document, slug string) errors.E {
+	current := GetSlug(document)
+	if current == slug {
+		return nil
+	}
+
+	document.Remove(GetStandardPropertyID("SLUG"))
+	document.RemoveByID(GetID(nameSpaceSlugs, document.ID, "PREVIOUS_SLUG", slug))
+
+	if current != "" {
+		claimID := GetID(nameSpaceSlugs, document.ID, "PREVIOUS_SLUG", current)
+		if document.GetByID(claimID) == nil {
+			prop, errE := StandardProperties.Reference("PREVIOUS_SLUG")
+			if errE != nil {
+				return errE
+			}
+			errE = document.Add(&IdentifierClaim{
+				CoreClaim: CoreClaim{
+					ID:         claimID,
+					Confidence: 1.0,
+				},
+				Prop:       prop,
+				Identifier: current,
+			})
+			if errE != nil {
+				return errE
+			}
+		}
+	}
+
+	prop, errE := StandardProperties.Reference("SLUG")
+	if errE != nil {
+		return errE
+	}
+	return document.Add(&IdentifierClaim{
+		CoreClaim: CoreClaim{
+			ID:         GetID(nameSpaceSlugs, document.ID, "SLUG", slug),
+			Confidence: 1.0,
+		},
+		Prop:       prop,
+		Identifier: slug,
+	})
+}
+
+// hasSlugBase returns true if the slug is the base slug or the base slug
+// with a numeric suffix added for de-duplication.
+func hasSlugBase(slug, base string) bool {
+	if slug == base {
+		return true
+	}
+	if !strings.HasPrefix(slug, base+"-") {
+		return false
+	}
+	_, err := strconv.ParseUint(strings.TrimPrefix(slug, base+"-"), 10, 64) //nolint:gomnd
+	return err == nil
+}
+
+// Slugs assigns unique slugs to documents.
+//
+// Once a slug is assigned to a document (as its current or a previous slug)
+// it is never assigned to another document.
+//
+// It is safe for concurrent use.
+type Slugs struct {
+	mu    sync.Mutex
+	slugs map[string]Identifier
+}
+
+// NewSlugs returns a new Slugs without any assigned slugs.
+func NewSlugs() *Slugs {
+	return &Slugs{
+		mu:    sync.Mutex{},
+		slugs: map[string]Identifier{},
+	}
+}
+
+// AddDocument records current and previous slugs of the document as assigned.
+func (s *Slugs) AddDocument(document *Document) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slug := GetSlug(document); slug != "" {
+		s.slugs[slug] = document.ID
+	}
+	for _, slug := range getPreviousSlugs(document) {
+		s.slugs[slug] = document.ID
+	}
+}
+
+// Assign makes sure the document has a current slug made from its English name (or, if it does
+// not have one, its name in another language, see Resolve). If the document already has such a slug,
+// it is kept. Otherwise a new unique slug is assigned. It returns true if the document has been changed.
+func (s *Slugs) Assign(document *Document) (bool, errors.E) {
+	base := MakeSlug(document.Name.Resolve([]string{"en"}))
+	if base == "" {
+		return false, nil
+	}
+
+	current := GetSlug(document)
+	if current != "" && hasSlugBase(current, base) {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slug := base
+	for i := 2; ; i++ {
+		id, ok := s.slugs[slug]
+		if !ok || id == document.ID {
+			break
+		}
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	errE := SetSlug(document, slug)
+	if errE != nil {
+		return false, errE
+	}
+	s.slugs[slug] = document.ID
+	return true, nil
+}
+
+// ResolveSlug returns the ID of the document with the current or a previous slug.
+// It returns an empty string if there is none.
+func ResolveSlug(ctx context.Context, backend Backend, slug string) (Identifier, errors.E) {
+	documents, errE := backend.FindByIdentifier(ctx, []Identifier{GetStandardPropertyID("SLUG"), GetStandardPropertyID("PREVIOUS_SLUG")}, slug)
+	if errE != nil {
+		errors.Details(errE)["slug"] = slug
+		return "", errE
+	}
+
+	// There might be multiple documents because identifier claims might be matched
+	// case insensitive, so we check that the slug matches exactly.
+	for _, document := range documents {
+		if GetSlug(document) == slug {
+			return document.ID, nil
+		}
+		for _, s := range getPreviousSlugs(document) {
+			if s == slug {
+				return document.ID, nil
+			}
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,96 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/peerdb/search"
+)
+
+func TestMakeSlug(t *testing.T) {
+	for name, expected := range map[string]string{
+		"Douglas Adams":         "douglas-adams",
+		"  Apollo 11  ":         "apollo-11",
+		"C++ (language)":        "c-language",
+		"Ljubljana, Slovenija!": "ljubljana-slovenija",
+		"Čaj":                   "čaj",
+		"!!!":                   "",
+		"":                      "",
+	} {
+		assert.Equal(t, expected, search.MakeSlug(name), name)
+	}
+}
+
+func slugDocument(id, name string) *search.Document {
+	return &search.Document{
+		CoreDocument: search.CoreDocument{
+			ID:   search.Identifier(id),
+			Name: search.Name{"en": name},
+		},
+	}
+}
+
+func TestSlugs(t *testing.T) {
+	slugs := search.NewSlugs()
+
+	first := slugDocument("first", "Douglas Adams")
+	changed, errE := slugs.Assign(first)
+	require.NoError(t, errE)
+	assert.True(t, changed)
+	assert.Equal(t, "douglas-adams", search.GetSlug(first))
+
+	// Assigning again does not change anything.
+	changed, errE = slugs.Assign(first)
+	require.NoError(t, errE)
+	assert.False(t, changed)
+
+	// Another document with the same name gets a de-duplicated slug.
+	second := slugDocument("second", "Douglas Adams")
+	changed, errE = slugs.Assign(second)
+	require.NoError(t, errE)
+	assert.True(t, changed)
+	assert.Equal(t, "douglas-adams-2", search.GetSlug(second))
+
+	// De-duplicated slug is kept.
+	changed, errE = slugs.Assign(second)
+	require.NoError(t, errE)
+	assert.False(t, changed)
+
+	// After a rename the previous slug is kept.
+	first.Name["en"] = "Douglas Noel Adams"
+	changed, errE = slugs.Assign(first)
+	require.NoError(t, errE)
+	assert.True(t, changed)
+	assert.Equal(t, "douglas-noel-adams", search.GetSlug(first))
+	assert.Len(t, first.Get(search.GetStandardPropertyID("PREVIOUS_SLUG")), 1)
+
+	// Previous slug is not assigned to another document.
+	third := slugDocument("third", "Douglas Adams")
+	changed, errE = slugs.Assign(third)
+	require.NoError(t, errE)
+	assert.True(t, changed)
+	assert.Equal(t, "douglas-adams-3", search.GetSlug(third))
+
+	// But it can be reused by the same document.
+	first.Name["en"] = "Douglas Adams"
+	changed, errE = slugs.Assign(first)
+	require.NoError(t, errE)
+	assert.True(t, changed)
+	assert.Equal(t, "douglas-adams", search.GetSlug(first))
+	previous := first.Get(search.GetStandardPropertyID("PREVIOUS_SLUG"))
+	require.Len(t, previous, 1)
+	c, ok := previous[0].(*search.IdentifierClaim)
+	require.True(t, ok)
+	assert.Equal(t, "douglas-noel-adams", c.Identifier)
+
+	// Existing slugs are recorded.
+	other := search.NewSlugs()
+	other.AddDocument(first)
+	fourth := slugDocument("fourth", "Douglas Noel Adams")
+	changed, errE = other.Assign(fourth)
+	require.NoError(t, errE)
+	assert.True(t, changed)
+	assert.Equal(t, "douglas-noel-adams-2", search.GetSlug(fourth))
+}
@@ -4,8 +4,18 @@ import Main from "@/Main.vue"
 import { routes } from "@/../routes.json"
 import "./main.css"
 
+// Additional datasets are served under a path prefix (e.g., "/staging"). The prefix is always
+// different from the first path segment of any route, so we can determine it from the current location.
+function getBase(): string {
+  const first = window.location.pathname.split("/")[1] || ""
+  if (first && !routes.some((route) => route.path.split("/")[1] === first)) {
+    return `/${first}`
+  }
+  return ""
+}
+
 const router = createRouter({
-  history: createWebHistory(),
+  history: createWebHistory(getBase()),
   scrollBehavior(to, from, savedPosition) {
     // DocumentSearch route handles its own scrolling through "at" query parameter.
     if (to.name === "DocumentSearch") {
@@ -17,12 +27,14 @@ const router = createRouter({
       return { top: 0 }
     }
   },
-  routes: routes.map((route) => ({
-    path: route.path,
-    name: route.name,
-    component: () => import(`./views/${route.name}.vue`),
-    props: true,
-  })),
+  routes: routes
+    .filter((route) => !("api" in route && route.api))
+    .map((route) => ({
+      path: route.path,
+      name: route.name,
+      component: () => import(`./views/${route.name}.vue`),
+      props: true,
+    })),
 })
 
 createApp(Main).use(router).mount("main")
@@ -5,10 +5,30 @@ import type { SearchResult, PeerDBDocument } from "@/types"
 import { ref, watch, readonly, onBeforeUnmount } from "vue"
 import { useRoute, useRouter } from "vue-router"
 import { assert } from "@vue/compiler-core"
+import { routes } from "@/../routes.json"
 
 const INITIAL_LIMIT = 50
 const INCREASE = 50
 
+// apiResolve returns the URL of the API route with the name. API routes are not frontend routes,
+// so the router does not know them, but they are served under the same base (i.e., the path
+// prefix of the dataset) as frontend routes.
+export function apiResolve(router: Router, name: string, params: Record<string, string> = {}): string {
+  const route = routes.find((r) => r.name === name)
+  if (!route) {
+    throw new Error(`unknown route "${name}"`)
+  }
+  const path = route.path.replace(/\/:(\w+)(\?)?/g, (match, param: string, optional: string) => {
+    if (param in params) {
+      return `/${encodeURIComponent(params[param])}`
+    } else if (optional) {
+      return ""
+    }
+    throw new Error(`missing parameter "${param}" for route "${name}"`)
+  })
+  return `${router.options.history.base}${path}`
+}
+
 export async function postSearch(router: Router, form: HTMLFormElement, progress: Ref<number>) {
   progress.value += 1
   try {
@@ -27,7 +47,7 @@ export async function postSearch(router: Router, form: HTMLFormElement, progress
         body: new URLSearchParams(new FormData(form) as any),
         mode: "same-origin",
         credentials: "omit",
-        redirect: "error",
+        redirect: "follow",
         referrer: document.location.href,
         referrerPolicy: "strict-origin-when-cross-origin",
       },
@@ -46,12 +66,25 @@ export async function postSearch(router: Router, form: HTMLFormElement, progress
 
 function updateDocs(router: Router, docs: Ref<PeerDBDocument[]>, limit: number, results: readonly SearchResult[], progress: Ref<number>, abortSignal: AbortSignal) {
   assert(limit <= results.length, `${limit} <= ${results.length}`)
-  for (let i = docs.value.length; i < limit; i++) {
+  const start = docs.value.length
+  if (start >= limit) {
+    return
+  }
+  const ids: string[] = []
+  for (let i = start; i < limit; i++) {
     docs.value.push(results[i])
-    getDocument(router, results[i]._id, progress, abortSignal).then((data) => {
-      docs.value[i] = data
-    })
+    ids.push(results[i]._id)
   }
+  // We fetch all new documents with one request.
+  getDocuments(router, ids, progress, abortSignal).then((data) => {
+    for (let i = start; i < limit; i++) {
+      const doc = data.get(results[i]._id)
+      // If a document could not be fetched, we keep the search result in its place.
+      if (doc !== undefined) {
+        docs.value[i] = doc
+      }
+    }
+  })
 }
 
 export function useSearch(
@@ -175,7 +208,7 @@ async function getSearch(
         },
         mode: "same-origin",
         credentials: "omit",
-        redirect: "error",
+        redirect: "follow",
         referrer: document.location.href,
         referrerPolicy: "strict-origin-when-cross-origin",
         signal: abortSignal,
@@ -204,6 +237,20 @@ async function getSearch(
   }
 }
 
+// getDocumentID returns the ID of the document from the URL of its DocumentGet route.
+function getDocumentID(router: Router, url: string): string {
+  const base = router.options.history.base
+  let path = new URL(url).pathname
+  if (path.startsWith(base)) {
+    path = path.slice(base.length)
+  }
+  const route = router.resolve(path)
+  if (route.name !== "DocumentGet") {
+    throw new Error(`unexpected redirect to "${url}"`)
+  }
+  return route.params.id as string
+}
+
 export async function getDocument(router: Router, id: string, progress: Ref<number>, abortSignal: AbortSignal): Promise<PeerDBDocument> {
   progress.value += 1
   try {
@@ -221,7 +268,7 @@ export async function getDocument(router: Router, id: string, progress: Ref<numb
         },
         mode: "same-origin",
         credentials: "omit",
-        redirect: "error",
+        redirect: "follow",
         referrer: document.location.href,
         referrerPolicy: "strict-origin-when-cross-origin",
         signal: abortSignal,
@@ -232,13 +279,55 @@ export async function getDocument(router: Router, id: string, progress: Ref<numb
     }
     const doc = await response.json()
     // TODO: JSON response should include _id field, but until then we add it here.
-    doc._id = id
+    // Requests for aliases are redirected to the document with the canonical ID.
+    doc._id = response.redirected ? getDocumentID(router, response.url) : id
     return doc
   } finally {
     progress.value -= 1
   }
 }
 
+// getDocuments fetches multiple documents with one request to the batch API endpoint.
+// Returned map contains only documents which were found.
+export async function getDocuments(router: Router, ids: string[], progress: Ref<number>, abortSignal: AbortSignal): Promise<Map<string, PeerDBDocument>> {
+  progress.value += 1
+  try {
+    const body = new URLSearchParams()
+    for (const id of ids) {
+      body.append("id", id)
+    }
+    const response = await fetch(apiResolve(router, "DocumentBatch"), {
+      method: "POST",
+      headers: {
+        Accept: "application/json",
+        "Content-Type": "application/x-www-form-urlencoded; charset=UTF-8",
+      },
+      body,
+      mode: "same-origin",
+      credentials: "omit",
+      redirect: "follow",
+      referrer: document.location.href,
+      referrerPolicy: "strict-origin-when-cross-origin",
+      signal: abortSignal,
+    })
+    if (!response.ok) {
+      throw new Error(`fetch error ${response.status}: ${await response.text()}`)
+    }
+    const data: Record<string, { _id: string; doc?: PeerDBDocument; error?: string }> = await response.json()
+    const docs = new Map<string, PeerDBDocument>()
+    for (const [id, result] of Object.entries(data)) {
+      if (result.doc !== undefined) {
+        // TODO: JSON response should include _id field, but until then we add it here.
+        result.doc._id = id
+        docs.set(id, result.doc)
+      }
+    }
+    return docs
+  } finally {
+    progress.value -= 1
+  }
+}
+
 export function useSearchState(
   progress: Ref<number>,
   redirect: (query: LocationQueryRaw) => Promise<void | undefined>,
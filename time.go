@@ -0,0 +1,342 @@
+package search
+
+import (
+	"math"
+	"time"
+
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/x"
+)
+
+const (
+	secondsInDay = 24 * 60 * 60
+	// The largest number of seconds in a year (in a leap year).
+	maxSecondsInYear = 366 * secondsInDay
+)
+
+// isLeapYear returns true if the year in the proleptic Gregorian calendar is a leap year.
+func isLeapYear(year int64) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// secondsInYear returns the number of seconds in the year.
+func secondsInYear(year int64) int64 {
+	if isLeapYear(year) {
+		return maxSecondsInYear
+	}
+	return maxSecondsInYear - secondsInDay
+}
+
+// referenceYear returns a year which time.Time can represent and which has
+// the same calendar (months and days) as the year.
+func referenceYear(year int64) int {
+	if isLeapYear(year) {
+		return 2000 //nolint:gomnd
+	}
+	return 2001 //nolint:gomnd
+}
+
+// NewTimestamp returns the timestamp for the given date and time in UTC.
+// It returns an error if any of the values is out of its range.
+func NewTimestamp(year int64, month time.Month, day, hour, minute, second int) (Timestamp, errors.E) {
+	ref := referenceYear(year)
+	t := time.Date(ref, month, day, hour, minute, second, 0, time.UTC)
+	if t.Year() != ref || t.Month() != month || t.Day() != day || t.Hour() != hour || t.Minute() != minute || t.Second() != second {
+		return Timestamp{}, errors.Errorf("date and time out of range: %d-%02d-%02dT%02d:%02d:%02dZ", year, month, day, hour, minute, second)
+	}
+	return Timestamp{
+		Year:    year,
+		Seconds: int64(t.Sub(time.Date(ref, time.January, 1, 0, 0, 0, 0, time.UTC)) / time.Second),
+	}, nil
+}
+
+// TimestampFromTime returns the timestamp for t, truncated to the second.
+func TimestampFromTime(t time.Time) Timestamp {
+	t = t.UTC()
+	return Timestamp{
+		Year:    int64(t.Year()),
+		Seconds: int64(t.Sub(time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)) / time.Second),
+	}
+}
+
+// reference returns the timestamp as time.Time in its reference year.
+func (t Timestamp) reference() time.Time {
+	return time.Date(referenceYear(t.Year), time.January, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(t.Seconds) * time.Second)
+}
+
+// fromReference returns the timestamp for r in the reference year of the year.
+func fromReference(year int64, r time.Time) Timestamp {
+	return Timestamp{
+		Year:    year,
+		Seconds: int64(r.Sub(time.Date(r.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)) / time.Second),
+	}
+}
+
+// Date returns the year, month, and day of the timestamp.
+func (t Timestamp) Date() (int64, time.Month, int) {
+	r := t.reference()
+	return t.Year, r.Month(), r.Day()
+}
+
+// Clock returns the hour, minute, and second of the timestamp.
+func (t Timestamp) Clock() (int, int, int) {
+	return t.reference().Clock()
+}
+
+// Time returns the timestamp as time.Time.
+//
+// time.Time cannot represent years far in the past or in the future (beyond
+// around 292 billion years) and the result for such timestamps is undefined.
+func (t Timestamp) Time() time.Time {
+	return time.Date(int(t.Year), time.January, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(t.Seconds) * time.Second)
+}
+
+// Compare returns -1 if t is before u, +1 if t is after u, and 0 if they are equal.
+func (t Timestamp) Compare(u Timestamp) int {
+	switch {
+	case t.Year < u.Year:
+		return -1
+	case t.Year > u.Year:
+		return 1
+	case t.Seconds < u.Seconds:
+		return -1
+	case t.Seconds > u.Seconds:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Before returns true if t is before u.
+func (t Timestamp) Before(u Timestamp) bool {
+	return t.Compare(u) < 0
+}
+
+// After returns true if t is after u.
+func (t Timestamp) After(u Timestamp) bool {
+	return t.Compare(u) > 0
+}
+
+// Sortable returns a numeric encoding of the timestamp which sorts in the same
+// order as timestamps. It is used for indexing timestamps in ElasticSearch.
+//
+// Every year is encoded as the same number of seconds (those of a leap year)
+// so the encoding is not the number of seconds since some epoch, but it is
+// independent of calendar details and works for any year which fits. Years
+// beyond around ±292 billion saturate to the smallest or the largest int64.
+func (t Timestamp) Sortable() int64 {
+	if t.Year > math.MaxInt64/maxSecondsInYear-1 {
+		return math.MaxInt64
+	} else if t.Year < math.MinInt64/maxSecondsInYear+1 {
+		return math.MinInt64
+	}
+	return t.Year*maxSecondsInYear + t.Seconds
+}
+
+// TimeInterval is an interval of time between Lower and Upper timestamps.
+// Both bounds are inclusive.
+type TimeInterval struct {
+	Lower Timestamp
+	Upper Timestamp
+}
+
+// Contains returns true if timestamp t is inside the interval.
+func (i TimeInterval) Contains(t Timestamp) bool {
+	return !t.Before(i.Lower) && !t.After(i.Upper)
+}
+
+// Overlaps returns true if intervals i and o have at least one timestamp in common.
+func (i TimeInterval) Overlaps(o TimeInterval) bool {
+	return !i.Upper.Before(o.Lower) && !o.Upper.Before(i.Lower)
+}
+
+// union returns the smallest interval which contains both intervals i and o.
+func (i TimeInterval) union(o TimeInterval) TimeInterval {
+	if o.Lower.Before(i.Lower) {
+		i.Lower = o.Lower
+	}
+	if o.Upper.After(i.Upper) {
+		i.Upper = o.Upper
+	}
+	return i
+}
+
+// timeIntervalRange is how TimeInterval is indexed in ElasticSearch.
+// We use a long_range of sortable encodings of timestamps instead of date_range
+// because ElasticSearch dates cannot represent years beyond ±292 million
+// while we have to support time precisions up to billion years.
+type timeIntervalRange struct {
+	Gte int64 `json:"gte"`
+	Lte int64 `json:"lte"`
+}
+
+func (i TimeInterval) toRange() timeIntervalRange {
+	return timeIntervalRange{
+		Gte: i.Lower.Sortable(),
+		Lte: i.Upper.Sortable(),
+	}
+}
+
+// floorDiv is integer division which rounds towards negative infinity.
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// yearsInterval returns the interval of n years which contains the year.
+func yearsInterval(year, n int64) TimeInterval {
+	start := floorDiv(year, n) * n
+	end := start + n - 1
+	return TimeInterval{
+		Lower: Timestamp{Year: start, Seconds: 0},
+		Upper: Timestamp{Year: end, Seconds: secondsInYear(end) - 1},
+	}
+}
+
+// Interval returns the interval of time the timestamp represents at the given precision.
+// E.g., timestamp 1815-06-18T00:00:00Z with precision TimePrecisionTenYears represents
+// the whole decade from 1810-01-01T00:00:00Z to 1819-12-31T23:59:59Z.
+//
+// Precisions coarser than a year use intervals aligned to a multiple of their size
+// (e.g., TimePrecisionHundredYears for 1815 is from 1800 to 1899).
+func (t Timestamp) Interval(precision TimePrecision) TimeInterval {
+	r := t.reference()
+	var lower, upper time.Time
+	switch precision {
+	case TimePrecisionGigaYears:
+		return yearsInterval(t.Year, 1_000_000_000) //nolint:gomnd
+	case TimePrecisionHundredMegaYears:
+		return yearsInterval(t.Year, 100_000_000) //nolint:gomnd
+	case TimePrecisionTenMegaYears:
+		return yearsInterval(t.Year, 10_000_000) //nolint:gomnd
+	case TimePrecisionMegaYears:
+		return yearsInterval(t.Year, 1_000_000) //nolint:gomnd
+	case TimePrecisionHundredKiloYears:
+		return yearsInterval(t.Year, 100_000) //nolint:gomnd
+	case TimePrecisionTenKiloYears:
+		return yearsInterval(t.Year, 10_000) //nolint:gomnd
+	case TimePrecisionKiloYears:
+		return yearsInterval(t.Year, 1_000) //nolint:gomnd
+	case TimePrecisionHundredYears:
+		return yearsInterval(t.Year, 100) //nolint:gomnd
+	case TimePrecisionTenYears:
+		return yearsInterval(t.Year, 10) //nolint:gomnd
+	case TimePrecisionYear:
+		return yearsInterval(t.Year, 1)
+	case TimePrecisionMonth:
+		lower = time.Date(r.Year(), r.Month(), 1, 0, 0, 0, 0, time.UTC)
+		upper = lower.AddDate(0, 1, 0)
+	case TimePrecisionDay:
+		lower = time.Date(r.Year(), r.Month(), r.Day(), 0, 0, 0, 0, time.UTC)
+		upper = lower.AddDate(0, 0, 1)
+	case TimePrecisionHour:
+		lower = time.Date(r.Year(), r.Month(), r.Day(), r.Hour(), 0, 0, 0, time.UTC)
+		upper = lower.Add(time.Hour)
+	case TimePrecisionMinute:
+		lower = time.Date(r.Year(), r.Month(), r.Day(), r.Hour(), r.Minute(), 0, 0, time.UTC)
+		upper = lower.Add(time.Minute)
+	case TimePrecisionSecond:
+		fallthrough
+	default:
+		return TimeInterval{Lower: t, Upper: t}
+	}
+	// Upper bound is always still in the same (reference) year.
+	return TimeInterval{
+		Lower: fromReference(t.Year, lower),
+		Upper: fromReference(t.Year, upper.Add(-time.Second)),
+	}
+}
+
+// widen returns interval i extended with uncertainty bounds (if provided) at the given precision.
+func widen(i TimeInterval, uncertaintyLower, uncertaintyUpper *Timestamp, precision TimePrecision) TimeInterval {
+	if uncertaintyLower != nil {
+		i = i.union(uncertaintyLower.Interval(precision))
+	}
+	if uncertaintyUpper != nil {
+		i = i.union(uncertaintyUpper.Interval(precision))
+	}
+	return i
+}
+
+// Interval returns the effective interval of time the claim represents,
+// taking into account its precision and uncertainty.
+func (c *TimeClaim) Interval() TimeInterval {
+	return widen(c.Timestamp.Interval(c.Precision), c.UncertaintyLower, c.UncertaintyUpper, c.Precision)
+}
+
+// Interval returns the effective interval of time the claim represents,
+// taking into account its precision and uncertainty.
+func (c *TimeRangeClaim) Interval() TimeInterval {
+	i := c.Lower.Interval(c.Precision).union(c.Upper.Interval(c.Precision))
+	return widen(i, c.UncertaintyLower, c.UncertaintyUpper, c.Precision)
+}
+
+// We cannot just alias TimeClaim because then MarshalJSON and UnmarshalJSON would recurse.
+type timeClaim TimeClaim
+
+type timeClaimJSON struct {
+	timeClaim
+
+	// Interval and TimestampSortable are computed from other fields when marshaling
+	// and ignored when unmarshaling. They are used for indexing in ElasticSearch.
+	Interval          *timeIntervalRange `json:"interval,omitempty"`
+	TimestampSortable *int64             `json:"timestampSortable,omitempty"`
+}
+
+func (c TimeClaim) MarshalJSON() ([]byte, error) {
+	i := c.Interval().toRange()
+	s := c.Timestamp.Sortable()
+	return x.MarshalWithoutEscapeHTML(timeClaimJSON{
+		timeClaim:         timeClaim(c),
+		Interval:          &i,
+		TimestampSortable: &s,
+	})
+}
+
+func (c *TimeClaim) UnmarshalJSON(data []byte) error {
+	var t timeClaimJSON
+	err := x.UnmarshalWithoutUnknownFields(data, &t)
+	if err != nil {
+		return err
+	}
+	*c = TimeClaim(t.timeClaim)
+	return nil
+}
+
+// We cannot just alias TimeRangeClaim because then MarshalJSON and UnmarshalJSON would recurse.
+type timeRangeClaim TimeRangeClaim
+
+type timeRangeClaimJSON struct {
+	timeRangeClaim
+
+	// Interval, LowerSortable, and UpperSortable are computed from other fields when
+	// marshaling and ignored when unmarshaling. They are used for indexing in ElasticSearch.
+	Interval      *timeIntervalRange `json:"interval,omitempty"`
+	LowerSortable *int64             `json:"lowerSortable,omitempty"`
+	UpperSortable *int64             `json:"upperSortable,omitempty"`
+}
+
+func (c TimeRangeClaim) MarshalJSON() ([]byte, error) {
+	i := c.Interval().toRange()
+	l := c.Lower.Sortable()
+	u := c.Upper.Sortable()
+	return x.MarshalWithoutEscapeHTML(timeRangeClaimJSON{
+		timeRangeClaim: timeRangeClaim(c),
+		Interval:       &i,
+		LowerSortable:  &l,
+		UpperSortable:  &u,
+	})
+}
+
+func (c *TimeRangeClaim) UnmarshalJSON(data []byte) error {
+	var t timeRangeClaimJSON
+	err := x.UnmarshalWithoutUnknownFields(data, &t)
+	if err != nil {
+		return err
+	}
+	*c = TimeRangeClaim(t.timeRangeClaim)
+	return nil
+}
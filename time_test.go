@@ -0,0 +1,230 @@
+package search_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/peerdb/search"
+)
+
+func timestamp(year int64, month time.Month, day, hour, min, sec int) search.Timestamp {
+	t, err := search.NewTimestamp(year, month, day, hour, min, sec)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestTimestampInterval(t *testing.T) {
+	ts := timestamp(1815, time.June, 18, 11, 30, 15)
+	tests := []struct {
+		precision search.TimePrecision
+		lower     search.Timestamp
+		upper     search.Timestamp
+	}{
+		{search.TimePrecisionGigaYears, timestamp(0, time.January, 1, 0, 0, 0), timestamp(999_999_999, time.December, 31, 23, 59, 59)},
+		{search.TimePrecisionMegaYears, timestamp(0, time.January, 1, 0, 0, 0), timestamp(999_999, time.December, 31, 23, 59, 59)},
+		{search.TimePrecisionKiloYears, timestamp(1000, time.January, 1, 0, 0, 0), timestamp(1999, time.December, 31, 23, 59, 59)},
+		{search.TimePrecisionHundredYears, timestamp(1800, time.January, 1, 0, 0, 0), timestamp(1899, time.December, 31, 23, 59, 59)},
+		{search.TimePrecisionTenYears, timestamp(1810, time.January, 1, 0, 0, 0), timestamp(1819, time.December, 31, 23, 59, 59)},
+		{search.TimePrecisionYear, timestamp(1815, time.January, 1, 0, 0, 0), timestamp(1815, time.December, 31, 23, 59, 59)},
+		{search.TimePrecisionMonth, timestamp(1815, time.June, 1, 0, 0, 0), timestamp(1815, time.June, 30, 23, 59, 59)},
+		{search.TimePrecisionDay, timestamp(1815, time.June, 18, 0, 0, 0), timestamp(1815, time.June, 18, 23, 59, 59)},
+		{search.TimePrecisionHour, timestamp(1815, time.June, 18, 11, 0, 0), timestamp(1815, time.June, 18, 11, 59, 59)},
+		{search.TimePrecisionMinute, timestamp(1815, time.June, 18, 11, 30, 0), timestamp(1815, time.June, 18, 11, 30, 59)},
+		{search.TimePrecisionSecond, ts, ts},
+	}
+	for _, test := range tests {
+		p, err := json.Marshal(test.precision)
+		require.NoError(t, err)
+		t.Run(string(p), func(t *testing.T) {
+			assert.Equal(t, search.TimeInterval{Lower: test.lower, Upper: test.upper}, ts.Interval(test.precision))
+		})
+	}
+
+	// Negative years are aligned towards negative infinity.
+	assert.Equal(t, search.TimeInterval{
+		Lower: timestamp(-200, time.January, 1, 0, 0, 0),
+		Upper: timestamp(-101, time.December, 31, 23, 59, 59),
+	}, timestamp(-150, time.March, 1, 0, 0, 0).Interval(search.TimePrecisionHundredYears))
+
+	// Month precision in a leap year.
+	assert.Equal(t, search.TimeInterval{
+		Lower: timestamp(2000, time.February, 1, 0, 0, 0),
+		Upper: timestamp(2000, time.February, 29, 23, 59, 59),
+	}, timestamp(2000, time.February, 10, 0, 0, 0).Interval(search.TimePrecisionMonth))
+}
+
+func TestTimeClaimInterval(t *testing.T) {
+	lower := timestamp(1790, time.January, 1, 0, 0, 0)
+	claim := search.TimeClaim{
+		Timestamp:        timestamp(1800, time.January, 1, 0, 0, 0),
+		UncertaintyLower: &lower,
+		Precision:        search.TimePrecisionTenYears,
+	}
+	interval := claim.Interval()
+	assert.Equal(t, search.TimeInterval{
+		Lower: timestamp(1790, time.January, 1, 0, 0, 0),
+		Upper: timestamp(1809, time.December, 31, 23, 59, 59),
+	}, interval)
+
+	// "Born in the 1800s."
+	century := timestamp(1800, time.January, 1, 0, 0, 0).Interval(search.TimePrecisionHundredYears)
+	assert.True(t, century.Overlaps(interval))
+	assert.True(t, interval.Contains(timestamp(1795, time.May, 5, 0, 0, 0)))
+	assert.False(t, interval.Contains(timestamp(1810, time.January, 1, 0, 0, 0)))
+
+	rangeClaim := search.TimeRangeClaim{
+		Lower:     timestamp(1914, time.July, 28, 0, 0, 0),
+		Upper:     timestamp(1918, time.November, 11, 0, 0, 0),
+		Precision: search.TimePrecisionMonth,
+	}
+	assert.Equal(t, search.TimeInterval{
+		Lower: timestamp(1914, time.July, 1, 0, 0, 0),
+		Upper: timestamp(1918, time.November, 30, 23, 59, 59),
+	}, rangeClaim.Interval())
+	assert.False(t, century.Overlaps(rangeClaim.Interval()))
+}
+
+func TestTimeClaimMarshal(t *testing.T) {
+	claim := search.TimeClaim{
+		CoreClaim: search.CoreClaim{
+			ID:         "XkbTJqwFCFkfoxMBXow4HU",
+			Confidence: 1.0,
+		},
+		Prop: search.DocumentReference{
+			ID:    "8mnDdqkCFYLERqYstB4WCF",
+			Name:  search.Name{"en": "date of birth"},
+			Score: 0.5,
+		},
+		// Outside of ElasticSearch date limits.
+		Timestamp: timestamp(-1_000_000_000, time.January, 1, 0, 0, 0),
+		Precision: search.TimePrecisionGigaYears,
+	}
+	out, err := json.Marshal(claim)
+	require.NoError(t, err)
+	assert.Equal(t, `{"_id":"XkbTJqwFCFkfoxMBXow4HU","confidence":1,"prop":{"_id":"8mnDdqkCFYLERqYstB4WCF","name":{"en":"date of birth"},"score":0.5},`+
+		`"timestamp":"-1000000000-01-01T00:00:00Z","precision":"G","interval":{"gte":-31622400000000000,"lte":-86401},"timestampSortable":-31622400000000000}`, string(out))
+
+	var in search.TimeClaim
+	err = json.Unmarshal(out, &in)
+	require.NoError(t, err)
+	assert.Equal(t, claim, in)
+}
+
+func TestTimeRangeClaimMarshal(t *testing.T) {
+	claim := search.TimeRangeClaim{
+		CoreClaim: search.CoreClaim{
+			ID:         "XkbTJqwFCFkfoxMBXow4HU",
+			Confidence: 1.0,
+		},
+		Prop: search.DocumentReference{
+			ID:    "8mnDdqkCFYLERqYstB4WCF",
+			Name:  search.Name{"en": "period"},
+			Score: 0.5,
+		},
+		// Outside of what even int64 sortable encoding can represent.
+		Lower:     timestamp(-9_999_999_999_999_999, time.January, 1, 0, 0, 0),
+		Upper:     timestamp(0, time.January, 1, 0, 0, 0),
+		Precision: search.TimePrecisionYear,
+	}
+	out, err := json.Marshal(claim)
+	require.NoError(t, err)
+	assert.Equal(t, `{"_id":"XkbTJqwFCFkfoxMBXow4HU","confidence":1,"prop":{"_id":"8mnDdqkCFYLERqYstB4WCF","name":{"en":"period"},"score":0.5},`+
+		`"lower":"-9999999999999999-01-01T00:00:00Z","upper":"0000-01-01T00:00:00Z","precision":"y",`+
+		`"interval":{"gte":-9223372036854775808,"lte":31622399},"lowerSortable":-9223372036854775808,"upperSortable":0}`, string(out))
+
+	var in search.TimeRangeClaim
+	err = json.Unmarshal(out, &in)
+	require.NoError(t, err)
+	assert.Equal(t, claim, in)
+}
+
+func TestTimestampExtreme(t *testing.T) {
+	tests := []string{
+		`"-13800000000-01-01T00:00:00Z"`,
+		`"-292277026596-12-04T15:30:07Z"`,
+		`"292277026596-12-04T15:30:07Z"`,
+		`"9999999999999999-12-31T23:59:59Z"`,
+		`"-10000000000000000-02-29T00:00:00Z"`,
+	}
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			var timestamp search.Timestamp
+			in := []byte(test)
+			err := json.Unmarshal(in, &timestamp)
+			assert.NoError(t, err)
+			out, err := json.Marshal(timestamp)
+			assert.NoError(t, err)
+			assert.Equal(t, in, out)
+		})
+	}
+}
+
+func TestTimestampInvalid(t *testing.T) {
+	tests := []string{
+		`"2001-02-29T00:00:00Z"`,
+		`"1900-02-29T00:00:00Z"`,
+		`"2000-13-01T00:00:00Z"`,
+		`"2000-00-01T00:00:00Z"`,
+		`"2000-01-00T00:00:00Z"`,
+		`"2000-01-01T24:00:00Z"`,
+		`"2000-01-01T00:60:00Z"`,
+		`"2000-01-01T00:00:60Z"`,
+		`"99999999999999999999-01-01T00:00:00Z"`,
+	}
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			var timestamp search.Timestamp
+			err := json.Unmarshal([]byte(test), &timestamp)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestTimestampSortable(t *testing.T) {
+	timestamps := []search.Timestamp{
+		timestamp(-9_999_999_999_999_999, time.January, 1, 0, 0, 0),
+		timestamp(-13_800_000_000, time.January, 1, 0, 0, 0),
+		timestamp(-1, time.December, 31, 23, 59, 59),
+		timestamp(0, time.January, 1, 0, 0, 0),
+		timestamp(0, time.December, 31, 23, 59, 59),
+		timestamp(1, time.January, 1, 0, 0, 0),
+		timestamp(1999, time.December, 31, 23, 59, 59),
+		timestamp(2000, time.February, 29, 0, 0, 0),
+		timestamp(2000, time.December, 31, 23, 59, 59),
+		timestamp(2001, time.January, 1, 0, 0, 0),
+		timestamp(13_800_000_000, time.January, 1, 0, 0, 0),
+		timestamp(9_999_999_999_999_999, time.December, 31, 23, 59, 59),
+	}
+	for i := 1; i < len(timestamps); i++ {
+		assert.True(t, timestamps[i-1].Before(timestamps[i]))
+		assert.LessOrEqual(t, timestamps[i-1].Sortable(), timestamps[i].Sortable())
+	}
+	for i := 1; i < len(timestamps)-2; i++ {
+		assert.Less(t, timestamps[i-1].Sortable(), timestamps[i].Sortable())
+	}
+	assert.Equal(t, int64(math.MinInt64), timestamps[0].Sortable())
+	assert.Equal(t, int64(math.MaxInt64), timestamps[len(timestamps)-1].Sortable())
+	assert.Equal(t, int64(0), timestamp(0, time.January, 1, 0, 0, 0).Sortable())
+}
+
+func TestTimestampTime(t *testing.T) {
+	for _, tt := range []time.Time{
+		time.Date(2000, time.February, 29, 12, 34, 56, 0, time.UTC),
+		time.Date(-4000, time.March, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1_000_000_000, time.December, 31, 23, 59, 59, 0, time.UTC),
+	} {
+		ts := search.TimestampFromTime(tt)
+		assert.Equal(t, tt, ts.Time())
+		year, month, day := ts.Date()
+		assert.Equal(t, int64(tt.Year()), year)
+		assert.Equal(t, tt.Month(), month)
+		assert.Equal(t, tt.Day(), day)
+	}
+}
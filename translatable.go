@@ -0,0 +1,237 @@
+package search
+
+import (
+	"sort"
+	"strings"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// DefaultLanguage is the language used when none of the requested languages is available.
+const DefaultLanguage = "en"
+
+// languageFallbacks returns the BCP 47 fallback chain for languages: each language
+// is followed by its less specific tags (e.g., "sl-SI" is followed by "sl"),
+// and DefaultLanguage is at the end. Tags are lower-cased.
+func languageFallbacks(languages []string) []string {
+	seen := map[string]bool{}
+	fallbacks := []string{}
+	add := func(tag string) {
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			fallbacks = append(fallbacks, tag)
+		}
+	}
+	for _, language := range languages {
+		tag := strings.ToLower(strings.TrimSpace(language))
+		for tag != "" {
+			add(tag)
+			i := strings.LastIndex(tag, "-")
+			if i == -1 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	add(DefaultLanguage)
+	return fallbacks
+}
+
+// resolveLanguage returns the key in values which best matches the
+// fallback chain for languages. If none matches, the smallest key is
+// returned so that the result is deterministic. It returns an empty
+// string if values is empty.
+func resolveLanguage(values map[string]string, languages []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, tag := range languageFallbacks(languages) {
+		for _, key := range keys {
+			if strings.EqualFold(key, tag) {
+				return key
+			}
+		}
+	}
+	return keys[0]
+}
+
+// ResolveLanguage returns the language of the string which best matches the
+// requested languages, following BCP 47 fallback chains (e.g., "sl-SI" → "sl" →
+// "en" → any). It returns an empty string if there are no translations.
+func (t TranslatablePlainString) ResolveLanguage(languages []string) string {
+	return resolveLanguage(t, languages)
+}
+
+// Resolve returns the string in the language which best matches the requested languages.
+// See ResolveLanguage for details.
+func (t TranslatablePlainString) Resolve(languages []string) string {
+	return t[t.ResolveLanguage(languages)]
+}
+
+// ResolveLanguage returns the language of the string which best matches the
+// requested languages, following BCP 47 fallback chains (e.g., "sl-SI" → "sl" →
+// "en" → any). It returns an empty string if there are no translations.
+func (t TranslatableHTMLString) ResolveLanguage(languages []string) string {
+	return resolveLanguage(t, languages)
+}
+
+// Resolve returns the string in the language which best matches the requested languages.
+// See ResolveLanguage for details.
+func (t TranslatableHTMLString) Resolve(languages []string) string {
+	return t[t.ResolveLanguage(languages)]
+}
+
+// project returns the string with only the translation which best matches the requested languages.
+func (t TranslatablePlainString) project(languages []string) TranslatablePlainString {
+	language := t.ResolveLanguage(languages)
+	if language == "" {
+		return t
+	}
+	return TranslatablePlainString{language: t[language]}
+}
+
+// project returns the string with only the translation which best matches the requested languages.
+func (t TranslatableHTMLString) project(languages []string) TranslatableHTMLString {
+	language := t.ResolveLanguage(languages)
+	if language == "" {
+		return t
+	}
+	return TranslatableHTMLString{language: t[language]}
+}
+
+type projectLanguagesVisitor struct {
+	Languages []string
+}
+
+func (v *projectLanguagesVisitor) projectReference(ref *DocumentReference) errors.E {
+	ref.Name = ref.Name.project(v.Languages)
+	if ref.Claims != nil {
+		return ref.Claims.Visit(v)
+	}
+	return nil
+}
+
+func (v *projectLanguagesVisitor) VisitIdentifier(claim *IdentifierClaim) (VisitResult, errors.E) {
+	errE := v.projectReference(&claim.Prop)
+	if errE != nil {
+		return Keep, errE
+	}
+	return Keep, claim.VisitMeta(v)
+}
+
+func (v *projectLanguagesVisitor) VisitReference(claim *ReferenceClaim) (VisitResult, errors.E) {
+	errE := v.projectReference(&claim.Prop)
+	if errE != nil {
+		return Keep, errE
+	}
+	return Keep, claim.VisitMeta(v)
+}
+
+func (v *projectLanguagesVisitor) VisitText(claim *TextClaim) (VisitResult, errors.E) {
+	errE := v.projectReference(&claim.Prop)
+	if errE != nil {
+		return Keep, errE
+	}
+	claim.HTML = claim.HTML.project(v.Languages)
+	return Keep, claim.VisitMeta(v)
+}
+
+func (v *projectLanguagesVisitor) VisitString(claim *StringClaim) (VisitResult, errors.E) {
+	errE := v.projectReference(&claim.Prop)
+	if errE != nil {
+		return Keep, errE
+	}
+	return Keep, claim.VisitMeta(v)
+}
+
+func (v *projectLanguagesVisitor) VisitAmount(claim *AmountClaim) (VisitResult, errors.E) {
+	errE := v.projectReference(&claim.Prop)
+	if errE != nil {
+		return Keep, errE
+	}
+	return Keep, claim.VisitMeta(v)
+}
+
+func (v *projectLanguagesVisitor) VisitAmountRange(claim *AmountRangeClaim) (VisitResult, errors.E) {
+	errE := v.projectReference(&claim.Prop)
+	if errE != nil {
+		return Keep, errE
+	}
+	return Keep, claim.VisitMeta(v)
+}
+
+func (v *projectLanguagesVisitor) VisitEnumeration(claim *EnumerationClaim) (VisitResult, errors.E) {
+	errE := v.projectReference(&claim.Prop)
+	if errE != nil {
+		return Keep, errE
+	}
+	return Keep, claim.VisitMeta(v)
+}
+
+func (v *projectLanguagesVisitor) VisitRelation(claim *RelationClaim) (VisitResult, errors.E) {
+	errE := v.projectReference(&claim.Prop)
+	if errE != nil {
+		return Keep, errE
+	}
+	errE = v.projectReference(&claim.To)
+	if errE != nil {
+		return Keep, errE
+	}
+	return Keep, claim.VisitMeta(v)
+}
+
+func (v *projectLanguagesVisitor) VisitFile(claim *FileClaim) (VisitResult, errors.E) {
+	errE := v.projectReference(&claim.Prop)
+	if errE != nil {
+		return Keep, errE
+	}
+	return Keep, claim.VisitMeta(v)
+}
+
+func (v *projectLanguagesVisitor) VisitNoValue(claim *NoValueClaim) (VisitResult, errors.E) {
+	errE := v.projectReference(&claim.Prop)
+	if errE != nil {
+		return Keep, errE
+	}
+	return Keep, claim.VisitMeta(v)
+}
+
+func (v *projectLanguagesVisitor) VisitUnknownValue(claim *UnknownValueClaim) (VisitResult, errors.E) {
+	errE := v.projectReference(&claim.Prop)
+	if errE != nil {
+		return Keep, errE
+	}
+	return Keep, claim.VisitMeta(v)
+}
+
+func (v *projectLanguagesVisitor) VisitTime(claim *TimeClaim) (VisitResult, errors.E) {
+	errE := v.projectReference(&claim.Prop)
+	if errE != nil {
+		return Keep, errE
+	}
+	return Keep, claim.VisitMeta(v)
+}
+
+func (v *projectLanguagesVisitor) VisitTimeRange(claim *TimeRangeClaim) (VisitResult, errors.E) {
+	errE := v.projectReference(&claim.Prop)
+	if errE != nil {
+		return Keep, errE
+	}
+	return Keep, claim.VisitMeta(v)
+}
+
+// ProjectLanguages modifies the document so that its name, names of all
+// referenced documents, and HTML of text claims (including meta claims and
+// claims of expanded references) contain only the translation which best matches the requested languages.
+// See TranslatablePlainString.ResolveLanguage for details.
+func (d *Document) ProjectLanguages(languages []string) errors.E {
+	d.Name = d.Name.project(languages)
+	return d.Visit(&projectLanguagesVisitor{
+		Languages: languages,
+	})
+}
@@ -0,0 +1,92 @@
+package search_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/peerdb/search"
+)
+
+func TestResolve(t *testing.T) {
+	s := search.TranslatablePlainString{
+		"en":    "color",
+		"en-GB": "colour",
+		"sl":    "barva",
+		"de":    "Farbe",
+	}
+
+	tests := []struct {
+		languages []string
+		expected  string
+	}{
+		{[]string{"sl-SI"}, "barva"},
+		{[]string{"sl"}, "barva"},
+		{[]string{"en-GB"}, "colour"},
+		{[]string{"en-gb"}, "colour"},
+		{[]string{"en-US"}, "color"},
+		{[]string{"fr", "de"}, "Farbe"},
+		{[]string{"fr"}, "color"},
+		{[]string{}, "color"},
+		{nil, "color"},
+		{[]string{"zh-Hant-TW", "de-AT"}, "Farbe"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.languages), func(t *testing.T) {
+			assert.Equal(t, test.expected, s.Resolve(test.languages))
+		})
+	}
+
+	// Any translation is used as the last fallback.
+	assert.Equal(t, "Farbe", search.TranslatablePlainString{"de": "Farbe", "sl": "barva"}.Resolve([]string{"fr"}))
+	assert.Equal(t, "", search.TranslatableHTMLString{}.Resolve([]string{"fr"}))
+	assert.Equal(t, "", search.TranslatableHTMLString{}.ResolveLanguage([]string{"fr"}))
+	assert.Equal(t, "<b>barva</b>", search.TranslatableHTMLString{"en": "<b>color</b>", "sl": "<b>barva</b>"}.Resolve([]string{"sl-SI"}))
+}
+
+func TestProjectLanguages(t *testing.T) {
+	prop := search.DocumentReference{
+		ID:    "8mnDdqkCFYLERqYstB4WCF",
+		Name:  search.Name{"sl": "opis", "en": "description"},
+		Score: 0.5,
+	}
+	doc := search.Document{
+		CoreDocument: search.CoreDocument{
+			ID:    "XkbTJqwFCFkfoxMBXow4HU",
+			Name:  search.Name{"sl": "Ime", "en": "Name"},
+			Score: 0.5,
+		},
+		Active: &search.ClaimTypes{
+			Text: search.TextClaims{
+				{
+					CoreClaim: search.CoreClaim{
+						ID:         "A8uA1GpBmqp9m8HMY1EUYz",
+						Confidence: 1.0,
+						Meta: &search.ClaimTypes{
+							Relation: search.RelationClaims{
+								{
+									CoreClaim: search.CoreClaim{ID: "B8uA1GpBmqp9m8HMY1EUYz", Confidence: 1.0},
+									Prop:      prop,
+									To:        search.DocumentReference{ID: "9mnDdqkCFYLERqYstB4WCF", Name: search.Name{"en": "English only"}, Score: 0.5},
+								},
+							},
+						},
+					},
+					Prop: prop,
+					HTML: search.TranslatableHTMLString{"sl": "<p>Opis.</p>", "en": "<p>Description.</p>"},
+				},
+			},
+		},
+	}
+
+	errE := doc.ProjectLanguages([]string{"sl-SI"})
+	require.NoError(t, errE)
+	assert.Equal(t, search.Name{"sl": "Ime"}, doc.Name)
+	claim := doc.Active.Text[0]
+	assert.Equal(t, search.Name{"sl": "opis"}, claim.Prop.Name)
+	assert.Equal(t, search.TranslatableHTMLString{"sl": "<p>Opis.</p>"}, claim.HTML)
+	assert.Equal(t, search.Name{"sl": "opis"}, claim.Meta.Relation[0].Prop.Name)
+	assert.Equal(t, search.Name{"en": "English only"}, claim.Meta.Relation[0].To.Name)
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"io"
 	"log"
 	"mime"
 	"net"
@@ -167,49 +168,51 @@ func (s *Service) badRequest(w http.ResponseWriter, req *http.Request, err error
 
 // TODO: Use a pool of compression workers?
 func compress(compression string, data []byte) ([]byte, errors.E) {
+	if compression == compressionIdentity {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	writer, errE := newCompressWriter(compression, &buf)
+	if errE != nil {
+		return nil, errE
+	}
+	_, err := writer.Write(data)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return buf.Bytes(), nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// newCompressWriter returns a writer which compresses data written to it and writes
+// it to w. Close has to be called to write any remaining compressed data.
+func newCompressWriter(compression string, w io.Writer) (io.WriteCloser, errors.E) {
 	switch compression {
 	case compressionBrotli:
-		var buf bytes.Buffer
-		writer := brotli.NewWriter(&buf)
-		_, err := writer.Write(data)
-		if closeErr := writer.Close(); err == nil {
-			err = closeErr
-		}
-		if err != nil {
-			return nil, errors.WithStack(err)
-		}
-		data = buf.Bytes()
+		return brotli.NewWriter(w), nil
 	case compressionGzip:
-		var buf bytes.Buffer
-		writer := gzip.NewWriter(&buf)
-		_, err := writer.Write(data)
-		if closeErr := writer.Close(); err == nil {
-			err = closeErr
-		}
-		if err != nil {
-			return nil, errors.WithStack(err)
-		}
-		data = buf.Bytes()
+		return gzip.NewWriter(w), nil
 	case compressionDeflate:
-		var buf bytes.Buffer
-		writer, err := flate.NewWriter(&buf, -1)
-		if err != nil {
-			return nil, errors.WithStack(err)
-		}
-		_, err = writer.Write(data)
-		if closeErr := writer.Close(); err == nil {
-			err = closeErr
-		}
+		writer, err := flate.NewWriter(w, -1)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
-		data = buf.Bytes()
+		return writer, nil
 	case compressionIdentity:
-		// Nothing.
+		return nopWriteCloser{w}, nil
 	default:
 		return nil, errors.Errorf("unknown compression: %s", compression)
 	}
-	return data, nil
 }
 
 func (s *Service) writeJSON(w http.ResponseWriter, req *http.Request, contentEncoding string, data interface{}, metadata http.Header) {
@@ -226,11 +229,22 @@ func (s *Service) writeJSON(w http.ResponseWriter, req *http.Request, contentEnc
 
 	m.Stop()
 
+	s.writeEncoded(w, req, contentEncoding, "application/json", encoded, metadata)
+}
+
+// writeEncoded writes already encoded data with the content type, compressing it
+// based on the content encoding. ETag is computed from the data and metadata.
+func (s *Service) writeEncoded(
+	w http.ResponseWriter, req *http.Request, contentEncoding, contentType string, encoded []byte, metadata http.Header,
+) {
+	ctx := req.Context()
+	timing := servertiming.FromContext(ctx)
+
 	if len(encoded) <= minCompressionSize {
 		contentEncoding = compressionIdentity
 	}
 
-	m = timing.NewMetric("c").Start()
+	m := timing.NewMetric("c").Start()
 
 	encoded, errE := compress(contentEncoding, encoded)
 	if errE != nil {
@@ -260,7 +274,7 @@ func (s *Service) writeJSON(w http.ResponseWriter, req *http.Request, contentEnc
 		})
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", contentType)
 	if contentEncoding != compressionIdentity {
 		w.Header().Set("Content-Encoding", contentEncoding)
 	} else {
@@ -278,6 +292,28 @@ func (s *Service) writeJSON(w http.ResponseWriter, req *http.Request, contentEnc
 	http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(encoded))
 }
 
+// streamEncoded writes headers for a response with the content type and returns a writer
+// through which the response is then streamed, compressed based on the content encoding.
+// Close has to be called on the returned writer at the end. Contrary to writeEncoded,
+// the response is not known in advance, so ETag is not set.
+func (s *Service) streamEncoded(w http.ResponseWriter, contentEncoding, contentType string) (io.WriteCloser, errors.E) {
+	writer, errE := newCompressWriter(contentEncoding, w)
+	if errE != nil {
+		return nil, errE
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if contentEncoding != compressionIdentity {
+		w.Header().Set("Content-Encoding", contentEncoding)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	return writer, nil
+}
+
 func (s *Service) StaticFile(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	s.staticFile(w, req, req.URL.Path, true)
 }